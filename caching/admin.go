@@ -0,0 +1,161 @@
+package ristrettocache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+const (
+	// flushMarker передается в сообщении инвалидации вместо order_uid при полной очистке.
+	flushMarker = "*"
+	// flushTimeout ограничение на очистку общего кэша целиком.
+	flushTimeout = 30 * time.Second
+)
+
+// CacheStats снимок состояния локального кэша.
+type CacheStats struct {
+	Size          int        `json:"size"`
+	Cost          int64      `json:"cost"`
+	MaxCost       int64      `json:"max_cost"`
+	Hits          uint64     `json:"hits"`
+	Misses        uint64     `json:"misses"`
+	HitRatio      float64    `json:"hit_ratio"`
+	OldestOrderID string     `json:"oldest_order_uid,omitempty"`
+	OldestAddedAt *time.Time `json:"oldest_added_at,omitempty"`
+	SharedTier    bool       `json:"shared_tier"`
+}
+
+// Evict удаляет заказ из общего и локального кэша на всех репликах. Общий
+// уровень очищается первым: иначе параллельный запрос успел бы вернуть в
+// локальный кэш старую копию из общего.
+func (s *cacheService) Evict(ctx context.Context, orderUID string) error {
+	if s.shared != nil {
+		sharedCtx, cancel := context.WithTimeout(ctx, sharedTimeout)
+		err := s.shared.Delete(sharedCtx, sharedKeyPrefix+orderUID)
+		cancel()
+		if err != nil {
+			s.log(ctx).Error("Failed to evict order from shared cache", slog.String("orderID", orderUID), slog.Any("error", err))
+			return err
+		}
+	}
+
+	s.deleteLocal(orderUID)
+	s.broadcastInvalidation(ctx, orderUID)
+
+	s.log(ctx).Info("Order evicted from cache", slog.String("orderID", orderUID))
+	return nil
+}
+
+// EvictCustomer удаляет из кэша все заказы покупателя и возвращает их количество.
+func (s *cacheService) EvictCustomer(ctx context.Context, customerID string) (int, error) {
+	orderIDs, err := s.db.GetOrderIDsByCustomer(ctx, customerID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, orderID := range orderIDs {
+		if err := s.Evict(ctx, orderID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(orderIDs), nil
+}
+
+// Flush полностью очищает общий и локальный кэш на всех репликах. Реплики
+// оповещаются, даже если общий уровень очистить не удалось: их локальные
+// копии сбрасываются в любом случае.
+func (s *cacheService) Flush(ctx context.Context) error {
+	var err error
+	if s.shared != nil {
+		// Обход всех ключей общего кэша занимает заметно больше одной операции
+		sharedCtx, cancel := context.WithTimeout(ctx, flushTimeout)
+		err = s.shared.DeletePrefix(sharedCtx, sharedKeyPrefix)
+		cancel()
+		if err != nil {
+			s.log(ctx).Error("Failed to flush shared cache", slog.Any("error", err))
+		}
+	}
+
+	s.clearLocal()
+	s.broadcastInvalidation(ctx, flushMarker)
+	if err != nil {
+		return err
+	}
+
+	s.log(ctx).Info("Cache flushed")
+	return nil
+}
+
+// Warmup повторно загружает в кэш последние `limit` заказов из базы.
+func (s *cacheService) Warmup(ctx context.Context, limit int) (int, error) {
+	return s.loadCache(ctx, limit)
+}
+
+// Stats возвращает статистику локального кэша.
+func (s *cacheService) Stats() CacheStats {
+	metrics := s.cache.Metrics
+	stats := CacheStats{
+		Cost:       int64(metrics.CostAdded() - metrics.CostEvicted()),
+		MaxCost:    s.cache.MaxCost(),
+		Hits:       metrics.Hits(),
+		Misses:     metrics.Misses(),
+		HitRatio:   metrics.Ratio(),
+		SharedTier: s.shared != nil,
+	}
+
+	s.entriesMu.Lock()
+	defer s.entriesMu.Unlock()
+
+	stats.Size = len(s.entries)
+	for orderID, entry := range s.entries {
+		if stats.OldestAddedAt == nil || entry.addedAt.Before(*stats.OldestAddedAt) {
+			oldest := entry.addedAt
+			stats.OldestOrderID = orderID
+			stats.OldestAddedAt = &oldest
+		}
+	}
+
+	return stats
+}
+
+// deleteLocal удаляет заказ из локального кэша.
+func (s *cacheService) deleteLocal(orderUID string) {
+	s.cache.Del(orderUID)
+	s.cache.Wait()
+
+	s.entriesMu.Lock()
+	delete(s.entries, orderUID)
+	s.entriesMu.Unlock()
+}
+
+// clearLocal очищает локальный кэш.
+func (s *cacheService) clearLocal() {
+	s.cache.Clear()
+
+	s.entriesMu.Lock()
+	s.entries = make(map[string]*cacheEntry)
+	s.entriesMu.Unlock()
+}
+
+// forgetItem убирает из индекса ключ, вытесненный или отклоненный ristretto.
+func (s *cacheService) forgetItem(item *ristretto.Item) {
+	entry, ok := item.Value.(*cacheEntry)
+	if !ok {
+		return
+	}
+	s.forgetKey(entry.order.OrderID, entry)
+}
+
+// forgetKey убирает ключ из индекса, если он не был перезаписан позже.
+func (s *cacheService) forgetKey(orderUID string, entry *cacheEntry) {
+	s.entriesMu.Lock()
+	defer s.entriesMu.Unlock()
+
+	if s.entries[orderUID] == entry {
+		delete(s.entries, orderUID)
+	}
+}
@@ -1,24 +1,64 @@
 package ristrettocache
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
 	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
 	"github.com/dgraph-io/ristretto"
 )
 
 // CacheService интерфейс для работы с кэшем.
 type CacheService interface {
-	AddOrder(order *model.OrderDetails) error
-	GetOrder(orderUID string) (*model.OrderDetails, error)
+	AddOrder(ctx context.Context, order *model.OrderDetails) error
+	GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error)
+	GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error)
+	GetEncodedOrder(ctx context.Context, orderUID string) (*EncodedOrder, error)
+	Evict(ctx context.Context, orderUID string) error
+	EvictCustomer(ctx context.Context, customerID string) (int, error)
+	Flush(ctx context.Context) error
+	Warmup(ctx context.Context, limit int) (int, error)
+	Stats() CacheStats
+	Verify(ctx context.Context, repair bool) (*VerifyReport, error)
+	Close() error
 }
 
 // DBService интерфейс для взаимодействия с базой данных.
 type DBService interface {
-	AddOrder(order *model.OrderDetails) error
-	GetOrder(orderUID string) (*model.OrderDetails, error)
-	GetRecentOrderIDs(limit int) ([]string, error)
+	AddOrder(ctx context.Context, order *model.OrderDetails) error
+	UpdateItemStatuses(ctx context.Context, previous, next *model.OrderDetails) error
+	GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error)
+	GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error)
+	GetRecentOrderIDs(ctx context.Context, limit int) ([]string, error)
+	GetOrderIDsByCustomer(ctx context.Context, customerID string) ([]string, error)
+}
+
+const (
+	// warmupConcurrency ограничивает число параллельных запросов к базе при прогреве.
+	warmupConcurrency = 16
+
+	// sharedKeyPrefix префикс ключей заказов в общем кэше.
+	sharedKeyPrefix = "order:"
+	// invalidationChannel канал рассылки инвалидаций между репликами.
+	invalidationChannel = "orders:invalidate"
+	// sharedTimeout ограничение на одну операцию с общим кэшем.
+	sharedTimeout = 500 * time.Millisecond
+)
+
+// cacheEntry значение, хранимое в локальном кэше.
+type cacheEntry struct {
+	order   *model.OrderDetails
+	encoded *EncodedOrder // nil, если хранение сериализованных ответов отключено
+	addedAt time.Time
 }
 
 // cacheService реализует CacheService.
@@ -27,126 +67,414 @@ type cacheService struct {
 	db      DBService
 	logger  *slog.Logger
 	maxSize int
+
+	// entries записи локального кэша по ключу; ristretto хранит только хэши
+	// ключей, поэтому индекс ведется отдельно. Чтение через индекс не
+	// учитывается в метриках попаданий ristretto.
+	entriesMu sync.Mutex
+	entries   map[string]*cacheEntry
+
+	// shared общий (L2) уровень кэша, nil если не настроен.
+	shared     SharedTier
+	sharedTTL  time.Duration
+	instanceID string
+	// stopSubscription останавливает подписку на инвалидации.
+	stopSubscription context.CancelFunc
+
+	// encodeResponses хранить ли сериализованный ответ рядом с заказом.
+	encodeResponses bool
+}
+
+// Option настраивает cacheService.
+type Option func(*cacheService)
+
+// WithSharedTier подключает общий уровень кэша с заданным временем жизни записей.
+func WithSharedTier(tier SharedTier, ttl time.Duration) Option {
+	return func(s *cacheService) {
+		s.shared = tier
+		s.sharedTTL = ttl
+	}
+}
+
+// log возвращает логгер запроса из контекста, если он есть.
+func (s *cacheService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
 }
 
 // NewCacheService создает новый сервис с поддержкой Ristretto.
-func NewCacheService(logger *slog.Logger, cacheSize int, db DBService) (CacheService, error) {
+func NewCacheService(logger *slog.Logger, cacheSize int, db DBService, opts ...Option) (CacheService, error) {
+	service := &cacheService{
+		db:      db,
+		logger:  logger,
+		maxSize: cacheSize,
+		entries: make(map[string]*cacheEntry),
+	}
+
 	ristrettoCache, err := ristretto.NewCache(&ristretto.Config{
 		NumCounters: int64(cacheSize) * 10, // NumCounters рекомендуется как 10x от MaxCost
 		MaxCost:     int64(cacheSize),
 		BufferItems: 64, // Количество буферных элементов для асинхронной записи
+		// Стоимость записи — один заказ, без учета внутренних накладных расходов ristretto
+		IgnoreInternalCost: true,
+		Metrics:            true,
+		OnEvict:            service.forgetItem,
+		OnReject:           service.forgetItem,
 	})
 	if err != nil {
 		return nil, err
 	}
+	service.cache = ristrettoCache
+	for _, opt := range opts {
+		opt(service)
+	}
 
-	service := &cacheService{
-		cache:   ristrettoCache,
-		db:      db,
-		logger:  logger,
-		maxSize: cacheSize,
+	if service.shared != nil {
+		service.instanceID = newInstanceID()
+		subscriptionCtx, stop := context.WithCancel(context.Background())
+		err := service.shared.Subscribe(subscriptionCtx, invalidationChannel, service.handleInvalidation, service.handleResubscribe)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		service.stopSubscription = stop
 	}
 
 	// Инициализация кэша
-	if err := service.loadCache(); err != nil {
+	if _, err := service.loadCache(context.Background(), cacheSize); err != nil {
 		return nil, err
 	}
 
 	return service, nil
 }
 
-// loadCache загружает последние заказы из базы в кэш.
-func (s *cacheService) loadCache() error {
-	s.logger.Info("Initializing cache with recent orders...")
-	orderIDs, err := s.db.GetRecentOrderIDs(s.maxSize)
+// loadCache загружает последние `limit` заказов из базы в кэш и возвращает
+// количество загруженных заказов.
+func (s *cacheService) loadCache(ctx context.Context, limit int) (int, error) {
+	s.log(ctx).Info("Initializing cache with recent orders...", slog.Int("limit", limit))
+	orderIDs, err := s.db.GetRecentOrderIDs(ctx, limit)
 	if err != nil {
-		s.logger.Error("Failed to load recent orders from DB", slog.Any("error", err))
-		return err
+		s.log(ctx).Error("Failed to load recent orders from DB", slog.Any("error", err))
+		return 0, err
 	}
 
-	var wg sync.WaitGroup
+	var (
+		wg     sync.WaitGroup
+		loaded atomic.Int64
+		sem    = make(chan struct{}, warmupConcurrency)
+	)
 	for _, orderID := range orderIDs {
 		wg.Add(1)
+		sem <- struct{}{}
 		go func(id string) {
 			defer wg.Done()
-			order, err := s.db.GetOrder(id)
+			defer func() { <-sem }()
+			order, err := s.db.GetOrder(ctx, id)
 			if err != nil {
-				s.logger.Warn("Failed to fetch order during cache init", slog.String("orderID", id), slog.Any("error", err))
+				s.log(ctx).Warn("Failed to fetch order during cache init", slog.String("orderID", id), slog.Any("error", err))
 				return
 			}
 
 			// Добавляем в кэш
-			if ok := s.cache.Set(id, order, 1); ok {
-				s.logger.Debug("Order added to cache", slog.String("orderID", id))
-			}
-			s.cache.Wait()
+			s.addToCache(ctx, id, order)
+			loaded.Add(1)
 		}(orderID)
 	}
 
 	wg.Wait()
-	s.logger.Info("Cache initialization complete")
+	s.log(ctx).Info("Cache initialization complete", slog.Int64("loaded", loaded.Load()))
+	return int(loaded.Load()), nil
+}
+
+// AddOrder добавляет заказ в базу данных и кэш. Повторно присланный заказ
+// не перезаписывает сохраненный, возвращается model.ErrDuplicateOrder; если
+// в нем изменились только статусы товаров, они сохраняются (см.
+// replaceStatuses).
+func (s *cacheService) AddOrder(ctx context.Context, order *model.OrderDetails) error {
+	if err := s.db.AddOrder(ctx, order); err != nil {
+		if errors.Is(err, model.ErrDuplicateOrder) {
+			return s.replaceStatuses(ctx, order)
+		}
+		s.log(ctx).Error("Failed to add order to DB", slog.String("orderID", order.OrderID), slog.Any("error", err))
+		return err
+	}
+
+	s.log(ctx).Debug("Adding order to cache", slog.String("orderID", order.OrderID))
+	s.addToCache(ctx, order.OrderID, order)
+	s.addToShared(ctx, order)
+	s.broadcastInvalidation(ctx, order.OrderID)
+
+	s.log(ctx).Info("Order added successfully", slog.String("orderID", order.OrderID))
 	return nil
 }
 
-// AddOrder добавляет заказ в кэш и базу данных.
-func (s *cacheService) AddOrder(order *model.OrderDetails) error {
-	s.logger.Debug("Adding order to cache", slog.String("orderID", order.OrderID))
-	s.cache.Set(order.OrderID, order, 1)
-	s.cache.Wait()
+// replaceStatuses заменяет сохраненный заказ присланной версией, в которой
+// изменились статусы товаров. Переходы проверяет model.ValidateOrderTransition;
+// недопустимый переход возвращается как model.ErrConflictingData. Остальные
+// поля заказа не меняются: версия с другими изменениями, как и неизмененная,
+// считается повтором (model.ErrDuplicateOrder).
+func (s *cacheService) replaceStatuses(ctx context.Context, order *model.OrderDetails) error {
+	stored, err := s.db.GetOrder(ctx, order.OrderID)
+	if err != nil {
+		s.log(ctx).Error("Failed to load stored order", slog.String("orderID", order.OrderID), slog.Any("error", err))
+		return err
+	}
+	diffs := model.Diff(stored, order)
+	if len(diffs) == 0 {
+		return model.ErrDuplicateOrder
+	}
+	if err := model.ValidateOrderTransition(stored, order); err != nil {
+		return fmt.Errorf("%w: %w", model.ErrConflictingData, err)
+	}
+	for _, diff := range diffs {
+		if !strings.HasPrefix(diff.Field, "items[") || !strings.HasSuffix(diff.Field, "].status") {
+			return model.ErrDuplicateOrder
+		}
+	}
 
-	if err := s.db.AddOrder(order); err != nil {
-		s.logger.Error("Failed to add order to DB", slog.String("orderID", order.OrderID), slog.Any("error", err))
+	if err := s.db.UpdateItemStatuses(ctx, stored, order); err != nil {
+		if !errors.Is(err, model.ErrConflictingData) {
+			s.log(ctx).Error("Failed to update item statuses", slog.String("orderID", order.OrderID), slog.Any("error", err))
+		}
 		return err
 	}
+	// Заказ уже обновлен в базе; ошибку очистки кэша Evict записывает в журнал
+	_ = s.Evict(ctx, order.OrderID)
 
-	s.logger.Info("Order added successfully", slog.String("orderID", order.OrderID))
+	s.log(ctx).Info("Item statuses updated", slog.String("orderID", order.OrderID), slog.Int("changed", len(diffs)))
 	return nil
 }
 
 // GetOrder получает заказ из кэша или базы данных.
-func (s *cacheService) GetOrder(orderUID string) (*model.OrderDetails, error) {
+func (s *cacheService) GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error) {
 	// Сначала пытаемся найти заказ в кэше
 	order, found := s.getFromCache(orderUID)
 	if found {
-		s.logger.Debug("Cache hit", slog.String("orderID", orderUID))
+		s.log(ctx).Debug("Cache hit", slog.String("orderID", orderUID))
 		return order, nil
 	}
 
-	s.logger.Debug("Cache miss", slog.String("orderID", orderUID))
+	s.log(ctx).Debug("Cache miss", slog.String("orderID", orderUID))
+
+	entry, err := s.fetchOrder(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	return entry.order, nil
+}
+
+// GetOrders получает заказы по списку UID: найденные в кэше берутся из него,
+// остальные загружаются из базы одним запросом. Отсутствующих заказов нет в
+// результате.
+func (s *cacheService) GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error) {
+	orders := make(map[string]*model.OrderDetails, len(orderUIDs))
+	var missing []string
+	for _, orderUID := range orderUIDs {
+		if _, done := orders[orderUID]; done {
+			continue
+		}
+		if order, found := s.getFromCache(orderUID); found {
+			orders[orderUID] = order
+			continue
+		}
+		if order, found := s.getFromShared(ctx, orderUID); found {
+			orders[orderUID] = s.addToCache(ctx, orderUID, order).order
+			continue
+		}
+		missing = append(missing, orderUID)
+	}
+
+	s.log(ctx).Debug("Batch cache lookup", slog.Int("requested", len(orderUIDs)), slog.Int("misses", len(missing)))
+	if len(missing) == 0 {
+		return orders, nil
+	}
+
+	fetched, err := s.db.GetOrders(ctx, missing)
+	if err != nil {
+		s.log(ctx).Error("Failed to fetch orders from DB", slog.Int("count", len(missing)), slog.Any("error", err))
+		return nil, err
+	}
+	for orderUID, order := range fetched {
+		orders[orderUID] = s.addToCache(ctx, orderUID, order).order
+		s.addToShared(ctx, order)
+	}
+	return orders, nil
+}
+
+// fetchOrder загружает заказ из общего кэша или базы данных и сохраняет его
+// в локальный кэш.
+func (s *cacheService) fetchOrder(ctx context.Context, orderUID string) (*cacheEntry, error) {
+	// Сначала в общем кэше, если он подключен
+	if order, found := s.getFromShared(ctx, orderUID); found {
+		s.log(ctx).Debug("Shared cache hit", slog.String("orderID", orderUID))
+		return s.addToCache(ctx, orderUID, order), nil
+	}
 
 	// Если в кэше нет, загружаем из базы
-	order, err := s.db.GetOrder(orderUID)
+	order, err := s.db.GetOrder(ctx, orderUID)
+	if errors.Is(err, model.ErrOrderNotFound) {
+		return nil, err
+	}
 	if err != nil {
-		s.logger.Error("Failed to fetch order from DB", slog.String("orderID", orderUID), slog.Any("error", err))
+		s.log(ctx).Error("Failed to fetch order from DB", slog.String("orderID", orderUID), slog.Any("error", err))
 		return nil, err
 	}
 
 	// Сохраняем в кэш для дальнейшего использования
-	s.addToCache(orderUID, order)
-	return order, nil
+	entry := s.addToCache(ctx, orderUID, order)
+	s.addToShared(ctx, order)
+	return entry, nil
 }
 
 // getFromCache пытается получить заказ из кэша.
 func (s *cacheService) getFromCache(orderUID string) (*model.OrderDetails, bool) {
+	entry, found := s.getEntry(orderUID)
+	if !found {
+		return nil, false
+	}
+	return entry.order, true
+}
+
+// getEntry пытается получить запись из локального кэша.
+func (s *cacheService) getEntry(orderUID string) (*cacheEntry, bool) {
 	item, found := s.cache.Get(orderUID)
 	if !found {
 		return nil, false
 	}
 
-	order, ok := item.(*model.OrderDetails)
+	entry, ok := item.(*cacheEntry)
 	if !ok {
 		s.logger.Warn("Cache contains invalid data type", slog.String("orderID", orderUID))
 		return nil, false
 	}
 
-	return order, true
+	return entry, true
 }
 
-// addToCache добавляет заказ в кэш.
-func (s *cacheService) addToCache(orderUID string, order *model.OrderDetails) {
-	ok := s.cache.Set(orderUID, order, 1)
+// addToCache добавляет заказ в кэш и возвращает созданную запись.
+func (s *cacheService) addToCache(ctx context.Context, orderUID string, order *model.OrderDetails) *cacheEntry {
+	entry := &cacheEntry{order: order, addedAt: time.Now()}
+	if s.encodeResponses {
+		encoded, err := EncodeOrder(order)
+		if err != nil {
+			s.log(ctx).Warn("Failed to pre-encode order", slog.String("orderID", orderUID), slog.Any("error", err))
+		}
+		entry.encoded = encoded
+	}
+
+	s.entriesMu.Lock()
+	s.entries[orderUID] = entry
+	s.entriesMu.Unlock()
+
+	ok := s.cache.Set(orderUID, entry, 1)
 	s.cache.Wait()
-	if ok {
-		s.logger.Debug("Order added to cache", slog.String("orderID", orderUID))
+	if !ok {
+		s.forgetKey(orderUID, entry)
+		return entry
+	}
+	s.log(ctx).Debug("Order added to cache", slog.String("orderID", orderUID))
+	return entry
+}
+
+// getFromShared пытается получить заказ из общего кэша.
+func (s *cacheService) getFromShared(ctx context.Context, orderUID string) (*model.OrderDetails, bool) {
+	if s.shared == nil {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sharedTimeout)
+	defer cancel()
+
+	data, err := s.shared.Get(ctx, sharedKeyPrefix+orderUID)
+	if err != nil {
+		if !errors.Is(err, ErrCacheMiss) {
+			s.log(ctx).Warn("Failed to read from shared cache", slog.String("orderID", orderUID), slog.Any("error", err))
+		}
+		return nil, false
+	}
+
+	order, err := decodeOrder(data)
+	if err != nil {
+		s.log(ctx).Warn("Shared cache contains invalid data", slog.String("orderID", orderUID), slog.Any("error", err))
+		return nil, false
+	}
+
+	return order, true
+}
+
+// addToShared записывает заказ в общий кэш.
+func (s *cacheService) addToShared(ctx context.Context, order *model.OrderDetails) {
+	if s.shared == nil {
+		return
+	}
+
+	data, err := encodeOrder(order)
+	if err != nil {
+		s.log(ctx).Warn("Failed to encode order for shared cache", slog.String("orderID", order.OrderID), slog.Any("error", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sharedTimeout)
+	defer cancel()
+
+	if err := s.shared.Set(ctx, sharedKeyPrefix+order.OrderID, data, s.sharedTTL); err != nil {
+		s.log(ctx).Warn("Failed to write to shared cache", slog.String("orderID", order.OrderID), slog.Any("error", err))
+	}
+}
+
+// broadcastInvalidation сообщает остальным репликам, что их локальная копия заказа устарела.
+func (s *cacheService) broadcastInvalidation(ctx context.Context, orderUID string) {
+	if s.shared == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sharedTimeout)
+	defer cancel()
+
+	message := []byte(s.instanceID + " " + orderUID)
+	if err := s.shared.Publish(ctx, invalidationChannel, message); err != nil {
+		s.log(ctx).Warn("Failed to broadcast cache invalidation", slog.String("orderID", orderUID), slog.Any("error", err))
+	}
+}
+
+// handleInvalidation удаляет заказ из локального кэша по сообщению другой реплики.
+func (s *cacheService) handleInvalidation(message []byte) {
+	sender, orderUID, ok := strings.Cut(string(message), " ")
+	if !ok || sender == s.instanceID {
+		return
+	}
+
+	if orderUID == flushMarker {
+		s.clearLocal()
+		s.logger.Info("Cache flushed by another replica", slog.String("sender", sender))
+		return
+	}
+
+	s.deleteLocal(orderUID)
+	s.logger.Debug("Order invalidated by another replica", slog.String("orderID", orderUID), slog.String("sender", sender))
+}
+
+// handleResubscribe очищает локальный кэш после переподключения подписки:
+// инвалидации, разосланные во время обрыва, не были получены, и любая
+// локальная копия могла устареть.
+func (s *cacheService) handleResubscribe() {
+	s.clearLocal()
+	s.logger.Warn("Invalidation subscription restored, local cache flushed")
+}
+
+// Close останавливает подписку на инвалидации от других реплик. Общий
+// уровень кэша закрывает его владелец.
+func (s *cacheService) Close() error {
+	if s.stopSubscription != nil {
+		s.stopSubscription()
+	}
+	return nil
+}
+
+// newInstanceID генерирует идентификатор реплики для сообщений инвалидации.
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
 	}
+	return hex.EncodeToString(buf)
 }
@@ -0,0 +1,413 @@
+package ristrettocache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/caching/respfake"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// memDB база заказов в памяти, считающая обращения за заказами.
+type memDB struct {
+	mu     sync.Mutex
+	orders map[string]*model.OrderDetails
+	reads  int
+}
+
+func newMemDB(orders ...*model.OrderDetails) *memDB {
+	db := &memDB{orders: make(map[string]*model.OrderDetails)}
+	for _, order := range orders {
+		db.orders[order.OrderID] = order
+	}
+	return db
+}
+
+func (db *memDB) AddOrder(_ context.Context, order *model.OrderDetails) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.orders[order.OrderID]; ok {
+		return model.ErrDuplicateOrder
+	}
+	db.orders[order.OrderID] = order
+	return nil
+}
+
+func (db *memDB) UpdateItemStatuses(_ context.Context, _, next *model.OrderDetails) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.orders[next.OrderID] = next
+	return nil
+}
+
+func (db *memDB) GetOrder(_ context.Context, orderUID string) (*model.OrderDetails, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.reads++
+	order, ok := db.orders[orderUID]
+	if !ok {
+		return nil, model.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (db *memDB) GetOrders(_ context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.reads++
+	orders := make(map[string]*model.OrderDetails)
+	for _, orderUID := range orderUIDs {
+		if order, ok := db.orders[orderUID]; ok {
+			orders[orderUID] = order
+		}
+	}
+	return orders, nil
+}
+
+func (db *memDB) GetRecentOrderIDs(context.Context, int) ([]string, error) { return nil, nil }
+
+func (db *memDB) GetOrderIDsByCustomer(context.Context, string) ([]string, error) { return nil, nil }
+
+// replace подменяет заказ в базе в обход кэша, как это сделала бы другая реплика.
+func (db *memDB) replace(order *model.OrderDetails) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.orders[order.OrderID] = order
+}
+
+func (db *memDB) readCount() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.reads
+}
+
+func testOrder(orderUID, trackNumber string) *model.OrderDetails {
+	return &model.OrderDetails{OrderID: orderUID, TrackingNumber: trackNumber, CustomerID: "test"}
+}
+
+func startFake(t *testing.T) *respfake.Server {
+	t.Helper()
+	server, err := respfake.Start()
+	if err != nil {
+		t.Fatalf("start fake server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// newReplica создает реплику кэша со своим клиентом общего уровня.
+func newReplica(t *testing.T, server *respfake.Server, db DBService) CacheService {
+	t.Helper()
+	client, err := NewRESPClient(server.Addr())
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service, err := NewCacheService(logger, 100, db, WithSharedTier(client, time.Minute))
+	if err != nil {
+		t.Fatalf("create cache service: %v", err)
+	}
+	t.Cleanup(func() {
+		service.Close()
+		client.Close()
+	})
+	return service
+}
+
+// eventually повторяет check, пока он не вернет true или не выйдет время.
+func eventually(t *testing.T, timeout time.Duration, check func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if check() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return check()
+}
+
+func trackNumberOf(t *testing.T, service CacheService, orderUID string) string {
+	t.Helper()
+	order, err := service.GetOrder(context.Background(), orderUID)
+	if err != nil {
+		t.Fatalf("GetOrder(%s): %v", orderUID, err)
+	}
+	return order.TrackingNumber
+}
+
+func TestSharedTierReadThrough(t *testing.T) {
+	server := startFake(t)
+	db := newMemDB(testOrder("o1", "v1"))
+	first := newReplica(t, server, db)
+	second := newReplica(t, server, db)
+
+	if got := trackNumberOf(t, first, "o1"); got != "v1" {
+		t.Fatalf("first replica: got %q, want v1", got)
+	}
+	if server.Len() != 1 {
+		t.Fatalf("shared tier holds %d keys, want 1", server.Len())
+	}
+
+	// Вторая реплика находит заказ в общем кэше, не обращаясь к базе
+	reads := db.readCount()
+	if got := trackNumberOf(t, second, "o1"); got != "v1" {
+		t.Fatalf("second replica: got %q, want v1", got)
+	}
+	if db.readCount() != reads {
+		t.Errorf("second replica read the database, want a shared tier hit")
+	}
+
+	orders, err := second.GetOrders(context.Background(), []string{"o1", "missing"})
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(orders) != 1 || orders["o1"] == nil {
+		t.Errorf("GetOrders returned %v, want only o1", orders)
+	}
+}
+
+func TestInvalidationBetweenReplicas(t *testing.T) {
+	server := startFake(t)
+	db := newMemDB(testOrder("o1", "v1"))
+	first := newReplica(t, server, db)
+	second := newReplica(t, server, db)
+
+	trackNumberOf(t, first, "o1")
+	trackNumberOf(t, second, "o1")
+
+	db.replace(testOrder("o1", "v2"))
+	if err := second.Evict(context.Background(), "o1"); err != nil {
+		t.Fatalf("Evict: %v", err)
+	}
+
+	if !eventually(t, 2*time.Second, func() bool { return trackNumberOf(t, first, "o1") == "v2" }) {
+		t.Fatalf("first replica still serves the evicted order")
+	}
+
+	db.replace(testOrder("o1", "v3"))
+	if err := second.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if server.Len() != 0 {
+		t.Errorf("shared tier holds %d keys after flush, want 0", server.Len())
+	}
+	if !eventually(t, 2*time.Second, func() bool { return trackNumberOf(t, first, "o1") == "v3" }) {
+		t.Fatalf("first replica still serves orders after a flush")
+	}
+}
+
+func TestResubscribeFlushesLocalCache(t *testing.T) {
+	server := startFake(t)
+	db := newMemDB(testOrder("o1", "v1"))
+	first := newReplica(t, server, db)
+
+	if got := trackNumberOf(t, first, "o1"); got != "v1" {
+		t.Fatalf("got %q, want v1", got)
+	}
+
+	// Инвалидация, разосланная во время обрыва, до первой реплики не доходит
+	server.DropConnections()
+	second := newReplica(t, server, db)
+	db.replace(testOrder("o1", "v2"))
+	if err := second.Evict(context.Background(), "o1"); err != nil {
+		t.Fatalf("Evict: %v", err)
+	}
+	if got := trackNumberOf(t, first, "o1"); got != "v1" {
+		t.Fatalf("invalidation reached the disconnected replica: got %q", got)
+	}
+
+	// После переподключения подписки локальный кэш сбрасывается
+	if !eventually(t, 3*respReconnectDelay, func() bool { return trackNumberOf(t, first, "o1") == "v2" }) {
+		t.Fatalf("replica kept a stale order after resubscribing")
+	}
+}
+
+func TestCloseStopsSubscription(t *testing.T) {
+	server := startFake(t)
+	db := newMemDB(testOrder("o1", "v1"))
+	first := newReplica(t, server, db)
+	second := newReplica(t, server, db)
+
+	trackNumberOf(t, first, "o1")
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	db.replace(testOrder("o1", "v2"))
+	if err := second.Evict(context.Background(), "o1"); err != nil {
+		t.Fatalf("Evict: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := trackNumberOf(t, first, "o1"); got != "v1" {
+		t.Errorf("closed replica still receives invalidations: got %q", got)
+	}
+}
+
+// recordingTier общий уровень, запоминающий операции; DeletePrefix и Delete
+// завершаются ошибкой, если она задана.
+type recordingTier struct {
+	mu        sync.Mutex
+	deleteErr error
+	ops       []string
+	published []string
+}
+
+func (r *recordingTier) record(op string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, op)
+}
+
+func (r *recordingTier) Get(context.Context, string) ([]byte, error) { return nil, ErrCacheMiss }
+
+func (r *recordingTier) Set(context.Context, string, []byte, time.Duration) error { return nil }
+
+func (r *recordingTier) Delete(context.Context, ...string) error {
+	r.record("delete")
+	return r.deleteErr
+}
+
+func (r *recordingTier) DeletePrefix(context.Context, string) error {
+	r.record("delete")
+	return r.deleteErr
+}
+
+func (r *recordingTier) ScanPrefix(context.Context, string, func([]string) error) error { return nil }
+
+func (r *recordingTier) Publish(_ context.Context, _ string, message []byte) error {
+	r.record("publish")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published = append(r.published, string(message))
+	return nil
+}
+
+func (r *recordingTier) Subscribe(context.Context, string, func([]byte), func()) error { return nil }
+
+func TestFlushBroadcastsWhenSharedTierFails(t *testing.T) {
+	tier := &recordingTier{deleteErr: errors.New("scan timed out")}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service, err := NewCacheService(logger, 100, newMemDB(testOrder("o1", "v1")), WithSharedTier(tier, time.Minute))
+	if err != nil {
+		t.Fatalf("create cache service: %v", err)
+	}
+	defer service.Close()
+
+	if err := service.Flush(context.Background()); err == nil {
+		t.Fatalf("Flush succeeded, want the shared tier error")
+	}
+	if len(tier.published) != 1 || !strings.HasSuffix(tier.published[0], " "+flushMarker) {
+		t.Errorf("published %q, want a flush broadcast", tier.published)
+	}
+}
+
+func TestEvictDeletesSharedBeforePublishing(t *testing.T) {
+	tier := &recordingTier{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service, err := NewCacheService(logger, 100, newMemDB(testOrder("o1", "v1")), WithSharedTier(tier, time.Minute))
+	if err != nil {
+		t.Fatalf("create cache service: %v", err)
+	}
+	defer service.Close()
+
+	if err := service.Evict(context.Background(), "o1"); err != nil {
+		t.Fatalf("Evict: %v", err)
+	}
+	if got := strings.Join(tier.ops, ","); got != "delete,publish" {
+		t.Errorf("operations %s, want delete,publish", got)
+	}
+
+	// Если общий уровень не очищен, реплики не оповещаются
+	tier.ops, tier.deleteErr = nil, errors.New("unavailable")
+	if err := service.Evict(context.Background(), "o1"); err == nil {
+		t.Fatalf("Evict succeeded, want the shared tier error")
+	}
+	if got := strings.Join(tier.ops, ","); got != "delete" {
+		t.Errorf("operations %s, want delete only", got)
+	}
+}
+
+func TestAddOrderReplacesItemStatuses(t *testing.T) {
+	withStatus := func(status model.ItemStatus, trackNumber string) *model.OrderDetails {
+		order := testOrder("o1", trackNumber)
+		order.Products = []model.ProductItem{{ChartID: 1, Status: status}}
+		return order
+	}
+	db := newMemDB(withStatus(model.StatusPaid, "v1"))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service, err := NewCacheService(logger, 100, db)
+	if err != nil {
+		t.Fatalf("create cache service: %v", err)
+	}
+	defer service.Close()
+	ctx := context.Background()
+
+	if _, err := service.GetOrder(ctx, "o1"); err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if err := service.AddOrder(ctx, withStatus(model.StatusPaid, "v1")); !errors.Is(err, model.ErrDuplicateOrder) {
+		t.Errorf("unchanged order: error %v, want ErrDuplicateOrder", err)
+	}
+	if err := service.AddOrder(ctx, withStatus(model.StatusAssembling, "v1")); err != nil {
+		t.Fatalf("legal transition: %v", err)
+	}
+	order, err := service.GetOrder(ctx, "o1")
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if order.Products[0].Status != model.StatusAssembling {
+		t.Errorf("cached status %s, want the updated one", order.Products[0].Status)
+	}
+
+	if err := service.AddOrder(ctx, withStatus(model.StatusCreated, "v1")); !errors.Is(err, model.ErrConflictingData) {
+		t.Errorf("illegal transition: error %v, want ErrConflictingData", err)
+	}
+	if err := service.AddOrder(ctx, withStatus(model.StatusInTransit, "v2")); !errors.Is(err, model.ErrDuplicateOrder) {
+		t.Errorf("changed order fields: error %v, want ErrDuplicateOrder", err)
+	}
+	if got := trackNumberOf(t, service, "o1"); got != "v1" {
+		t.Errorf("track number %q, want the stored v1", got)
+	}
+}
+
+func TestVerifyDoesNotCountCacheHits(t *testing.T) {
+	db := newMemDB(testOrder("o1", "v1"), testOrder("o2", "v1"))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service, err := NewCacheService(logger, 100, db)
+	if err != nil {
+		t.Fatalf("create cache service: %v", err)
+	}
+	defer service.Close()
+	ctx := context.Background()
+
+	for _, orderUID := range []string{"o1", "o2", "o1"} {
+		if _, err := service.GetOrder(ctx, orderUID); err != nil {
+			t.Fatalf("GetOrder(%s): %v", orderUID, err)
+		}
+	}
+	before := service.Stats()
+
+	db.replace(testOrder("o1", "v2"))
+	report, err := service.Verify(ctx, false)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if report.LocalChecked != 2 || len(report.Mismatches) != 1 || report.Mismatches[0].OrderUID != "o1" {
+		t.Errorf("report %+v, want 2 checked and a mismatch of o1", report)
+	}
+
+	after := service.Stats()
+	if after.Hits != before.Hits || after.Misses != before.Misses {
+		t.Errorf("hits/misses %d/%d after Verify, want %d/%d", after.Hits, after.Misses, before.Hits, before.Misses)
+	}
+}
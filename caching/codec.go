@@ -0,0 +1,61 @@
+package ristrettocache
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// codecVersion первый байт закодированного заказа, позволяет менять формат
+// без сброса общего кэша: записи неизвестной версии считаются промахом.
+// Версия 2: суммы в минимальных единицах валюты.
+const codecVersion byte = 2
+
+var errUnknownCodec = errors.New("unknown cache codec version")
+
+// encodeOrder кодирует заказ в компактный формат для общего уровня кэша:
+// байт версии и сжатый deflate JSON.
+func encodeOrder(order *model.OrderDetails) ([]byte, error) {
+	raw, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal order: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(codecVersion)
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeOrder восстанавливает заказ, закодированный encodeOrder.
+func decodeOrder(data []byte) (*model.OrderDetails, error) {
+	if len(data) == 0 || data[0] != codecVersion {
+		return nil, errUnknownCodec
+	}
+
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data[1:])))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate order: %w", err)
+	}
+
+	var order model.OrderDetails
+	if err := json.Unmarshal(raw, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order: %w", err)
+	}
+	return &order, nil
+}
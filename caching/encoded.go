@@ -0,0 +1,79 @@
+package ristrettocache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// EncodedOrder каноническое JSON-представление заказа, готовое к отдаче по HTTP.
+type EncodedOrder struct {
+	JSON []byte
+	Gzip []byte
+	// ETag сильный валидатор, вычисленный по JSON, вместе с кавычками.
+	ETag string
+}
+
+// WithEncodedResponses включает хранение сериализованных ответов рядом с заказом.
+func WithEncodedResponses() Option {
+	return func(s *cacheService) {
+		s.encodeResponses = true
+	}
+}
+
+// EncodeOrder сериализует заказ в канонический JSON (как json.Encoder, с переводом
+// строки в конце), сжимает его gzip и вычисляет ETag.
+func EncodeOrder(order *model.OrderDetails) (*EncodedOrder, error) {
+	raw, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal order: %w", err)
+	}
+	raw = append(raw, '\n')
+
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(raw)
+	return &EncodedOrder{
+		JSON: raw,
+		Gzip: buf.Bytes(),
+		ETag: `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}, nil
+}
+
+// GetEncodedOrder возвращает сериализованный заказ. Если хранение сериализованных
+// ответов отключено, заказ кодируется при каждом вызове.
+func (s *cacheService) GetEncodedOrder(ctx context.Context, orderUID string) (*EncodedOrder, error) {
+	entry, found := s.getEntry(orderUID)
+	if found {
+		s.log(ctx).Debug("Cache hit", slog.String("orderID", orderUID))
+	} else {
+		s.log(ctx).Debug("Cache miss", slog.String("orderID", orderUID))
+
+		var err error
+		if entry, err = s.fetchOrder(ctx, orderUID); err != nil {
+			return nil, err
+		}
+	}
+
+	if entry.encoded != nil {
+		return entry.encoded, nil
+	}
+	return EncodeOrder(entry.order)
+}
@@ -0,0 +1,454 @@
+package ristrettocache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss возвращается общим уровнем кэша, если ключ не найден.
+var ErrCacheMiss = errors.New("cache miss")
+
+// errClientClosed возвращается при обращении к закрытому клиенту.
+var errClientClosed = errors.New("resp client is closed")
+
+const (
+	respMaxIdleConns   = 4
+	respDialTimeout    = 3 * time.Second
+	respReconnectDelay = time.Second
+)
+
+// SharedTier интерфейс общего (L2) уровня кэша, разделяемого между репликами.
+type SharedTier interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+	ScanPrefix(ctx context.Context, prefix string, fn func(keys []string) error) error
+	Publish(ctx context.Context, channel string, message []byte) error
+	Subscribe(ctx context.Context, channel string, handler func(message []byte), resubscribed func()) error
+}
+
+// RESPClient минимальный клиент протокола Redis (RESP2), реализующий SharedTier.
+type RESPClient struct {
+	addr     string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	idle   []*respConn
+	closed bool
+	done   chan struct{}
+}
+
+// respConn одно соединение с сервером вместе с буферизованным читателем.
+type respConn struct {
+	net.Conn
+	rd *bufio.Reader
+}
+
+// respError ошибка, которую вернул сервер (ответ вида "-ERR ...").
+type respError string
+
+func (e respError) Error() string { return string(e) }
+
+// NewRESPClient создает клиент по адресу вида redis://[:password@]host:port[/db]
+// или просто host:port. Соединения устанавливаются лениво.
+func NewRESPClient(rawURL string) (*RESPClient, error) {
+	client := &RESPClient{done: make(chan struct{})}
+
+	if !strings.Contains(rawURL, "://") {
+		client.addr = rawURL
+		return client, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RESP URL: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported RESP URL scheme %q", u.Scheme)
+	}
+
+	client.addr = u.Host
+	if password, ok := u.User.Password(); ok {
+		client.password = password
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		client.db, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESP database number %q", db)
+		}
+	}
+
+	return client, nil
+}
+
+// Ping проверяет доступность сервера.
+func (c *RESPClient) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, "PING")
+	return err
+}
+
+// Get возвращает значение по ключу или ErrCacheMiss.
+func (c *RESPClient) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrCacheMiss
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected GET reply type %T", reply)
+	}
+	return value, nil
+}
+
+// Set сохраняет значение с указанным временем жизни (0 — без ограничения).
+func (c *RESPClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+// Delete удаляет ключи.
+func (c *RESPClient) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// DeletePrefix удаляет все ключи с указанным префиксом, перебирая их через SCAN.
+func (c *RESPClient) DeletePrefix(ctx context.Context, prefix string) error {
+	return c.ScanPrefix(ctx, prefix, func(keys []string) error {
+		return c.Delete(ctx, keys...)
+	})
+}
+
+// ScanPrefix перебирает ключи с указанным префиксом через SCAN и передает их
+// в fn пачками. Ключи, измененные во время перебора, могут быть пропущены
+// или переданы повторно.
+func (c *RESPClient) ScanPrefix(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, "SCAN", cursor, "MATCH", prefix+"*", "COUNT", "500")
+		if err != nil {
+			return err
+		}
+
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 2 {
+			return fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		next, _ := parts[0].([]byte)
+		items, _ := parts[1].([]any)
+
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			if key, ok := item.([]byte); ok {
+				keys = append(keys, string(key))
+			}
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// Publish отправляет сообщение в канал.
+func (c *RESPClient) Publish(ctx context.Context, channel string, message []byte) error {
+	_, err := c.do(ctx, "PUBLISH", channel, string(message))
+	return err
+}
+
+// Subscribe подписывается на канал и вызывает handler для каждого сообщения.
+// Подписка работает в отдельной горутине на выделенном соединении и
+// переподключается при обрыве до отмены ctx или закрытия клиента. Сообщения,
+// опубликованные во время обрыва, теряются, поэтому после переподключения
+// вызывается resubscribed (если не nil).
+func (c *RESPClient) Subscribe(ctx context.Context, channel string, handler func(message []byte), resubscribed func()) error {
+	conn, err := c.subscribe(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			err := c.receive(ctx, conn, handler)
+			conn.Close()
+			if errors.Is(err, errClientClosed) {
+				return
+			}
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-c.done:
+					return
+				case <-time.After(respReconnectDelay):
+				}
+
+				if conn, err = c.subscribe(ctx, channel); err == nil {
+					break
+				}
+			}
+			if resubscribed != nil {
+				resubscribed()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Close закрывает все соединения клиента и останавливает подписки.
+func (c *RESPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+
+	for _, conn := range c.idle {
+		conn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+// subscribe открывает выделенное соединение и отправляет команду SUBSCRIBE.
+func (c *RESPClient) subscribe(ctx context.Context, channel string) (*respConn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.roundTrip(ctx, "SUBSCRIBE", channel); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// receive читает сообщения подписки, пока соединение не оборвется.
+func (c *RESPClient) receive(ctx context.Context, conn *respConn, handler func(message []byte)) error {
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-c.done:
+			conn.Close()
+		case <-ctx.Done():
+			conn.Close()
+		case <-finished:
+		}
+	}()
+
+	for {
+		reply, err := readReply(conn.rd)
+		if err != nil {
+			select {
+			case <-c.done:
+				return errClientClosed
+			case <-ctx.Done():
+				return errClientClosed
+			default:
+				return err
+			}
+		}
+
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 {
+			continue
+		}
+		if kind, _ := parts[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		if payload, ok := parts[2].([]byte); ok {
+			handler(payload)
+		}
+	}
+}
+
+// do выполняет одну команду на соединении из пула.
+func (c *RESPClient) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.roundTrip(ctx, args...)
+	var serverErr respError
+	if err != nil && !errors.As(err, &serverErr) {
+		conn.Close()
+		return nil, err
+	}
+
+	c.put(conn)
+	return reply, err
+}
+
+// get берет свободное соединение из пула или устанавливает новое.
+func (c *RESPClient) get(ctx context.Context) (*respConn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errClientClosed
+	}
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	return c.dial(ctx)
+}
+
+// put возвращает соединение в пул.
+func (c *RESPClient) put(conn *respConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || len(c.idle) >= respMaxIdleConns {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// dial устанавливает соединение и выполняет AUTH/SELECT при необходимости.
+func (c *RESPClient) dial(ctx context.Context) (*respConn, error) {
+	dialer := net.Dialer{Timeout: respDialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &respConn{Conn: netConn, rd: bufio.NewReader(netConn)}
+	if c.password != "" {
+		if _, err := conn.roundTrip(ctx, "AUTH", c.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := conn.roundTrip(ctx, "SELECT", strconv.Itoa(c.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// roundTrip отправляет команду и читает один ответ.
+func (conn *respConn) roundTrip(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(encodeCommand(args)); err != nil {
+		return nil, err
+	}
+	return readReply(conn.rd)
+}
+
+// encodeCommand кодирует команду как массив bulk-строк.
+func encodeCommand(args []string) []byte {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		b.WriteString(arg)
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+// readReply читает один ответ RESP. Bulk-строки возвращаются как []byte,
+// nil-ответы как nil, массивы как []any, ошибки сервера как respError.
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty RESP reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", line[1:])
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length %q", line[1:])
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, 0, count)
+		for i := 0; i < count; i++ {
+			item, err := readReply(rd)
+			var serverErr respError
+			if err != nil && !errors.As(err, &serverErr) {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown RESP reply type %q", line[0])
+	}
+}
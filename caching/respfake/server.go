@@ -0,0 +1,392 @@
+// Package respfake реализует встроенный в процесс сервер протокола Redis (RESP2)
+// с минимальным набором команд, достаточным для общего уровня кэша.
+package respfake
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server хранит данные в памяти и обслуживает клиентов на локальном порту.
+type Server struct {
+	listener net.Listener
+
+	mu          sync.Mutex
+	data        map[string]entry
+	subscribers map[string]map[*client]struct{}
+	clients     map[*client]struct{}
+	closed      bool
+
+	wg sync.WaitGroup
+}
+
+// entry значение ключа со временем истечения (нулевое — без ограничения).
+type entry struct {
+	value    string
+	expireAt time.Time
+}
+
+// client одно подключение к серверу.
+type client struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+// Start запускает сервер на случайном свободном порту 127.0.0.1.
+func Start() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener:    listener,
+		data:        make(map[string]entry),
+		subscribers: make(map[string]map[*client]struct{}),
+		clients:     make(map[*client]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr возвращает адрес сервера в виде host:port.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close останавливает сервер и закрывает все подключения.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// DropConnections разрывает все клиентские соединения, не останавливая
+// сервер, и сразу снимает подписки: так имитируется обрыв связи, после
+// которого клиенты переподключаются.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.subscribers = make(map[string]map[*client]struct{})
+}
+
+// Len возвращает количество живых ключей.
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for key := range s.data {
+		if _, ok := s.lookup(key); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &client{conn: conn}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+// handle читает команды клиента до разрыва соединения.
+func (s *Server) handle(c *client) {
+	defer s.wg.Done()
+	defer s.disconnect(c)
+
+	rd := bufio.NewReader(c.conn)
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.write(errorReply(err.Error()))
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		c.write(s.exec(c, args))
+	}
+}
+
+// disconnect удаляет клиента из всех подписок.
+func (s *Server) disconnect(c *client) {
+	c.conn.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.clients, c)
+	for channel, subs := range s.subscribers {
+		delete(subs, c)
+		if len(subs) == 0 {
+			delete(s.subscribers, channel)
+		}
+	}
+}
+
+// exec выполняет команду и возвращает закодированный ответ.
+func (s *Server) exec(c *client, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch cmd := strings.ToUpper(args[0]); cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		if len(args) != 2 {
+			return wrongArgs(cmd)
+		}
+		value, ok := s.lookup(args[1])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulkReply(value)
+	case "SET":
+		return s.set(args)
+	case "DEL":
+		if len(args) < 2 {
+			return wrongArgs(cmd)
+		}
+		removed := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key); ok {
+				removed++
+			}
+			delete(s.data, key)
+		}
+		return intReply(removed)
+	case "EXISTS":
+		if len(args) < 2 {
+			return wrongArgs(cmd)
+		}
+		found := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key); ok {
+				found++
+			}
+		}
+		return intReply(found)
+	case "SCAN":
+		return s.scan(args)
+	case "FLUSHDB", "FLUSHALL":
+		s.data = make(map[string]entry)
+		return "+OK\r\n"
+	case "PUBLISH":
+		if len(args) != 3 {
+			return wrongArgs(cmd)
+		}
+		return intReply(s.publish(args[1], args[2]))
+	case "SUBSCRIBE":
+		if len(args) < 2 {
+			return wrongArgs(cmd)
+		}
+		var b strings.Builder
+		for i, channel := range args[1:] {
+			if s.subscribers[channel] == nil {
+				s.subscribers[channel] = make(map[*client]struct{})
+			}
+			s.subscribers[channel][c] = struct{}{}
+			b.WriteString(arrayReply(bulkReply("subscribe"), bulkReply(channel), intReply(i+1)))
+		}
+		return b.String()
+	default:
+		return errorReply(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+// set обрабатывает SET key value [EX seconds | PX milliseconds].
+func (s *Server) set(args []string) string {
+	if len(args) != 3 && len(args) != 5 {
+		return wrongArgs("SET")
+	}
+
+	e := entry{value: args[2]}
+	if len(args) == 5 {
+		amount, err := strconv.ParseInt(args[4], 10, 64)
+		if err != nil || amount <= 0 {
+			return errorReply("ERR invalid expire time in 'set' command")
+		}
+		switch strings.ToUpper(args[3]) {
+		case "EX":
+			e.expireAt = time.Now().Add(time.Duration(amount) * time.Second)
+		case "PX":
+			e.expireAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+
+	s.data[args[1]] = e
+	return "+OK\r\n"
+}
+
+// scan обрабатывает SCAN cursor [MATCH pattern] [COUNT n]. Все подходящие
+// ключи возвращаются за один вызов с курсором 0.
+func (s *Server) scan(args []string) string {
+	if len(args) < 2 {
+		return wrongArgs("SCAN")
+	}
+
+	pattern := "*"
+	for i := 2; i+1 < len(args); i += 2 {
+		if strings.EqualFold(args[i], "MATCH") {
+			pattern = args[i+1]
+		}
+	}
+
+	var keys []string
+	for key := range s.data {
+		if _, ok := s.lookup(key); !ok {
+			continue
+		}
+		if matched, _ := path.Match(pattern, key); matched {
+			keys = append(keys, bulkReply(key))
+		}
+	}
+
+	return arrayReply(bulkReply("0"), arrayReply(keys...))
+}
+
+// publish рассылает сообщение подписчикам канала и возвращает их количество.
+func (s *Server) publish(channel, message string) int {
+	reply := arrayReply(bulkReply("message"), bulkReply(channel), bulkReply(message))
+	for c := range s.subscribers[channel] {
+		c.write(reply)
+	}
+	return len(s.subscribers[channel])
+}
+
+// lookup возвращает значение ключа, удаляя его, если срок истек.
+func (s *Server) lookup(key string) (string, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return "", false
+	}
+	if !e.expireAt.IsZero() && time.Now().After(e.expireAt) {
+		delete(s.data, key)
+		return "", false
+	}
+	return e.value, true
+}
+
+func (c *client) write(reply string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.Write([]byte(reply))
+}
+
+// readCommand читает команду в виде массива bulk-строк или inline-строки.
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("ERR invalid multibulk length")
+	}
+
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		header, err := readLine(rd)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("ERR expected '$', got '%s'", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("ERR invalid bulk length")
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func bulkReply(value string) string {
+	return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+}
+
+func intReply(value int) string {
+	return ":" + strconv.Itoa(value) + "\r\n"
+}
+
+func arrayReply(items ...string) string {
+	return "*" + strconv.Itoa(len(items)) + "\r\n" + strings.Join(items, "")
+}
+
+func errorReply(message string) string {
+	return "-" + message + "\r\n"
+}
+
+func wrongArgs(cmd string) string {
+	return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
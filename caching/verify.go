@@ -0,0 +1,149 @@
+package ristrettocache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// verifyBatchSize количество заказов, сверяемых с базой одним запросом.
+const verifyBatchSize = 500
+
+// Уровни кэша в отчете сверки.
+const (
+	TierLocal  = "local"
+	TierShared = "shared"
+)
+
+// CacheMismatch запись кэша, расходящаяся с базой.
+type CacheMismatch struct {
+	OrderUID string `json:"order_uid"`
+	// Tier уровень кэша: local или shared
+	Tier string `json:"tier"`
+	// MissingInDB заказа из кэша нет в базе
+	MissingInDB bool `json:"missing_in_db,omitempty"`
+	// Diffs поля, в которых копия в кэше (old) отличается от базы (new)
+	Diffs []model.FieldDiff `json:"diffs,omitempty"`
+	// Repaired запись удалена из кэша
+	Repaired bool `json:"repaired,omitempty"`
+}
+
+// VerifyReport итог сверки кэша с базой.
+type VerifyReport struct {
+	LocalChecked  int             `json:"local_checked"`
+	SharedChecked int             `json:"shared_checked"`
+	Mismatches    []CacheMismatch `json:"mismatches"`
+}
+
+// Verify сверяет записи локального и общего кэша с заказами, заново
+// загруженными из базы. С repair расходящиеся записи удаляются из кэша на
+// всех репликах, как при Evict, и следующее чтение загрузит заказ из базы.
+func (s *cacheService) Verify(ctx context.Context, repair bool) (*VerifyReport, error) {
+	report := &VerifyReport{Mismatches: []CacheMismatch{}}
+
+	s.entriesMu.Lock()
+	orderIDs := make([]string, 0, len(s.entries))
+	for orderID := range s.entries {
+		orderIDs = append(orderIDs, orderID)
+	}
+	s.entriesMu.Unlock()
+
+	for start := 0; start < len(orderIDs); start += verifyBatchSize {
+		batch := make(map[string]*model.OrderDetails, verifyBatchSize)
+		for _, orderID := range orderIDs[start:min(start+verifyBatchSize, len(orderIDs))] {
+			// Записи, вытесненные после снимка индекса, пропускаются
+			if entry, found := s.peekEntry(orderID); found {
+				batch[orderID] = entry.order
+			}
+		}
+		report.LocalChecked += len(batch)
+		if err := s.verifyBatch(ctx, TierLocal, batch, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.shared != nil {
+		err := s.shared.ScanPrefix(ctx, sharedKeyPrefix, func(keys []string) error {
+			batch := make(map[string]*model.OrderDetails, len(keys))
+			for _, key := range keys {
+				data, err := s.shared.Get(ctx, key)
+				if errors.Is(err, ErrCacheMiss) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				order, err := decodeOrder(data)
+				if err != nil {
+					// Записи другой версии формата читаются как промах
+					s.log(ctx).Warn("Skipping undecodable shared cache entry", slog.String("key", key), slog.Any("error", err))
+					continue
+				}
+				batch[strings.TrimPrefix(key, sharedKeyPrefix)] = order
+			}
+			report.SharedChecked += len(batch)
+			return s.verifyBatch(ctx, TierShared, batch, report)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if repair {
+		for i := range report.Mismatches {
+			mismatch := &report.Mismatches[i]
+			if err := s.Evict(ctx, mismatch.OrderUID); err != nil {
+				return nil, err
+			}
+			mismatch.Repaired = true
+		}
+	}
+
+	s.log(ctx).Info("Cache verified",
+		slog.Int("localChecked", report.LocalChecked),
+		slog.Int("sharedChecked", report.SharedChecked),
+		slog.Int("mismatches", len(report.Mismatches)),
+		slog.Bool("repair", repair))
+	return report, nil
+}
+
+// peekEntry возвращает запись локального кэша из индекса. В отличие от
+// getEntry обращение не засчитывается ristretto как попадание, поэтому сверка
+// не искажает долю попаданий в статистике кэша.
+func (s *cacheService) peekEntry(orderUID string) (*cacheEntry, bool) {
+	s.entriesMu.Lock()
+	defer s.entriesMu.Unlock()
+
+	entry, found := s.entries[orderUID]
+	return entry, found
+}
+
+// verifyBatch сравнивает заказы из кэша с их версиями в базе.
+func (s *cacheService) verifyBatch(ctx context.Context, tier string, cached map[string]*model.OrderDetails, report *VerifyReport) error {
+	if len(cached) == 0 {
+		return nil
+	}
+	orderIDs := make([]string, 0, len(cached))
+	for orderID := range cached {
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	fresh, err := s.db.GetOrders(ctx, orderIDs)
+	if err != nil {
+		return err
+	}
+	for _, orderID := range orderIDs {
+		order, ok := fresh[orderID]
+		if !ok {
+			report.Mismatches = append(report.Mismatches, CacheMismatch{OrderUID: orderID, Tier: tier, MissingInDB: true})
+			continue
+		}
+		if diffs := model.Diff(cached[orderID], order); len(diffs) > 0 {
+			report.Mismatches = append(report.Mismatches, CacheMismatch{OrderUID: orderID, Tier: tier, Diffs: diffs})
+		}
+	}
+	return nil
+}
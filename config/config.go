@@ -0,0 +1,331 @@
+// Package config собирает настройки сервиса и его команд в одну типизированную
+// структуру. Значения берутся по возрастанию приоритета из значений по
+// умолчанию, файла YAML или TOML, переменных окружения и флагов командной
+// строки.
+//
+// Каждое поле описывается тегами: key — имя в файле (внутри секции),
+// env — переменная окружения, usage — описание флага, secret — скрывать ли
+// значение при выводе (true — целиком, url — только пароль в адресе). Флаг
+// называется по пути в файле: db.max_conns задается флагом -db.max-conns.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	graphqlapi "github.com/Sh1ni-Gami/WB_Tech_L0/graphql"
+	httptransport "github.com/Sh1ni-Gami/WB_Tech_L0/transport"
+)
+
+// Config настройки сервиса.
+type Config struct {
+	DB      DBConfig      `key:"db"`
+	Kafka   KafkaConfig   `key:"kafka"`
+	Cache   CacheConfig   `key:"cache"`
+	HTTP    HTTPConfig    `key:"http"`
+	GRPC    GRPCConfig    `key:"grpc"`
+	GraphQL GraphQLConfig `key:"graphql"`
+	Auth    AuthConfig    `key:"auth"`
+	PII     PIIConfig     `key:"pii"`
+
+	// sources источник значения каждого ключа для вывода
+	sources map[string]string
+}
+
+// DBConfig подключение к Postgres и пул соединений.
+type DBConfig struct {
+	User     string `key:"user" env:"POSTGRES_USER" usage:"Postgres user"`
+	Password string `key:"password" env:"POSTGRES_PASSWORD" usage:"Postgres password" secret:"true"`
+	Address  string `key:"address" env:"POSTGRES_URL" usage:"Postgres host:port"`
+	Name     string `key:"name" env:"POSTGRES_DB" usage:"database name"`
+	SSLMode  string `key:"sslmode" env:"POSTGRES_SSLMODE" usage:"sslmode connection parameter; empty for the driver default"`
+
+	MaxConns          int           `key:"max_conns" env:"POSTGRES_MAX_CONNS" usage:"maximum pool size; 0 for the driver default"`
+	MinConns          int           `key:"min_conns" env:"POSTGRES_MIN_CONNS" usage:"connections kept open while idle"`
+	MaxConnLifetime   time.Duration `key:"max_conn_lifetime" env:"POSTGRES_MAX_CONN_LIFETIME" usage:"connection age after which it is closed; 0 for the driver default"`
+	MaxConnIdleTime   time.Duration `key:"max_conn_idle_time" env:"POSTGRES_MAX_CONN_IDLE_TIME" usage:"idle time after which a connection is closed; 0 for the driver default"`
+	HealthCheckPeriod time.Duration `key:"health_check_period" env:"POSTGRES_HEALTH_CHECK_PERIOD" usage:"interval of idle connection checks; 0 for the driver default"`
+}
+
+// KafkaConfig топик заказов, форматы сообщений и подписи.
+type KafkaConfig struct {
+	URL             string `key:"url" env:"KAFKA_URL" usage:"Kafka broker address"`
+	Topic           string `key:"topic" env:"KAFKA_TOPIC" usage:"orders topic"`
+	Partition       int    `key:"partition" env:"KAFKA_PARTITION" usage:"partition read by the service"`
+	Codec           string `key:"codec" env:"KAFKA_CODEC" usage:"format of produced orders: json, protobuf or avro"`
+	SchemaRegistry  string `key:"schema_registry" env:"KAFKA_SCHEMA_REGISTRY" usage:"Schema Registry URL or schema directory; enables avro" secret:"url"`
+	AvroSchemaID    int    `key:"avro_schema_id" env:"KAFKA_AVRO_SCHEMA_ID" usage:"schema ID of produced avro orders"`
+	SignatureMode   string `key:"signature_mode" env:"ORDER_SIGNATURE_MODE" usage:"handling of unsigned or badly signed orders: off, reject or quarantine"`
+	SigningKeyset   string `key:"signing_keyset" env:"ORDER_SIGNING_KEYSET" usage:"signing keyset file"`
+	QuarantineTopic string `key:"quarantine_topic" env:"KAFKA_QUARANTINE_TOPIC" usage:"topic for quarantined orders (default <topic>-quarantine)"`
+}
+
+// CacheConfig локальный и общий кэш заказов.
+type CacheConfig struct {
+	Size             int           `key:"size" env:"CACHE_SIZE" usage:"maximum number of orders in the local cache"`
+	EncodedResponses bool          `key:"encoded_responses" env:"CACHE_ENCODED_RESPONSES" usage:"keep ready JSON and gzip responses next to cached orders"`
+	L2URL            string        `key:"l2_url" env:"CACHE_L2_URL" usage:"Redis-compatible shared cache URL; empty disables it" secret:"url"`
+	L2TTL            time.Duration `key:"l2_ttl" env:"CACHE_L2_TTL" usage:"lifetime of shared cache entries"`
+}
+
+// HTTPConfig HTTP-сервер и его ограничения.
+type HTTPConfig struct {
+	Addr              string        `key:"addr" env:"HTTP_ADDR" usage:"HTTP listen address"`
+	IngestMode        string        `key:"ingest_mode" env:"HTTP_INGEST_MODE" usage:"HTTP order intake: kafka, direct or empty to disable"`
+	ReadHeaderTimeout time.Duration `key:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" usage:"timeout for reading request headers"`
+	ReadTimeout       time.Duration `key:"read_timeout" env:"HTTP_READ_TIMEOUT" usage:"timeout for reading a request"`
+	WriteTimeout      time.Duration `key:"write_timeout" env:"HTTP_WRITE_TIMEOUT" usage:"timeout for writing a response"`
+	IdleTimeout       time.Duration `key:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" usage:"keep-alive idle timeout"`
+	MaxHeaderBytes    int           `key:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES" usage:"maximum size of request headers"`
+	MaxBodyBytes      int64         `key:"max_body_bytes" env:"HTTP_MAX_BODY_BYTES" usage:"maximum request body size"`
+	MaxBatchBodyBytes int64         `key:"max_batch_body_bytes" env:"HTTP_MAX_BATCH_BODY_BYTES" usage:"maximum batch request body size"`
+	RateLimit         float64       `key:"rate_limit" env:"HTTP_RATE_LIMIT" usage:"requests per second per client; 0 disables the limit"`
+	RateBurst         int           `key:"rate_burst" env:"HTTP_RATE_BURST" usage:"burst of requests per client"`
+	MaxConcurrent     int           `key:"max_concurrent" env:"HTTP_MAX_CONCURRENT" usage:"maximum concurrently served requests; 0 disables the limit"`
+	TrustProxyHeaders bool          `key:"trust_proxy_headers" env:"HTTP_TRUST_PROXY_HEADERS" usage:"take the client address from X-Forwarded-For"`
+	TrustedProxyHops  int           `key:"trusted_proxy_hops" env:"HTTP_TRUSTED_PROXY_HOPS" usage:"number of trusted proxies appending to X-Forwarded-For"`
+}
+
+// GRPCConfig gRPC-сервер.
+type GRPCConfig struct {
+	Addr string `key:"addr" env:"GRPC_ADDR" usage:"gRPC listen address; empty disables the server"`
+}
+
+// GraphQLConfig эндпоинт GraphQL.
+type GraphQLConfig struct {
+	Enabled       bool `key:"enabled" env:"GRAPHQL_ENABLED" usage:"serve POST /graphql"`
+	MaxDepth      int  `key:"max_depth" env:"GRAPHQL_MAX_DEPTH" usage:"maximum query depth"`
+	MaxComplexity int  `key:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" usage:"maximum estimated query cost"`
+}
+
+// AuthConfig аутентификация HTTP API.
+type AuthConfig struct {
+	JWKSFile        string   `key:"jwks_file" env:"AUTH_JWKS_FILE" usage:"JWKS file for JWT bearer tokens"`
+	JWTIssuer       string   `key:"jwt_issuer" env:"AUTH_JWT_ISSUER" usage:"expected JWT issuer"`
+	JWTAudience     string   `key:"jwt_audience" env:"AUTH_JWT_AUDIENCE" usage:"expected JWT audience"`
+	APIKeys         string   `key:"api_keys" env:"AUTH_API_KEYS" usage:"static API keys: name:sha256hex:scope1,scope2;name2:sha256hex:scope3 (hex SHA-256 of the key)" secret:"true"`
+	AdminToken      string   `key:"admin_token" env:"ADMIN_TOKEN" usage:"API key with the orders:admin scope" secret:"true"`
+	DBAPIKeys       bool     `key:"db_api_keys" env:"AUTH_DB_API_KEYS" usage:"look up API keys in the api_keys table"`
+	AnonymousScopes []string `key:"anonymous_scopes" env:"AUTH_ANONYMOUS_SCOPES" usage:"comma-separated scopes of unauthenticated requests"`
+}
+
+// PIIConfig маскирование персональных данных в ответах.
+type PIIConfig struct {
+	DefaultProfile string `key:"default_profile" env:"PII_DEFAULT_PROFILE" usage:"masking profile of clients without a scope profile; empty disables masking"`
+	ScopeProfiles  string `key:"scope_profiles" env:"PII_SCOPE_PROFILES" usage:"masking profiles by scope: scope=profile,..."`
+	ProfilesFile   string `key:"profiles_file" env:"PII_PROFILES_FILE" usage:"file with custom masking profiles"`
+}
+
+// Default возвращает настройки по умолчанию.
+func Default() *Config {
+	limits := httptransport.DefaultLimits()
+	return &Config{
+		DB: DBConfig{
+			User:    "postgres",
+			Address: "localhost:5432",
+			Name:    "wb_tech",
+		},
+		Kafka: KafkaConfig{
+			URL:           "localhost:9092",
+			Topic:         "wb-topic",
+			Codec:         "json",
+			SignatureMode: "off",
+		},
+		Cache: CacheConfig{
+			Size:             1024,
+			EncodedResponses: true,
+			L2TTL:            24 * time.Hour,
+		},
+		HTTP: HTTPConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: limits.ReadHeaderTimeout,
+			ReadTimeout:       limits.ReadTimeout,
+			WriteTimeout:      limits.WriteTimeout,
+			IdleTimeout:       limits.IdleTimeout,
+			MaxHeaderBytes:    limits.MaxHeaderBytes,
+			MaxBodyBytes:      limits.MaxBodyBytes,
+			MaxBatchBodyBytes: limits.MaxBatchBodyBytes,
+			RateLimit:         limits.RateLimit,
+			RateBurst:         limits.RateBurst,
+			MaxConcurrent:     limits.MaxConcurrent,
+			TrustProxyHeaders: limits.TrustProxyHeaders,
+			TrustedProxyHops:  limits.TrustedProxyHops,
+		},
+		GRPC: GRPCConfig{Addr: ":9090"},
+		GraphQL: GraphQLConfig{
+			Enabled:       true,
+			MaxDepth:      graphqlapi.DefaultMaxDepth,
+			MaxComplexity: graphqlapi.DefaultMaxComplexity,
+		},
+		Auth: AuthConfig{AnonymousScopes: []string{httptransport.ScopeOrdersRead}},
+	}
+}
+
+// ConnString строка подключения к Postgres с настройками пула.
+func (c DBConfig) ConnString() string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   c.Address,
+		Path:   "/" + c.Name,
+	}
+	query := url.Values{}
+	if c.SSLMode != "" {
+		query.Set("sslmode", c.SSLMode)
+	}
+	if c.MaxConns > 0 {
+		query.Set("pool_max_conns", strconv.Itoa(c.MaxConns))
+	}
+	if c.MinConns > 0 {
+		query.Set("pool_min_conns", strconv.Itoa(c.MinConns))
+	}
+	durations := map[string]time.Duration{
+		"pool_max_conn_lifetime":   c.MaxConnLifetime,
+		"pool_max_conn_idle_time":  c.MaxConnIdleTime,
+		"pool_health_check_period": c.HealthCheckPeriod,
+	}
+	for name, value := range durations {
+		if value > 0 {
+			query.Set(name, value.String())
+		}
+	}
+	dsn.RawQuery = query.Encode()
+	return dsn.String()
+}
+
+// QuarantineTopicName топик карантина с учетом значения по умолчанию.
+func (c KafkaConfig) QuarantineTopicName() string {
+	if c.QuarantineTopic != "" {
+		return c.QuarantineTopic
+	}
+	return c.Topic + "-quarantine"
+}
+
+// Limits ограничения HTTP-сервера для httptransport.WithLimits.
+func (c HTTPConfig) Limits() httptransport.Limits {
+	return httptransport.Limits{
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		MaxBodyBytes:      c.MaxBodyBytes,
+		MaxBatchBodyBytes: c.MaxBatchBodyBytes,
+		RateLimit:         c.RateLimit,
+		RateBurst:         c.RateBurst,
+		MaxConcurrent:     c.MaxConcurrent,
+		TrustProxyHeaders: c.TrustProxyHeaders,
+		TrustedProxyHops:  c.TrustedProxyHops,
+	}
+}
+
+// Validate проверяет все настройки сразу и возвращает все найденные ошибки
+// одной, по строке на ключ.
+func (c *Config) Validate() error {
+	var v validator
+
+	v.check(c.DB.Address != "", "db.address", "must not be empty")
+	if c.DB.Address != "" {
+		_, _, err := net.SplitHostPort(c.DB.Address)
+		v.check(err == nil, "db.address", "must be host:port")
+	}
+	v.check(c.DB.Name != "", "db.name", "must not be empty")
+	v.oneOf(c.DB.SSLMode, "db.sslmode", "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.nonNegative(c.DB.MaxConns, "db.max_conns")
+	v.nonNegative(c.DB.MinConns, "db.min_conns")
+	v.check(c.DB.MaxConns == 0 || c.DB.MinConns <= c.DB.MaxConns, "db.min_conns", "must not exceed db.max_conns")
+	v.nonNegative(c.DB.MaxConnLifetime, "db.max_conn_lifetime")
+	v.nonNegative(c.DB.MaxConnIdleTime, "db.max_conn_idle_time")
+	v.nonNegative(c.DB.HealthCheckPeriod, "db.health_check_period")
+
+	v.check(c.Kafka.URL != "", "kafka.url", "must not be empty")
+	v.check(c.Kafka.Topic != "", "kafka.topic", "must not be empty")
+	v.nonNegative(c.Kafka.Partition, "kafka.partition")
+	v.oneOf(c.Kafka.Codec, "kafka.codec", "json", "protobuf", "avro")
+	if c.Kafka.Codec == "avro" {
+		v.check(c.Kafka.SchemaRegistry != "", "kafka.schema_registry", "is required by kafka.codec avro")
+		v.check(c.Kafka.AvroSchemaID > 0, "kafka.avro_schema_id", "is required by kafka.codec avro")
+	}
+	v.nonNegative(c.Kafka.AvroSchemaID, "kafka.avro_schema_id")
+	v.oneOf(c.Kafka.SignatureMode, "kafka.signature_mode", "off", "reject", "quarantine")
+	if c.Kafka.SignatureMode != "off" {
+		v.check(c.Kafka.SigningKeyset != "", "kafka.signing_keyset", "is required by kafka.signature_mode "+c.Kafka.SignatureMode)
+	}
+
+	v.check(c.Cache.Size > 0, "cache.size", "must be positive")
+	if c.Cache.L2URL != "" {
+		v.check(c.Cache.L2TTL > 0, "cache.l2_ttl", "must be positive")
+	}
+
+	v.check(c.HTTP.Addr != "", "http.addr", "must not be empty")
+	v.oneOf(c.HTTP.IngestMode, "http.ingest_mode", "", string(httptransport.IngestKafka), string(httptransport.IngestDirect))
+	v.nonNegative(c.HTTP.ReadHeaderTimeout, "http.read_header_timeout")
+	v.nonNegative(c.HTTP.ReadTimeout, "http.read_timeout")
+	v.nonNegative(c.HTTP.WriteTimeout, "http.write_timeout")
+	v.nonNegative(c.HTTP.IdleTimeout, "http.idle_timeout")
+	v.nonNegative(c.HTTP.MaxHeaderBytes, "http.max_header_bytes")
+	v.check(c.HTTP.MaxBodyBytes > 0, "http.max_body_bytes", "must be positive")
+	v.check(c.HTTP.MaxBatchBodyBytes > 0, "http.max_batch_body_bytes", "must be positive")
+	v.nonNegative(c.HTTP.RateLimit, "http.rate_limit")
+	v.nonNegative(c.HTTP.RateBurst, "http.rate_burst")
+	v.nonNegative(c.HTTP.MaxConcurrent, "http.max_concurrent")
+	if c.HTTP.TrustProxyHeaders {
+		v.check(c.HTTP.TrustedProxyHops > 0, "http.trusted_proxy_hops", "must be positive")
+	}
+
+	if c.Auth.APIKeys != "" {
+		// Текст ошибки разбора не выводится: в нем была бы запись с ключом
+		_, err := httptransport.ParseStaticAPIKeys(c.Auth.APIKeys)
+		v.check(err == nil, "auth.api_keys", "must be name:sha256hex:scope1,scope2;...")
+	}
+
+	v.check(c.GraphQL.MaxDepth > 0, "graphql.max_depth", "must be positive")
+	v.check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity", "must be positive")
+
+	return errors.Join(v.errs...)
+}
+
+// validator накапливает ошибки проверки.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, key, message string) {
+	if !ok {
+		v.errs = append(v.errs, fieldError(key, message))
+	}
+}
+
+func (v *validator) oneOf(value, key string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.errs = append(v.errs, fieldError(key, fmt.Sprintf("unsupported value %q", value)))
+}
+
+func (v *validator) nonNegative(value any, key string) {
+	var negative bool
+	switch value := value.(type) {
+	case int:
+		negative = value < 0
+	case time.Duration:
+		negative = value < 0
+	case float64:
+		negative = value < 0
+	}
+	v.check(!negative, key, "must not be negative")
+}
+
+// fieldError ошибка значения ключа; указывает и переменную окружения ключа.
+func fieldError(key, message string) error {
+	if f, ok := fieldsByKey[key]; ok && f.env != "" {
+		return fmt.Errorf("%s (%s): %s", key, f.env, message)
+	}
+	return fmt.Errorf("%s: %s", key, message)
+}
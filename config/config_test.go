@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDefault(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("default configuration: %v", err)
+	}
+}
+
+func TestValidateJoinsErrors(t *testing.T) {
+	cfg := Default()
+	cfg.DB.Address = "localhost"
+	cfg.DB.MaxConns = 2
+	cfg.DB.MinConns = 5
+	cfg.Kafka.Codec = "avro"
+	cfg.Cache.Size = 0
+	cfg.HTTP.RateLimit = -1
+	cfg.HTTP.TrustProxyHeaders = true
+	cfg.HTTP.TrustedProxyHops = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded, want errors")
+	}
+	want := []string{
+		"db.address (POSTGRES_URL): must be host:port",
+		"db.min_conns (POSTGRES_MIN_CONNS): must not exceed db.max_conns",
+		"kafka.schema_registry (KAFKA_SCHEMA_REGISTRY): is required by kafka.codec avro",
+		"kafka.avro_schema_id (KAFKA_AVRO_SCHEMA_ID): is required by kafka.codec avro",
+		"cache.size (CACHE_SIZE): must be positive",
+		"http.rate_limit (HTTP_RATE_LIMIT): must not be negative",
+		"http.trusted_proxy_hops (HTTP_TRUSTED_PROXY_HOPS): must be positive",
+	}
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != len(want) {
+		t.Errorf("%d errors, want one line per key:\n%v", len(lines), err)
+	}
+	for _, line := range want {
+		if !strings.Contains(err.Error(), line) {
+			t.Errorf("error\n%v\nwant it to contain %q", err, line)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Источники значений в порядке возрастания приоритета.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// FileEnv переменная окружения с путем к файлу настроек; флаг -config
+// имеет приоритет над ней.
+const FileEnv = "CONFIG_FILE"
+
+// field описание одного ключа настроек.
+type field struct {
+	key    string
+	env    string
+	usage  string
+	secret string
+	// index путь к полю в Config для reflect.Value.FieldByIndex
+	index []int
+	typ   reflect.Type
+}
+
+// fields ключи настроек в порядке объявления, fieldsByKey — по имени.
+var fields, fieldsByKey = collectFields()
+
+func collectFields() ([]field, map[string]field) {
+	var list []field
+	byKey := make(map[string]field)
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		section := configType.Field(i)
+		sectionKey := section.Tag.Get("key")
+		if sectionKey == "" {
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			sf := section.Type.Field(j)
+			f := field{
+				key:    sectionKey + "." + sf.Tag.Get("key"),
+				env:    sf.Tag.Get("env"),
+				usage:  sf.Tag.Get("usage"),
+				secret: sf.Tag.Get("secret"),
+				index:  []int{i, j},
+				typ:    sf.Type,
+			}
+			list = append(list, f)
+			byKey[f.key] = f
+		}
+	}
+	return list, byKey
+}
+
+// flagName имя флага ключа: db.max_conns — db.max-conns.
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+// section секция ключа: db для db.max_conns.
+func (f field) section() string {
+	section, _, _ := strings.Cut(f.key, ".")
+	return section
+}
+
+// Loader читает настройки из файла, окружения и флагов.
+type Loader struct {
+	flags    *flag.FlagSet
+	file     *string
+	sections map[string]bool
+	defaults []func(*Config)
+	required []string
+}
+
+// Option настраивает Loader.
+type Option func(*Loader)
+
+// WithFlags регистрирует в flags флаг -config и флаги ключей секций sections
+// (всех, если секции не указаны). Load нужно вызывать после flags.Parse.
+func WithFlags(flags *flag.FlagSet, sections ...string) Option {
+	return func(l *Loader) {
+		l.flags = flags
+		l.sections = make(map[string]bool, len(sections))
+		for _, section := range sections {
+			l.sections[section] = true
+		}
+	}
+}
+
+// WithDefaults меняет значения по умолчанию, например адрес брокера для
+// утилит, запускаемых вне docker compose.
+func WithDefaults(fn func(*Config)) Option {
+	return func(l *Loader) {
+		l.defaults = append(l.defaults, fn)
+	}
+}
+
+// WithHostDefaults задает значения по умолчанию для утилит, запускаемых на
+// машине разработчика рядом с docker compose: брокер доступен снаружи через
+// порт 9094.
+func WithHostDefaults() Option {
+	return WithDefaults(func(cfg *Config) {
+		cfg.Kafka.URL = "localhost:9094"
+	})
+}
+
+// WithRequired требует непустых значений ключей, например db.password для
+// команд, которым нужна база.
+func WithRequired(keys ...string) Option {
+	return func(l *Loader) {
+		l.Require(keys...)
+	}
+}
+
+// Require добавляет обязательные ключи, когда они зависят от разобранных
+// флагов команды; вызывается до Load.
+func (l *Loader) Require(keys ...string) {
+	l.required = append(l.required, keys...)
+}
+
+// NewLoader создает Loader. Флаги регистрируются сразу, поэтому их значения
+// по умолчанию в справке — значения Default, а не итоговые.
+func NewLoader(opts ...Option) *Loader {
+	l := &Loader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.flags == nil {
+		return l
+	}
+
+	l.file = l.flags.String("config", "", "YAML or TOML configuration file (default $"+FileEnv+")")
+	defaults := l.defaultConfig()
+	for _, f := range fields {
+		if len(l.sections) > 0 && !l.sections[f.section()] {
+			continue
+		}
+		usage := f.usage
+		if f.env != "" {
+			usage += " ($" + f.env + ")"
+		}
+		value := &flagValue{typ: f.typ, value: formatValue(reflect.ValueOf(defaults).Elem().FieldByIndex(f.index), f.secret)}
+		l.flags.Var(value, f.flagName(), usage)
+	}
+	return l
+}
+
+// Load собирает настройки: значения по умолчанию, затем файл, окружение и
+// флаги, и проверяет результат. Все ошибки чтения и проверки возвращаются
+// вместе.
+func (l *Loader) Load() (*Config, error) {
+	cfg := l.defaultConfig()
+	cfg.sources = make(map[string]string, len(fields))
+	for _, f := range fields {
+		cfg.sources[f.key] = SourceDefault
+	}
+
+	var errs []error
+	path := os.Getenv(FileEnv)
+	if l.file != nil && *l.file != "" {
+		path = *l.file
+	}
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			raw := values[key]
+			f, ok := fieldsByKey[key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown key %s", path, key))
+				continue
+			}
+			errs = append(errs, cfg.set(f, raw, SourceFile))
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(f.env); ok {
+			errs = append(errs, cfg.set(f, value, SourceEnv))
+		}
+	}
+
+	if l.flags != nil {
+		l.flags.Visit(func(fl *flag.Flag) {
+			value, ok := fl.Value.(*flagValue)
+			if !ok {
+				return
+			}
+			for _, f := range fields {
+				if f.flagName() == fl.Name {
+					errs = append(errs, cfg.set(f, value.value, SourceFlag))
+				}
+			}
+		})
+	}
+
+	for _, key := range l.required {
+		f, ok := fieldsByKey[key]
+		if ok && reflect.ValueOf(cfg).Elem().FieldByIndex(f.index).IsZero() {
+			errs = append(errs, fieldError(key, "is required"))
+		}
+	}
+	errs = append(errs, cfg.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (l *Loader) defaultConfig() *Config {
+	cfg := Default()
+	for _, fn := range l.defaults {
+		fn(cfg)
+	}
+	return cfg
+}
+
+// Source возвращает источник значения ключа: default, file, env или flag.
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// set записывает значение ключа. raw — строка из окружения или флага либо
+// значение из файла.
+func (c *Config) set(f field, raw any, source string) error {
+	target := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	if err := setValue(target, raw); err != nil {
+		where := source
+		if source == SourceEnv {
+			where = f.env
+		} else if source == SourceFlag {
+			where = "-" + f.flagName()
+		}
+		return fmt.Errorf("%s (%s): %w", f.key, where, err)
+	}
+	if c.sources != nil {
+		c.sources[f.key] = source
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue разбирает значение по типу поля. Списки из файла принимаются
+// как есть, строки окружения и флагов разделяются запятыми.
+func setValue(target reflect.Value, raw any) error {
+	if target.Kind() == reflect.Slice {
+		var items []string
+		switch raw := raw.(type) {
+		case []any:
+			for _, item := range raw {
+				items = append(items, fmt.Sprint(item))
+			}
+		default:
+			for _, item := range strings.Split(fmt.Sprint(raw), ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		target.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	var value string
+	switch raw := raw.(type) {
+	case string:
+		value = raw
+	case nil:
+		value = ""
+	case []any, map[string]any, []map[string]any:
+		return errors.New("must be a scalar value")
+	default:
+		value = fmt.Sprint(raw)
+	}
+
+	switch {
+	case target.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		target.SetInt(int64(d))
+	case target.Kind() == reflect.String:
+		target.SetString(value)
+	case target.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		target.SetBool(b)
+	case target.Kind() == reflect.Int || target.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		target.SetInt(n)
+	case target.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		target.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
+
+// flagValue флаг ключа настроек. Значение проверяется при разборе флагов,
+// а применяется в Load поверх файла и окружения.
+type flagValue struct {
+	typ   reflect.Type
+	value string
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(value string) error {
+	if err := setValue(reflect.New(v.typ).Elem(), value); err != nil {
+		return err
+	}
+	v.value = value
+	return nil
+}
+
+// IsBoolFlag позволяет писать -cache.encoded-responses без значения.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.typ.Kind() == reflect.Bool
+}
+
+// readFile читает файл настроек в плоский набор ключей section.key. Формат
+// выбирается по расширению: .yaml, .yml или .toml.
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("%s: unsupported configuration format %q: use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]any)
+	flatten("", tree, values)
+	return values, nil
+}
+
+// flatten раскладывает вложенные таблицы в ключи через точку.
+func flatten(prefix string, tree map[string]any, values map[string]any) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, values)
+			continue
+		}
+		values[key] = value
+	}
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	httptransport "github.com/Sh1ni-Gami/WB_Tech_L0/transport"
+)
+
+// writeFile создает файл настроек во временном каталоге теста.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+// load собирает настройки с флагами args.
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(WithFlags(flags))
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	return loader.Load()
+}
+
+func TestReadFileTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+# Комментарий # с решеткой
+[db]
+address = "db:5432" # после значения
+name = 'literal # not a comment'
+max_conns = 1_000
+
+[http]
+rate_limit = 2.5
+trust_proxy_headers = true
+read_timeout = "30s"
+
+[auth]
+anonymous_scopes = [
+  "orders:read",
+  "orders:write", # многострочный массив
+]
+
+[kafka.nested]
+key = 1
+`)
+	values, err := readFile(path)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+
+	want := map[string]any{
+		"db.address":               "db:5432",
+		"db.name":                  "literal # not a comment",
+		"db.max_conns":             int64(1000),
+		"http.rate_limit":          2.5,
+		"http.trust_proxy_headers": true,
+		"http.read_timeout":        "30s",
+		"auth.anonymous_scopes":    []any{"orders:read", "orders:write"},
+		"kafka.nested.key":         int64(1),
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values\n%#v\nwant\n%#v", values, want)
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{name: "unterminated string", file: "bad.toml", content: "[db]\naddress = \"db:5432\n", wantErr: "bad.toml"},
+		{name: "duplicate key", file: "dup.toml", content: "[db]\nname = \"a\"\nname = \"b\"\n", wantErr: "dup.toml"},
+		{name: "missing value", file: "empty.toml", content: "[db]\nname =\n", wantErr: "empty.toml"},
+		{name: "invalid yaml", file: "bad.yaml", content: "db: [", wantErr: "bad.yaml"},
+		{name: "unknown format", file: "config.json", content: "{}", wantErr: "unsupported configuration format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readFile(writeFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[kafka]
+topic = "file-topic"
+
+[cache]
+size = 10
+l2_ttl = "1m"
+
+[http]
+addr = ":9000"
+`)
+	t.Setenv("KAFKA_TOPIC", "env-topic")
+	t.Setenv("CACHE_SIZE", "20")
+
+	cfg, err := load(t, "-config", path, "-kafka.topic", "flag-topic")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		key        string
+		got, want  any
+		wantSource string
+	}{
+		{key: "kafka.topic", got: cfg.Kafka.Topic, want: "flag-topic", wantSource: SourceFlag},
+		{key: "cache.size", got: cfg.Cache.Size, want: 20, wantSource: SourceEnv},
+		{key: "cache.l2_ttl", got: cfg.Cache.L2TTL, want: time.Minute, wantSource: SourceFile},
+		{key: "http.addr", got: cfg.HTTP.Addr, want: ":9000", wantSource: SourceFile},
+		{key: "db.name", got: cfg.DB.Name, want: "wb_tech", wantSource: SourceDefault},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+		if source := cfg.Source(tt.key); source != tt.wantSource {
+			t.Errorf("%s source %s, want %s", tt.key, source, tt.wantSource)
+		}
+	}
+}
+
+func TestLoadFileFromEnv(t *testing.T) {
+	t.Setenv(FileEnv, writeFile(t, "config.yaml", "http:\n  addr: \":9001\"\n"))
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.HTTP.Addr != ":9001" || cfg.Source("http.addr") != SourceFile {
+		t.Errorf("http.addr %q from %s, want :9001 from the file", cfg.HTTP.Addr, cfg.Source("http.addr"))
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[db]
+unknown = 1
+max_conns = [1, 2]
+
+[graphql]
+max_depth = 0
+`)
+	t.Setenv("CACHE_SIZE", "many")
+	t.Setenv("KAFKA_CODEC", "xml")
+
+	_, err := load(t, "-config", path)
+	if err == nil {
+		t.Fatal("Load succeeded, want errors")
+	}
+	for _, want := range []string{
+		"unknown key db.unknown",
+		"db.max_conns (file): must be a scalar value",
+		`cache.size (CACHE_SIZE): invalid integer "many"`,
+		`kafka.codec (KAFKA_CODEC): unsupported value "xml"`,
+		"graphql.max_depth (GRAPHQL_MAX_DEPTH): must be positive",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error\n%v\nwant it to contain %q", err, want)
+		}
+	}
+}
+
+func TestLoadStaticAPIKeys(t *testing.T) {
+	reader := httptransport.HashAPIKey("reader-key")
+	admin := httptransport.HashAPIKey("admin-key")
+	path := writeFile(t, "config.toml", fmt.Sprintf(`
+[auth]
+api_keys = "reader:%s:orders:read; admin:%s:orders:read,orders:admin"
+`, reader, admin))
+
+	cfg, err := load(t, "-config", path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	keys, err := httptransport.ParseStaticAPIKeys(cfg.Auth.APIKeys)
+	if err != nil {
+		t.Fatalf("ParseStaticAPIKeys: %v", err)
+	}
+	want := httptransport.StaticAPIKeys{
+		reader: {Name: "reader", Scopes: []string{"orders:read"}},
+		admin:  {Name: "admin", Scopes: []string{"orders:read", "orders:admin"}},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys %+v, want %+v", keys, want)
+	}
+
+	// Сырой ключ вместо хэша отклоняется при загрузке, а не при запуске сервера
+	t.Setenv("AUTH_API_KEYS", "reader:reader-key:orders:read")
+	if _, err := load(t, "-config", path); err == nil || !strings.Contains(err.Error(), "auth.api_keys (AUTH_API_KEYS)") {
+		t.Errorf("raw key: error %v, want auth.api_keys", err)
+	} else if strings.Contains(err.Error(), "reader-key") {
+		t.Errorf("error reveals the key: %v", err)
+	}
+}
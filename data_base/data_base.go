@@ -2,21 +2,46 @@ package data_base
 
 import (
 	"context"
-	"time"
+	"errors"
+	"fmt"
+	"strings"
 
 	"log/slog"
 
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
 	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DBService интерфейс для работы с базой данных.
 type DBService interface {
-	AddOrder(order *model.OrderDetails) error
-	GetOrder(orderUID string) (*model.OrderDetails, error)
-	GetRecentOrderIDs(limit int) ([]string, error)
+	AddOrder(ctx context.Context, order *model.OrderDetails) error
+	UpdateItemStatuses(ctx context.Context, previous, next *model.OrderDetails) error
+	GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error)
+	GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error)
+	GetRecentOrderIDs(ctx context.Context, limit int) ([]string, error)
+	GetOrderIDsByCustomer(ctx context.Context, customerID string) ([]string, error)
+	LookupAPIKey(ctx context.Context, keyHash string) (name string, scopes []string, err error)
+	ExportOrders(ctx context.Context, filter model.OrderFilter, fn func(*model.OrderDetails) error) error
+	ListOrders(ctx context.Context, filter model.OrderFilter) ([]*model.OrderDetails, error)
+	ListenNewOrders(ctx context.Context, fn func(orderUID string)) error
+	FindOrphans(ctx context.Context) (Orphans, error)
+	DeleteOrphans(ctx context.Context, orphans Orphans) (int64, error)
 }
 
+// Orphans строки delivery и payment, на которые не ссылается ни один заказ.
+// Остаются после ручного удаления заказов и прерванных миграций.
+type Orphans struct {
+	DeliveryIDs         []int    `json:"delivery_ids"`
+	PaymentTransactions []string `json:"payment_transactions"`
+}
+
+// newOrdersChannel канал LISTEN/NOTIFY, в который AddOrder публикует order_uid
+// сохраненного заказа.
+const newOrdersChannel = "orders_added"
+
 type dbService struct {
 	pool   *pgxpool.Pool
 	logger *slog.Logger
@@ -35,17 +60,24 @@ func New(connString string, logger *slog.Logger) (DBService, error) {
 	}, nil
 }
 
-// AddOrder добавляет заказ в базу данных.
-func (s *dbService) AddOrder(order *model.OrderDetails) error {
-	tx, err := s.pool.Begin(context.Background())
+// log возвращает логгер запроса из контекста, если он есть.
+func (s *dbService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// AddOrder добавляет заказ в базу данных. Повторно присланный заказ не
+// сохраняется: order_uid — ключ идемпотентности, повтор распознается по
+// нарушению уникальности и возвращается как model.ErrDuplicateOrder.
+func (s *dbService) AddOrder(ctx context.Context, order *model.OrderDetails) error {
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
 
 	// Добавление AddressDetails
 	var deliveryID int
-	err = tx.QueryRow(context.Background(),
+	err = tx.QueryRow(ctx,
 		`INSERT INTO delivery (name, phone, zip, city, address, region, email)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7)
 		 RETURNING id`,
@@ -53,59 +85,183 @@ func (s *dbService) AddOrder(order *model.OrderDetails) error {
 		order.Address.City, order.Address.Street, order.Address.Region, order.Address.Email).
 		Scan(&deliveryID)
 	if err != nil {
-		s.logger.Error("Failed to insert delivery", slog.Any("error", err))
+		s.log(ctx).Error("Failed to insert delivery", slog.Any("error", err))
 		return err
 	}
 
 	// Добавление PaymentDetails
-	_, err = tx.Exec(context.Background(),
+	_, err = tx.Exec(ctx,
 		`INSERT INTO payment (transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
 		order.Payment.TransactionID, order.Payment.RequestID, order.Payment.Currency,
 		order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDate,
 		order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.TotalGoods, order.Payment.CustomFee)
+	if constraint, ok := uniqueViolation(err); ok && constraint == paymentKey {
+		// Оплата вставляется раньше заказа, поэтому повторно присланный заказ
+		// чаще всего упирается в ее ключ, а не в ключ заказа
+		return s.paymentConflict(ctx, order)
+	}
 	if err != nil {
-		s.logger.Error("Failed to insert payment", slog.Any("error", err))
+		s.log(ctx).Error("Failed to insert payment", slog.Any("error", err))
 		return err
 	}
 
 	// Добавление OrderDetails
-	_, err = tx.Exec(context.Background(),
+	_, err = tx.Exec(ctx,
 		`INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
 		order.OrderID, order.TrackingNumber, order.EntryPoint, deliveryID, order.Payment.TransactionID,
 		order.Locale, order.Signature, order.CustomerID,
-		order.DeliveryService, order.ShardKey, order.SMID, time.Time(order.CreationTimestamp), order.OutOfShard)
+		order.DeliveryService, order.ShardKey, order.SMID, order.CreationTimestamp, order.OutOfShard)
+	if constraint, ok := uniqueViolation(err); ok && constraint == ordersKey {
+		return model.ErrDuplicateOrder
+	}
 	if err != nil {
-		s.logger.Error("Failed to insert order", slog.Any("error", err))
+		s.log(ctx).Error("Failed to insert order", slog.Any("error", err))
 		return err
 	}
 
-	// Добавление ProductItem
+	// Добавление ProductItem и связи с заказом. Строка товара общая для всех
+	// заказов с тем же chrt_id, поэтому товар с другими полями отклоняется
 	for _, item := range order.Products {
-		_, err = tx.Exec(context.Background(),
+		tag, err := tx.Exec(ctx,
 			`INSERT INTO items (chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			 ON CONFLICT (chrt_id) DO NOTHING`,
 			item.ChartID, item.TrackingNum, item.Price, item.RID, item.Name,
 			item.Discount, item.Size, item.TotalPrice, item.ProductID, item.Brand, item.Status)
 		if err != nil {
-			s.logger.Error("Failed to insert item", slog.Any("error", err))
+			s.log(ctx).Error("Failed to insert item", slog.Any("error", err))
 			return err
 		}
+		if tag.RowsAffected() == 0 {
+			if err := sameItem(ctx, tx, item); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx,
+			`INSERT INTO order_item_conn (order_uid, chrt_id) VALUES ($1, $2)
+			 ON CONFLICT DO NOTHING`,
+			order.OrderID, item.ChartID)
+		if err != nil {
+			s.log(ctx).Error("Failed to link item to order", slog.Any("error", err))
+			return err
+		}
+	}
+
+	// Уведомление доставляется подписчикам только после фиксации транзакции
+	if _, err = tx.Exec(ctx, `SELECT pg_notify($1, $2)`, newOrdersChannel, order.OrderID); err != nil {
+		s.log(ctx).Error("Failed to notify about new order", slog.Any("error", err))
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		s.log(ctx).Error("Failed to commit transaction", slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
+
+// Ограничения, нарушение которых AddOrder переводит в ошибки model.
+const (
+	uniqueViolationCode = "23505"
+	ordersKey           = "orders_pkey"
+	paymentKey          = "payment_pkey"
+)
+
+// uniqueViolation возвращает имя ограничения, если err — нарушение уникальности.
+func uniqueViolation(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return pgErr.ConstraintName, true
 	}
+	return "", false
+}
 
-	err = tx.Commit(context.Background())
+// paymentConflict разбирает нарушение ключа оплаты: если заказ уже сохранен,
+// это повтор, иначе транзакция принадлежит другому заказу. Проверка идет вне
+// прерванной транзакции и после фиксации конкурирующей, поэтому гонки нет.
+func (s *dbService) paymentConflict(ctx context.Context, order *model.OrderDetails) error {
+	var exists bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE order_uid = $1)`, order.OrderID).
+		Scan(&exists)
 	if err != nil {
-		s.logger.Error("Failed to commit transaction", slog.Any("error", err))
+		s.log(ctx).Error("Failed to check order existence", slog.Any("error", err))
 		return err
 	}
+	if exists {
+		return model.ErrDuplicateOrder
+	}
+	return fmt.Errorf("%w: payment transaction %s belongs to another order", model.ErrConflictingData, order.Payment.TransactionID)
+}
 
+// sameItem проверяет, что уже сохраненный товар с тем же chrt_id совпадает
+// с присланным.
+func sameItem(ctx context.Context, tx pgx.Tx, item model.ProductItem) error {
+	var same bool
+	err := tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM items WHERE chrt_id = $1 AND track_number = $2 AND price = $3 AND rid = $4
+		   AND name = $5 AND sale = $6 AND size = $7 AND total_price = $8 AND nm_id = $9 AND brand = $10 AND status = $11)`,
+		item.ChartID, item.TrackingNum, item.Price, item.RID, item.Name,
+		item.Discount, item.Size, item.TotalPrice, item.ProductID, item.Brand, item.Status).
+		Scan(&same)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("%w: item %d differs from the stored one", model.ErrConflictingData, item.ChartID)
+	}
+	return nil
+}
+
+// UpdateItemStatuses сохраняет статусы товаров заказа next, изменившиеся
+// относительно previous. Статус меняется, только если в базе все еще
+// прежний: при одновременном изменении возвращается model.ErrConflictingData.
+// Допустимость переходов проверяет вызывающий.
+func (s *dbService) UpdateItemStatuses(ctx context.Context, previous, next *model.OrderDetails) error {
+	before := make(map[int]model.ItemStatus, len(previous.Products))
+	for _, item := range previous.Products {
+		before[item.ChartID] = item.Status
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, item := range next.Products {
+		from, ok := before[item.ChartID]
+		if !ok || from == item.Status {
+			continue
+		}
+		tag, err := tx.Exec(ctx,
+			`UPDATE items SET status = $1
+			 WHERE chrt_id = $2 AND status = $3
+			   AND EXISTS (SELECT 1 FROM order_item_conn WHERE order_uid = $4 AND chrt_id = $2)`,
+			item.Status, item.ChartID, from, next.OrderID)
+		if err != nil {
+			s.log(ctx).Error("Failed to update item status", slog.Int("chrtID", item.ChartID), slog.Any("error", err))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%w: status of item %d has changed concurrently", model.ErrConflictingData, item.ChartID)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log(ctx).Error("Failed to commit transaction", slog.Any("error", err))
+		return err
+	}
 	return nil
 }
 
 // GetOrder получает заказ по UID.
-func (s *dbService) GetOrder(orderUID string) (*model.OrderDetails, error) {
-	row := s.pool.QueryRow(context.Background(), `SELECT * FROM orders WHERE order_uid = $1`, orderUID)
+func (s *dbService) GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error) {
+	row := s.pool.QueryRow(ctx, `SELECT * FROM orders WHERE order_uid = $1`, orderUID)
 	var orderRecord struct {
 		OrderUID        string
 		TrackNumber     string
@@ -118,7 +274,7 @@ func (s *dbService) GetOrder(orderUID string) (*model.OrderDetails, error) {
 		DeliveryService string
 		ShardKey        string
 		SmID            int
-		DateCreated     time.Time
+		DateCreated     model.ISO8601Time
 		OofShard        string
 	}
 
@@ -126,35 +282,38 @@ func (s *dbService) GetOrder(orderUID string) (*model.OrderDetails, error) {
 		&orderRecord.PaymentID, &orderRecord.Locale, &orderRecord.InternalSig, &orderRecord.CustomerID,
 		&orderRecord.DeliveryService, &orderRecord.ShardKey, &orderRecord.SmID, &orderRecord.DateCreated,
 		&orderRecord.OofShard); err != nil {
-		s.logger.Error("Failed to fetch order", slog.String("order_uid", orderUID), slog.Any("error", err))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrOrderNotFound
+		}
+		s.log(ctx).Error("Failed to fetch order", slog.String("order_uid", orderUID), slog.Any("error", err))
 		return nil, err
 	}
 
 	// Fetch delivery details
 	var deliveryRecord model.AddressDetails
-	err := s.pool.QueryRow(context.Background(), `SELECT name, phone, zip, city, address, region, email FROM delivery WHERE id = $1`, orderRecord.DeliveryID).
+	err := s.pool.QueryRow(ctx, `SELECT name, phone, zip, city, address, region, email FROM delivery WHERE id = $1`, orderRecord.DeliveryID).
 		Scan(&deliveryRecord.FullName, &deliveryRecord.Phone, &deliveryRecord.ZipCode, &deliveryRecord.City,
 			&deliveryRecord.Street, &deliveryRecord.Region, &deliveryRecord.Email)
 	if err != nil {
-		s.logger.Error("Failed to fetch delivery", slog.Any("error", err))
+		s.log(ctx).Error("Failed to fetch delivery", slog.Any("error", err))
 		return nil, err
 	}
 
 	// Fetch payment details
 	var paymentRecord model.PaymentDetails
-	err = s.pool.QueryRow(context.Background(), `SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM payment WHERE transaction = $1`, orderRecord.PaymentID).
+	err = s.pool.QueryRow(ctx, `SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM payment WHERE transaction = $1`, orderRecord.PaymentID).
 		Scan(&paymentRecord.TransactionID, &paymentRecord.RequestID, &paymentRecord.Currency, &paymentRecord.Provider,
 			&paymentRecord.Amount, &paymentRecord.PaymentDate, &paymentRecord.Bank, &paymentRecord.DeliveryCost,
 			&paymentRecord.TotalGoods, &paymentRecord.CustomFee)
 	if err != nil {
-		s.logger.Error("Failed to fetch payment", slog.Any("error", err))
+		s.log(ctx).Error("Failed to fetch payment", slog.Any("error", err))
 		return nil, err
 	}
 
 	// Fetch items
-	rows, err := s.pool.Query(context.Background(), `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM items WHERE chrt_id IN (SELECT chrt_id FROM order_item_conn WHERE order_uid = $1)`, orderRecord.OrderUID)
+	rows, err := s.pool.Query(ctx, `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM items WHERE chrt_id IN (SELECT chrt_id FROM order_item_conn WHERE order_uid = $1)`, orderRecord.OrderUID)
 	if err != nil {
-		s.logger.Error("Failed to fetch items", slog.Any("error", err))
+		s.log(ctx).Error("Failed to fetch items", slog.Any("error", err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -183,16 +342,16 @@ func (s *dbService) GetOrder(orderUID string) (*model.OrderDetails, error) {
 		DeliveryService:   orderRecord.DeliveryService,
 		ShardKey:          orderRecord.ShardKey,
 		SMID:              orderRecord.SmID,
-		CreationTimestamp: model.ISO8601Time(orderRecord.DateCreated),
+		CreationTimestamp: orderRecord.DateCreated,
 		OutOfShard:        orderRecord.OofShard,
 	}, nil
 }
 
 // GetRecentOrderIDs возвращает последние `limit` заказов.
-func (s *dbService) GetRecentOrderIDs(limit int) ([]string, error) {
-	rows, err := s.pool.Query(context.Background(), `SELECT order_uid FROM orders ORDER BY date_created DESC LIMIT $1`, limit)
+func (s *dbService) GetRecentOrderIDs(ctx context.Context, limit int) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `SELECT order_uid FROM orders ORDER BY date_created DESC LIMIT $1`, limit)
 	if err != nil {
-		s.logger.Error("Failed to fetch recent order IDs", slog.Any("error", err))
+		s.log(ctx).Error("Failed to fetch recent order IDs", slog.Any("error", err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -208,3 +367,359 @@ func (s *dbService) GetRecentOrderIDs(limit int) ([]string, error) {
 
 	return ids, nil
 }
+
+// GetOrderIDsByCustomer возвращает идентификаторы всех заказов покупателя,
+// новые первыми.
+func (s *dbService) GetOrderIDsByCustomer(ctx context.Context, customerID string) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `SELECT order_uid FROM orders WHERE customer_id = $1
+		ORDER BY date_created DESC, order_uid`, customerID)
+	if err != nil {
+		s.log(ctx).Error("Failed to fetch customer order IDs", slog.String("customer_id", customerID), slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// LookupAPIKey возвращает владельца и области доступа действующего API-ключа по его хэшу
+// или model.ErrAPIKeyNotFound.
+func (s *dbService) LookupAPIKey(ctx context.Context, keyHash string) (string, []string, error) {
+	var (
+		name   string
+		scopes []string
+	)
+	err := s.pool.QueryRow(ctx,
+		`SELECT name, scopes FROM api_keys WHERE key_hash = $1 AND NOT revoked`, keyHash).
+		Scan(&name, &scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, model.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to look up API key", slog.Any("error", err))
+		return "", nil, err
+	}
+
+	return name, scopes, nil
+}
+
+// exportBatchSize количество заказов, читаемых из курсора за один FETCH.
+const exportBatchSize = 500
+
+// ExportOrders передает в fn заказы, подходящие под фильтр, в порядке
+// date_created. Ошибка fn прерывает выгрузку и возвращается вызывающему.
+func (s *dbService) ExportOrders(ctx context.Context, filter model.OrderFilter, fn func(*model.OrderDetails) error) error {
+	exported, err := s.streamOrders(ctx, filter, fn)
+	if err != nil {
+		return err
+	}
+
+	s.log(ctx).Info("Orders exported", slog.Int("count", exported))
+	return nil
+}
+
+// ListOrders возвращает заказы, подходящие под фильтр, в порядке date_created.
+func (s *dbService) ListOrders(ctx context.Context, filter model.OrderFilter) ([]*model.OrderDetails, error) {
+	var orders []*model.OrderDetails
+	_, err := s.streamOrders(ctx, filter, func(order *model.OrderDetails) error {
+		orders = append(orders, order)
+		return nil
+	})
+	return orders, err
+}
+
+// streamOrders читает заказы серверным курсором пачками по exportBatchSize в
+// одной транзакции со снимком данных, поэтому память не зависит от размера
+// выборки. Возвращает количество переданных в fn заказов.
+func (s *dbService) streamOrders(ctx context.Context, filter model.OrderFilter, fn func(*model.OrderDetails) error) (int, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	where, args := filterClause(filter)
+	query := `DECLARE export_orders NO SCROLL CURSOR FOR ` + orderSelect + where + `
+		ORDER BY o.date_created, o.order_uid`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		s.log(ctx).Error("Failed to open export cursor", slog.Any("error", err))
+		return 0, err
+	}
+
+	exported := 0
+	for {
+		batch, err := fetchOrderBatch(ctx, tx)
+		if err != nil {
+			s.log(ctx).Error("Failed to fetch orders from export cursor", slog.Any("error", err))
+			return exported, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		if err := loadBatchItems(ctx, tx, batch); err != nil {
+			s.log(ctx).Error("Failed to fetch items for export", slog.Any("error", err))
+			return exported, err
+		}
+
+		for _, order := range batch {
+			if err := fn(order); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+	}
+
+	return exported, nil
+}
+
+// filterClause строит условие WHERE по фильтру заказов (таблица orders — o).
+func filterClause(filter model.OrderFilter) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		add("o.date_created >= $%d", filter.CreatedFrom.UTC())
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("o.date_created < $%d", filter.CreatedTo.UTC())
+	}
+	if filter.CustomerID != "" {
+		add("o.customer_id = $%d", filter.CustomerID)
+	}
+	if filter.DeliveryService != "" {
+		add("o.delivery_service = $%d", filter.DeliveryService)
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt.UTC(), filter.After.OrderUID)
+		conditions = append(conditions, fmt.Sprintf("(o.date_created, o.order_uid) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "\n\t\tWHERE " + strings.Join(conditions, " AND "), args
+}
+
+// orderSelect выборка заказов вместе с доставкой и оплатой, без товаров;
+// строки разбирает scanOrders.
+const orderSelect = `
+		SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id,
+			o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+			d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+			p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank,
+			p.delivery_cost, p.goods_total, p.custom_fee
+		FROM orders o
+		JOIN delivery d ON d.id = o.delivery_id
+		JOIN payment p ON p.transaction = o.payment_id`
+
+// GetOrders получает заказы по списку UID двумя запросами вместо запросов
+// на каждый заказ. Отсутствующих заказов нет в результате.
+func (s *dbService) GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error) {
+	orders := make(map[string]*model.OrderDetails, len(orderUIDs))
+	if len(orderUIDs) == 0 {
+		return orders, nil
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, orderSelect+`
+		WHERE o.order_uid = ANY($1)`, orderUIDs)
+	if err != nil {
+		s.log(ctx).Error("Failed to fetch orders", slog.Int("count", len(orderUIDs)), slog.Any("error", err))
+		return nil, err
+	}
+	batch, err := scanOrders(rows, len(orderUIDs))
+	if err != nil {
+		s.log(ctx).Error("Failed to fetch orders", slog.Int("count", len(orderUIDs)), slog.Any("error", err))
+		return nil, err
+	}
+	if len(batch) == 0 {
+		return orders, nil
+	}
+
+	if err := loadBatchItems(ctx, tx, batch); err != nil {
+		s.log(ctx).Error("Failed to fetch items", slog.Any("error", err))
+		return nil, err
+	}
+	for _, order := range batch {
+		orders[order.OrderID] = order
+	}
+	return orders, nil
+}
+
+// fetchOrderBatch читает из курсора export_orders очередную пачку заказов без товаров.
+func fetchOrderBatch(ctx context.Context, tx pgx.Tx) ([]*model.OrderDetails, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf("FETCH %d FROM export_orders", exportBatchSize))
+	if err != nil {
+		return nil, err
+	}
+	return scanOrders(rows, exportBatchSize)
+}
+
+// scanOrders разбирает строки выборки orderSelect и закрывает rows.
+func scanOrders(rows pgx.Rows, capacity int) ([]*model.OrderDetails, error) {
+	defer rows.Close()
+
+	batch := make([]*model.OrderDetails, 0, capacity)
+	for rows.Next() {
+		var order model.OrderDetails
+		if err := rows.Scan(&order.OrderID, &order.TrackingNumber, &order.EntryPoint, &order.Locale,
+			&order.Signature, &order.CustomerID, &order.DeliveryService, &order.ShardKey, &order.SMID,
+			&order.CreationTimestamp, &order.OutOfShard,
+			&order.Address.FullName, &order.Address.Phone, &order.Address.ZipCode, &order.Address.City,
+			&order.Address.Street, &order.Address.Region, &order.Address.Email,
+			&order.Payment.TransactionID, &order.Payment.RequestID, &order.Payment.Currency,
+			&order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDate, &order.Payment.Bank,
+			&order.Payment.DeliveryCost, &order.Payment.TotalGoods, &order.Payment.CustomFee); err != nil {
+			return nil, err
+		}
+		batch = append(batch, &order)
+	}
+
+	return batch, rows.Err()
+}
+
+// loadBatchItems загружает товары пачки заказов одним запросом.
+func loadBatchItems(ctx context.Context, tx pgx.Tx, batch []*model.OrderDetails) error {
+	byUID := make(map[string]*model.OrderDetails, len(batch))
+	uids := make([]string, 0, len(batch))
+	for _, order := range batch {
+		byUID[order.OrderID] = order
+		uids = append(uids, order.OrderID)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT c.order_uid, i.chrt_id, i.track_number, i.price, i.rid, i.name, i.sale,
+			i.size, i.total_price, i.nm_id, i.brand, i.status
+		FROM order_item_conn c
+		JOIN items i ON i.chrt_id = c.chrt_id
+		WHERE c.order_uid = ANY($1)
+		ORDER BY c.order_uid, i.chrt_id`, uids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			orderUID string
+			item     model.ProductItem
+		)
+		if err := rows.Scan(&orderUID, &item.ChartID, &item.TrackingNum, &item.Price, &item.RID, &item.Name,
+			&item.Discount, &item.Size, &item.TotalPrice, &item.ProductID, &item.Brand, &item.Status); err != nil {
+			return err
+		}
+		if order, ok := byUID[orderUID]; ok {
+			order.Products = append(order.Products, item)
+		}
+	}
+
+	return rows.Err()
+}
+
+// ListenNewOrders вызывает fn для каждого нового заказа, сохраненного любой
+// репликой сервиса, пока не отменен ctx. Соединение с базой занимается на
+// все время ожидания; при его потере возвращается ошибка.
+func (s *dbService) ListenNewOrders(ctx context.Context, fn func(orderUID string)) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// Соединение в режиме LISTEN не возвращается в пул
+	defer conn.Hijack().Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+newOrdersChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		fn(notification.Payload)
+	}
+}
+
+// FindOrphans возвращает строки delivery и payment без заказа.
+func (s *dbService) FindOrphans(ctx context.Context) (Orphans, error) {
+	orphans := Orphans{DeliveryIDs: []int{}, PaymentTransactions: []string{}}
+
+	rows, err := s.pool.Query(ctx, `SELECT d.id FROM delivery d
+		WHERE NOT EXISTS (SELECT 1 FROM orders o WHERE o.delivery_id = d.id)
+		ORDER BY d.id`)
+	if err != nil {
+		s.log(ctx).Error("Failed to find orphaned deliveries", slog.Any("error", err))
+		return Orphans{}, err
+	}
+	orphans.DeliveryIDs, err = pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return Orphans{}, err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT p.transaction FROM payment p
+		WHERE NOT EXISTS (SELECT 1 FROM orders o WHERE o.payment_id = p.transaction)
+		ORDER BY p.transaction`)
+	if err != nil {
+		s.log(ctx).Error("Failed to find orphaned payments", slog.Any("error", err))
+		return Orphans{}, err
+	}
+	orphans.PaymentTransactions, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return Orphans{}, err
+	}
+
+	return orphans, nil
+}
+
+// DeleteOrphans удаляет найденные FindOrphans строки и возвращает их число.
+// Отсутствие ссылок проверяется повторно: строка, к которой за это время
+// привязали заказ, не удаляется.
+func (s *dbService) DeleteOrphans(ctx context.Context, orphans Orphans) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	deliveries, err := tx.Exec(ctx, `DELETE FROM delivery d WHERE d.id = ANY($1)
+		AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.delivery_id = d.id)`, orphans.DeliveryIDs)
+	if err != nil {
+		s.log(ctx).Error("Failed to delete orphaned deliveries", slog.Any("error", err))
+		return 0, err
+	}
+	payments, err := tx.Exec(ctx, `DELETE FROM payment p WHERE p.transaction = ANY($1)
+		AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.payment_id = p.transaction)`, orphans.PaymentTransactions)
+	if err != nil {
+		s.log(ctx).Error("Failed to delete orphaned payments", slog.Any("error", err))
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log(ctx).Error("Failed to commit transaction", slog.Any("error", err))
+		return 0, err
+	}
+	return deliveries.RowsAffected() + payments.RowsAffected(), nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/export"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// runExport выгружает заказы из базы в файл или stdout и возвращает код завершения.
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	var (
+		formatName = flags.String("format", string(export.FormatNDJSON), "output format: ndjson or csv")
+		from       = flags.String("from", "", "created_from: YYYY-MM-DD or RFC 3339 (inclusive)")
+		to         = flags.String("to", "", "created_to: YYYY-MM-DD or RFC 3339 (exclusive)")
+		customerID = flags.String("customer", "", "only orders of this customer_id")
+		service    = flags.String("delivery-service", "", "only orders of this delivery_service")
+		limit      = flags.Int("limit", 0, "maximum number of orders, 0 for no limit")
+		output     = flags.String("o", "", "output file (default stdout)")
+	)
+	loader := config.NewLoader(config.WithFlags(flags, "db"), config.WithRequired("db.password"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// Журнал пишется в stderr, чтобы не смешиваться с выгрузкой в stdout
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	cfg, err := loadConfig(logger, loader)
+	if err != nil {
+		return 2
+	}
+
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	filter := model.OrderFilter{CustomerID: *customerID, DeliveryService: *service, Limit: *limit}
+	if *from != "" {
+		if filter.CreatedFrom, err = model.ParseFilterTime(*from); err != nil {
+			fmt.Fprintln(os.Stderr, "-from:", err)
+			return 2
+		}
+	}
+	if *to != "" {
+		if filter.CreatedTo, err = model.ParseFilterTime(*to); err != nil {
+			fmt.Fprintln(os.Stderr, "-to:", err)
+			return 2
+		}
+	}
+	if err := filter.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer out.Close()
+	}
+
+	dbConn, err := initDatabase(logger, cfg.DB)
+	if err != nil {
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	writer, err := export.NewWriter(format, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := dbConn.ExportOrders(ctx, filter, writer.Write); err != nil {
+		logger.Error("Export failed", slog.Any("error", err))
+		return 1
+	}
+	if err := writer.Flush(); err != nil {
+		logger.Error("Failed to write export", slog.Any("error", err))
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,174 @@
+// Package export записывает заказы в форматах для выгрузки: NDJSON и CSV.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// Format формат выгрузки.
+type Format string
+
+const (
+	// FormatNDJSON по заказу на строку, в том же виде, что и сообщения Kafka,
+	// но без подписи.
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV по строке на товар; поля заказа, доставки и оплаты повторяются.
+	FormatCSV Format = "csv"
+)
+
+// ParseFormat проверяет название формата.
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(value); format {
+	case FormatNDJSON, FormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q: must be ndjson or csv", value)
+	}
+}
+
+// ContentType MIME-тип формата.
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Writer последовательно записывает заказы. Данные буферизуются, Flush
+// отправляет их в исходный io.Writer.
+type Writer interface {
+	Write(order *model.OrderDetails) error
+	Flush() error
+}
+
+// NewWriter создает Writer для формата.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonWriter{buf: bufio.NewWriter(w)}, nil
+	case FormatCSV:
+		return &csvWriter{csv: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// ndjsonWriter реализует Writer для NDJSON.
+type ndjsonWriter struct {
+	buf *bufio.Writer
+}
+
+// Write записывает заказ так же, как его публикует производитель: с полем
+// schema_version, чтобы выгрузку можно было отправить обратно в Kafka и
+// суммы не были приняты за основные единицы версии 1.
+//
+// internal_signature выгружается пустой. Подпись вычислена над сообщением в
+// той версии схемы, в которой заказ был отправлен, а версия не хранится в
+// базе, поэтому над строкой выгрузки текущей версии она могла бы не сойтись.
+// При повторной отправке заказы подписываются заново (scripts -keyset).
+func (w *ndjsonWriter) Write(order *model.OrderDetails) error {
+	unsigned := *order
+	unsigned.Signature = ""
+	data, err := model.MarshalOrder(&unsigned)
+	if err != nil {
+		return err
+	}
+	if _, err := w.buf.Write(data); err != nil {
+		return err
+	}
+	return w.buf.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// csvColumns заголовок CSV.
+var csvColumns = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address",
+	"delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+	"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+	"payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale",
+	"item_size", "item_total_price", "item_nm_id", "item_brand", "item_status", "item_status_name",
+}
+
+// csvWriter реализует Writer для CSV.
+type csvWriter struct {
+	csv           *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvWriter) Write(order *model.OrderDetails) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	// Суммы выгружаются в основных единицах валюты: 1817.00 USD
+	exponent := order.Payment.Exponent()
+	orderColumns := []string{
+		order.OrderID, order.TrackingNumber, order.EntryPoint, order.Locale, order.Signature,
+		order.CustomerID, order.DeliveryService, order.ShardKey, strconv.Itoa(order.SMID),
+		formatTime(time.Time(order.CreationTimestamp)), order.OutOfShard,
+		order.Address.FullName, order.Address.Phone, order.Address.ZipCode, order.Address.City,
+		order.Address.Street, order.Address.Region, order.Address.Email,
+		order.Payment.TransactionID, order.Payment.RequestID, order.Payment.Currency,
+		order.Payment.Provider, order.Payment.Amount.Decimal(exponent), formatTime(time.Time(order.Payment.PaymentDate)),
+		order.Payment.Bank, order.Payment.DeliveryCost.Decimal(exponent),
+		order.Payment.TotalGoods.Decimal(exponent), order.Payment.CustomFee.Decimal(exponent),
+	}
+
+	// Заказ без товаров выгружается одной строкой с пустыми колонками товара
+	if len(order.Products) == 0 {
+		return w.csv.Write(append(orderColumns, make([]string, len(csvColumns)-len(orderColumns))...))
+	}
+
+	record := make([]string, 0, len(csvColumns))
+	for _, item := range order.Products {
+		record = append(record[:0], orderColumns...)
+		record = append(record,
+			strconv.Itoa(item.ChartID), item.TrackingNum, item.Price.Decimal(exponent), item.RID, item.Name,
+			strconv.Itoa(item.Discount), item.Size, item.TotalPrice.Decimal(exponent),
+			strconv.Itoa(item.ProductID), item.Brand, strconv.Itoa(int(item.Status)), item.Status.Name(),
+		)
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTime выводит время в RFC 3339, отсутствующее время — пустой строкой.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (w *csvWriter) Flush() error {
+	// Заголовок пишется и для пустой выгрузки
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+	return w.csv.Write(csvColumns)
+}
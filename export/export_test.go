@@ -0,0 +1,183 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// kafkaMessage пример сообщения Kafka, которое отправляет производитель.
+const kafkaMessage = "../scripts/order.json"
+
+func readKafkaOrder(t *testing.T) ([]byte, *model.OrderDetails) {
+	t.Helper()
+	data, err := os.ReadFile(kafkaMessage)
+	if err != nil {
+		t.Fatalf("read %s: %v", kafkaMessage, err)
+	}
+	order, err := model.ParseOrder(data, 100)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+	return data, order
+}
+
+func TestNDJSONMatchesKafkaMessage(t *testing.T) {
+	message, order := readKafkaOrder(t)
+	unsigned := *order
+	order.Signature = "k1:c2lnbmF0dXJl"
+
+	var out bytes.Buffer
+	writer, err := NewWriter(FormatNDJSON, &out)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for range 2 {
+		if err := writer.Write(order); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), out.String())
+	}
+
+	var want, got map[string]any
+	if err := json.Unmarshal(message, &want); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	// Подпись не выгружается: при повторной отправке заказ подписывается заново
+	want["internal_signature"] = ""
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("unmarshal line: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NDJSON line differs from the Kafka message:\n got %s\nwant %s", lines[0], message)
+	}
+
+	// Строка выгрузки снова разбирается как сообщение Kafka без пересчета сумм
+	parsed, err := model.ParseOrder([]byte(lines[1]), 100)
+	if err != nil {
+		t.Fatalf("ParseOrder(line): %v", err)
+	}
+	if diff := model.Diff(&unsigned, parsed); len(diff) > 0 {
+		t.Errorf("re-parsed order differs: %v", diff)
+	}
+}
+
+func TestCSVOneRowPerItem(t *testing.T) {
+	_, order := readKafkaOrder(t)
+	second := order.Products[0]
+	second.ChartID, second.Name, second.Price, second.TotalPrice = 42, "Lipstick", 105, 105
+	order.Products = append(order.Products, second)
+	empty := *order
+	empty.OrderID, empty.Products = "empty", nil
+
+	var out bytes.Buffer
+	writer, err := NewWriter(FormatCSV, &out)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, o := range []*model.OrderDetails{order, &empty} {
+		if err := writer.Write(o); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	wantHeader := []string{
+		"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+		"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+		"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address",
+		"delivery_region", "delivery_email",
+		"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+		"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+		"payment_goods_total", "payment_custom_fee",
+		"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale",
+		"item_size", "item_total_price", "item_nm_id", "item_brand", "item_status", "item_status_name",
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want header and 3 rows", len(records))
+	}
+	if !reflect.DeepEqual(records[0], wantHeader) {
+		t.Fatalf("header = %v\nwant %v", records[0], wantHeader)
+	}
+
+	wantRows := []map[string]string{
+		{
+			"order_uid": "b563feb7b2b84b6test", "sm_id": "99", "date_created": "2021-11-26T06:22:19Z",
+			"delivery_city": "Kiryat Mozkin", "payment_currency": "USD", "payment_amount": "1817.00",
+			"payment_dt": "2021-11-26T06:22:07Z", "payment_delivery_cost": "1500.00", "payment_goods_total": "317.00",
+			"payment_custom_fee": "0.00", "item_chrt_id": "9934930", "item_price": "453.00", "item_name": "Mascaras",
+			"item_sale": "30", "item_total_price": "317.00", "item_status": "202", "item_status_name": model.ItemStatus(202).Name(),
+		},
+		{
+			"order_uid": "b563feb7b2b84b6test", "payment_amount": "1817.00",
+			"item_chrt_id": "42", "item_name": "Lipstick", "item_price": "1.05", "item_total_price": "1.05",
+		},
+		{
+			"order_uid": "empty", "payment_amount": "1817.00",
+			"item_chrt_id": "", "item_name": "", "item_price": "", "item_status_name": "",
+		},
+	}
+	for i, want := range wantRows {
+		record := records[i+1]
+		if len(record) != len(wantHeader) {
+			t.Fatalf("row %d has %d columns, want %d", i, len(record), len(wantHeader))
+		}
+		for column, value := range want {
+			if got := record[columnIndex(t, wantHeader, column)]; got != value {
+				t.Errorf("row %d: %s = %q, want %q", i, column, got, value)
+			}
+		}
+	}
+}
+
+func TestCSVEmptyExportHasHeader(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewWriter(FormatCSV, &out)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	lines := 0
+	for scanner := bufio.NewScanner(&out); scanner.Scan(); lines++ {
+		if lines == 0 && !strings.HasPrefix(scanner.Text(), "order_uid,") {
+			t.Errorf("first line = %q, want the header", scanner.Text())
+		}
+	}
+	if lines != 1 {
+		t.Errorf("got %d lines, want only the header", lines)
+	}
+}
+
+func columnIndex(t *testing.T, header []string, column string) int {
+	t.Helper()
+	for i, name := range header {
+		if name == column {
+			return i
+		}
+	}
+	t.Fatalf("no column %s", column)
+	return -1
+}
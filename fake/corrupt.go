@@ -0,0 +1,116 @@
+package fake
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// Corruption нарушение, которое можно внести в заказ для негативных проверок.
+type Corruption string
+
+// Нарушения согласованности: заказ проходит model.Validate, но суммы или
+// трек-номера не сходятся или статус товара не известен.
+const (
+	// CorruptTotalPrice total_price товара не равен price*(100-sale)/100.
+	CorruptTotalPrice Corruption = "total_price"
+	// CorruptGoodsTotal goods_total не равен сумме total_price товаров.
+	CorruptGoodsTotal Corruption = "goods_total"
+	// CorruptAmount amount не равен goods_total + delivery_cost + custom_fee.
+	CorruptAmount Corruption = "amount"
+	// CorruptItemTrack track_number товара отличается от трек-номера заказа.
+	CorruptItemTrack Corruption = "item_track_number"
+	// CorruptStatus неизвестный код статуса товара: заказ принимается,
+	// статус выводится как unknown.
+	CorruptStatus Corruption = "status"
+)
+
+// Нарушения, которые отклоняет model.Validate.
+const (
+	// CorruptCurrency неизвестный код валюты.
+	CorruptCurrency Corruption = "currency"
+	// CorruptSale скидка больше 100%.
+	CorruptSale Corruption = "sale"
+	// CorruptNegativePrice отрицательная цена товара.
+	CorruptNegativePrice Corruption = "negative_price"
+	// CorruptMissingField пустое обязательное поле.
+	CorruptMissingField Corruption = "missing_field"
+	// CorruptNoItems заказ без товаров.
+	CorruptNoItems Corruption = "no_items"
+	// CorruptDateCreated не задано время создания.
+	CorruptDateCreated Corruption = "date_created"
+)
+
+// Corruptions возвращает все нарушения.
+func Corruptions() []Corruption {
+	return []Corruption{
+		CorruptTotalPrice, CorruptGoodsTotal, CorruptAmount, CorruptItemTrack,
+		CorruptCurrency, CorruptStatus, CorruptSale, CorruptNegativePrice,
+		CorruptMissingField, CorruptNoItems, CorruptDateCreated,
+	}
+}
+
+// ParseCorruption проверяет название нарушения.
+func ParseCorruption(name string) (Corruption, error) {
+	for _, c := range Corruptions() {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+	names := make([]string, 0, len(Corruptions()))
+	for _, c := range Corruptions() {
+		names = append(names, string(c))
+	}
+	return "", fmt.Errorf("unknown corruption %q: must be one of %s", name, strings.Join(names, ", "))
+}
+
+// Invalid сообщает, что заказ с таким нарушением не проходит model.Validate.
+func (c Corruption) Invalid() bool {
+	switch c {
+	case CorruptTotalPrice, CorruptGoodsTotal, CorruptAmount, CorruptItemTrack, CorruptStatus:
+		return false
+	}
+	return true
+}
+
+// missingFields обязательные поля, которые очищает CorruptMissingField.
+var missingFields = []func(*model.OrderDetails){
+	func(o *model.OrderDetails) { o.OrderID = "" },
+	func(o *model.OrderDetails) { o.TrackingNumber = "" },
+	func(o *model.OrderDetails) { o.CustomerID = "" },
+	func(o *model.OrderDetails) { o.Address.Phone = "" },
+	func(o *model.OrderDetails) { o.Address.City = "" },
+	func(o *model.OrderDetails) { o.Payment.TransactionID = " " },
+	func(o *model.OrderDetails) { o.Products[0].Name = "" },
+}
+
+// corrupt вносит нарушение в согласованный заказ.
+func corrupt(rnd *rand.Rand, order *model.OrderDetails, c Corruption) {
+	item := &order.Products[rnd.IntN(len(order.Products))]
+	switch c {
+	case CorruptTotalPrice:
+		item.TotalPrice += 1 + model.Money(rnd.IntN(100))
+	case CorruptGoodsTotal:
+		order.Payment.TotalGoods += 1 + model.Money(rnd.IntN(100))
+	case CorruptAmount:
+		order.Payment.Amount += 1 + model.Money(rnd.IntN(100))
+	case CorruptItemTrack:
+		item.TrackingNum = "WB" + strings.ToUpper(hexString(rnd, 6))
+	case CorruptCurrency:
+		order.Payment.Currency = "ZZZ"
+	case CorruptStatus:
+		item.Status = 999
+	case CorruptSale:
+		item.Discount = 101 + rnd.IntN(100)
+	case CorruptNegativePrice:
+		item.Price = -item.Price - 1
+	case CorruptMissingField:
+		pick(rnd, missingFields)(order)
+	case CorruptNoItems:
+		order.Products = nil
+	case CorruptDateCreated:
+		order.CreationTimestamp = model.ISO8601Time{}
+	}
+}
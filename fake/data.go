@@ -0,0 +1,244 @@
+package fake
+
+// person имя в написании рынка и латиницей для адреса почты.
+type person struct {
+	name  string
+	latin string
+}
+
+// region регион и его города.
+type region struct {
+	name   string
+	cities []string
+}
+
+// market страна продаж: валюта, язык, адреса и службы доставки.
+type market struct {
+	locale   string
+	currency string
+	// weight доля заказов рынка.
+	weight int
+	// rate единиц валюты за доллар; цены каталога заданы в долларах.
+	rate float64
+
+	phonePrefix string
+	phoneDigits int
+	zipDigits   int
+	regions     []region
+	streets     []string
+	firstNames  []person
+	lastNames   []person
+	emailDomain []string
+	banks       []string
+	services    []string
+
+	// deliveryCost стоимость доставки в основных единицах; от freeFrom
+	// (сумма товаров) доставка бесплатна.
+	deliveryCost float64
+	freeFrom     float64
+	// feePercent пошлина с суммы товаров для трансграничных заказов.
+	feePercent int
+}
+
+var markets = []market{
+	{
+		locale: "ru", currency: "RUB", weight: 55, rate: 90,
+		phonePrefix: "+7", phoneDigits: 10, zipDigits: 6,
+		regions: []region{
+			{"Moscow", []string{"Moscow", "Zelenograd"}},
+			{"Moscow Oblast", []string{"Podolsk", "Khimki", "Balashikha", "Mytishchi", "Lyubertsy"}},
+			{"Saint Petersburg", []string{"Saint Petersburg", "Kolpino"}},
+			{"Sverdlovsk Oblast", []string{"Yekaterinburg", "Nizhny Tagil"}},
+			{"Novosibirsk Oblast", []string{"Novosibirsk", "Berdsk"}},
+			{"Tatarstan", []string{"Kazan", "Naberezhnye Chelny"}},
+			{"Krasnodar Krai", []string{"Krasnodar", "Sochi", "Novorossiysk"}},
+		},
+		streets: []string{"Lenina st.", "Mira ave.", "Sadovaya st.", "Tverskaya st.", "Gagarina st.", "Pushkina st.", "Sovetskaya st."},
+		firstNames: []person{
+			{"Иван", "ivan"}, {"Алексей", "alexey"}, {"Дмитрий", "dmitry"}, {"Сергей", "sergey"}, {"Андрей", "andrey"},
+			{"Анна", "anna"}, {"Мария", "maria"}, {"Екатерина", "ekaterina"}, {"Ольга", "olga"}, {"Наталья", "natalia"},
+		},
+		lastNames: []person{
+			{"Иванов", "ivanov"}, {"Смирнов", "smirnov"}, {"Кузнецов", "kuznetsov"}, {"Попов", "popov"},
+			{"Соколов", "sokolov"}, {"Лебедев", "lebedev"}, {"Козлов", "kozlov"}, {"Новиков", "novikov"},
+		},
+		emailDomain:  []string{"mail.ru", "yandex.ru", "gmail.com"},
+		banks:        []string{"sber", "alpha", "tinkoff", "vtb"},
+		services:     []string{"wb", "cdek", "boxberry", "russianpost"},
+		deliveryCost: 199, freeFrom: 1500,
+	},
+	{
+		locale: "kk", currency: "KZT", weight: 10, rate: 450,
+		phonePrefix: "+7", phoneDigits: 10, zipDigits: 6,
+		regions: []region{
+			{"Almaty", []string{"Almaty"}},
+			{"Astana", []string{"Astana"}},
+			{"Karaganda Region", []string{"Karaganda", "Temirtau"}},
+			{"Turkistan Region", []string{"Shymkent", "Turkistan"}},
+		},
+		streets:      []string{"Abaya ave.", "Dostyk ave.", "Satpayeva st.", "Tole Bi st."},
+		firstNames:   []person{{"Айдар", "aidar"}, {"Нурлан", "nurlan"}, {"Асель", "assel"}, {"Дана", "dana"}, {"Ерлан", "erlan"}},
+		lastNames:    []person{{"Ахметов", "akhmetov"}, {"Сейткали", "seitkali"}, {"Нурланов", "nurlanov"}, {"Жумабаев", "zhumabaev"}},
+		emailDomain:  []string{"mail.kz", "gmail.com"},
+		banks:        []string{"kaspi", "halyk", "jusan"},
+		services:     []string{"wb", "kazpost", "cdek"},
+		deliveryCost: 990, freeFrom: 7500,
+	},
+	{
+		locale: "be", currency: "BYN", weight: 8, rate: 3.2,
+		phonePrefix: "+375", phoneDigits: 9, zipDigits: 6,
+		regions: []region{
+			{"Minsk", []string{"Minsk"}},
+			{"Brest Region", []string{"Brest", "Baranovichi", "Pinsk"}},
+			{"Gomel Region", []string{"Gomel", "Mozyr"}},
+		},
+		streets:      []string{"Nezavisimosti ave.", "Pobediteley ave.", "Kirova st."},
+		firstNames:   []person{{"Максим", "maxim"}, {"Павел", "pavel"}, {"Юлия", "yulia"}, {"Ирина", "irina"}},
+		lastNames:    []person{{"Ковалев", "kovalev"}, {"Шевчук", "shevchuk"}, {"Климович", "klimovich"}},
+		emailDomain:  []string{"tut.by", "gmail.com"},
+		banks:        []string{"belarusbank", "priorbank"},
+		services:     []string{"wb", "belpost", "europochta"},
+		deliveryCost: 6, freeFrom: 50,
+	},
+	{
+		locale: "uz", currency: "UZS", weight: 5, rate: 12500,
+		phonePrefix: "+998", phoneDigits: 9, zipDigits: 6,
+		regions: []region{
+			{"Tashkent", []string{"Tashkent"}},
+			{"Samarkand Region", []string{"Samarkand", "Urgut"}},
+		},
+		streets:      []string{"Amir Temur st.", "Navoi st.", "Mustaqillik ave."},
+		firstNames:   []person{{"Alisher", "alisher"}, {"Dilnoza", "dilnoza"}, {"Rustam", "rustam"}, {"Malika", "malika"}},
+		lastNames:    []person{{"Karimov", "karimov"}, {"Yusupov", "yusupov"}, {"Rakhimova", "rakhimova"}},
+		emailDomain:  []string{"mail.uz", "gmail.com"},
+		banks:        []string{"kapitalbank", "uzcard"},
+		services:     []string{"wb", "uzpost"},
+		deliveryCost: 20000, freeFrom: 250000,
+	},
+	{
+		locale: "he", currency: "ILS", weight: 6, rate: 3.7,
+		phonePrefix: "+972", phoneDigits: 9, zipDigits: 7,
+		regions: []region{
+			{"Kraiot", []string{"Kiryat Motzkin", "Kiryat Bialik", "Kiryat Yam"}},
+			{"Tel Aviv District", []string{"Tel Aviv", "Ramat Gan", "Holon"}},
+			{"Jerusalem District", []string{"Jerusalem", "Beit Shemesh"}},
+		},
+		streets:      []string{"Herzl st.", "Ben Gurion blvd.", "Rothschild blvd.", "Ploshad Mira"},
+		firstNames:   []person{{"Noa", "noa"}, {"David", "david"}, {"Yael", "yael"}, {"Ariel", "ariel"}},
+		lastNames:    []person{{"Cohen", "cohen"}, {"Levi", "levi"}, {"Mizrahi", "mizrahi"}, {"Peretz", "peretz"}},
+		emailDomain:  []string{"gmail.com", "walla.co.il"},
+		banks:        []string{"hapoalim", "leumi", "discount"},
+		services:     []string{"meest", "israelpost"},
+		deliveryCost: 25, freeFrom: 200, feePercent: 17,
+	},
+	{
+		locale: "en", currency: "USD", weight: 8, rate: 1,
+		phonePrefix: "+1", phoneDigits: 10, zipDigits: 5,
+		regions: []region{
+			{"New York", []string{"New York", "Brooklyn", "Buffalo"}},
+			{"California", []string{"Los Angeles", "San Francisco", "San Diego"}},
+			{"Florida", []string{"Miami", "Orlando"}},
+		},
+		streets:      []string{"Main st.", "Oak ave.", "Park ave.", "Brighton Beach ave."},
+		firstNames:   []person{{"John", "john"}, {"Emily", "emily"}, {"Michael", "michael"}, {"Sarah", "sarah"}},
+		lastNames:    []person{{"Smith", "smith"}, {"Johnson", "johnson"}, {"Brown", "brown"}, {"Miller", "miller"}},
+		emailDomain:  []string{"gmail.com", "outlook.com"},
+		banks:        []string{"chase", "citi", "wells"},
+		services:     []string{"meest", "usps"},
+		deliveryCost: 15, freeFrom: 100, feePercent: 5,
+	},
+	{
+		locale: "ja", currency: "JPY", weight: 3, rate: 150,
+		phonePrefix: "+81", phoneDigits: 10, zipDigits: 7,
+		regions: []region{
+			{"Tokyo", []string{"Shinjuku", "Shibuya", "Setagaya"}},
+			{"Osaka", []string{"Osaka", "Sakai"}},
+		},
+		streets:      []string{"Chuo-dori", "Meiji-dori", "Omotesando"},
+		firstNames:   []person{{"Haruto", "haruto"}, {"Yui", "yui"}, {"Sota", "sota"}, {"Aoi", "aoi"}},
+		lastNames:    []person{{"Sato", "sato"}, {"Suzuki", "suzuki"}, {"Takahashi", "takahashi"}},
+		emailDomain:  []string{"gmail.com", "yahoo.co.jp"},
+		banks:        []string{"mufg", "mizuho"},
+		services:     []string{"meest", "japanpost"},
+		deliveryCost: 1500, freeFrom: 15000, feePercent: 10,
+	},
+	{
+		locale: "ar", currency: "KWD", weight: 2, rate: 0.31,
+		phonePrefix: "+965", phoneDigits: 8, zipDigits: 5,
+		regions: []region{
+			{"Capital", []string{"Kuwait City"}},
+			{"Hawalli", []string{"Hawalli", "Salmiya"}},
+		},
+		streets:      []string{"Gulf st.", "Fahad Al-Salem st."},
+		firstNames:   []person{{"Ahmed", "ahmed"}, {"Fatima", "fatima"}, {"Omar", "omar"}},
+		lastNames:    []person{{"Al-Sabah", "alsabah"}, {"Al-Mutairi", "almutairi"}},
+		emailDomain:  []string{"gmail.com"},
+		banks:        []string{"nbk", "kfh"},
+		services:     []string{"meest", "aramex"},
+		deliveryCost: 3, freeFrom: 30, feePercent: 5,
+	},
+}
+
+// sizeKind вид размерной сетки товара.
+type sizeKind int
+
+const (
+	oneSize sizeKind = iota
+	clothing
+	shoes
+)
+
+var sizes = map[sizeKind][]string{
+	oneSize:  {"0"},
+	clothing: {"XS", "S", "M", "L", "XL", "XXL"},
+	shoes:    {"37", "38", "39", "40", "41", "42", "43", "44", "45"},
+}
+
+// product позиция каталога; цена в долларах задается диапазоном.
+type product struct {
+	brand  string
+	name   string
+	size   sizeKind
+	minUSD float64
+	maxUSD float64
+	// weight популярность позиции.
+	weight int
+}
+
+var catalog = []product{
+	{"Vivienne Sabo", "Mascara Lashes", oneSize, 3, 8, 12},
+	{"L'Oreal Paris", "Shampoo Elseve", oneSize, 4, 9, 10},
+	{"Nivea", "Face Cream", oneSize, 3, 10, 10},
+	{"Maybelline", "Lipstick", oneSize, 4, 12, 8},
+	{"Zarina", "Knit Sweater", clothing, 25, 60, 6},
+	{"Gloria Jeans", "Denim Jeans", clothing, 15, 40, 8},
+	{"Befree", "Oversized T-Shirt", clothing, 8, 20, 9},
+	{"Befree", "Hoodie", clothing, 20, 45, 5},
+	{"Nike", "Running Sneakers", shoes, 60, 150, 5},
+	{"Adidas", "Training Shoes", shoes, 50, 130, 5},
+	{"Demix", "Sport Leggings", clothing, 10, 25, 6},
+	{"Xiaomi", "Wireless Earbuds", oneSize, 20, 60, 6},
+	{"Samsung", "Phone Case", oneSize, 5, 20, 7},
+	{"Apple", "USB-C Cable", oneSize, 15, 30, 4},
+	{"Baseus", "Power Bank", oneSize, 20, 50, 5},
+	{"Tefal", "Frying Pan", oneSize, 20, 70, 4},
+	{"IKEA", "Storage Box", oneSize, 5, 25, 5},
+	{"Lego", "Building Set", oneSize, 20, 120, 4},
+	{"Hasbro", "Board Game", oneSize, 15, 50, 3},
+	{"Pedigree", "Dog Food 2kg", oneSize, 8, 20, 6},
+	{"Eksmo", "Paperback Novel", oneSize, 5, 15, 6},
+	{"Casio", "Digital Watch", oneSize, 25, 120, 2},
+	{"Dyson", "Hair Dryer", oneSize, 300, 500, 1},
+}
+
+// sales распределение скидок, в процентах.
+var sales = []weighted[int]{
+	{0, 40}, {5, 6}, {10, 10}, {15, 8}, {20, 8}, {25, 6}, {30, 8}, {40, 6}, {50, 5}, {70, 3},
+}
+
+// itemCounts распределение количества товаров в заказе.
+var itemCounts = []weighted[int]{
+	{1, 45}, {2, 22}, {3, 12}, {4, 7}, {5, 5}, {6, 3}, {7, 2}, {8, 2}, {10, 1}, {15, 1},
+}
+
+var entryPoints = []weighted[string]{{"WBIL", 70}, {"WBMB", 20}, {"WBWEB", 10}}
@@ -0,0 +1,324 @@
+// Package fake создает правдоподобные и согласованные тестовые заказы:
+// суммы товаров и оплаты сходятся, валюта, язык, город и телефон относятся
+// к одной стране, статусы товаров соответствуют этапу заказа. Заказы всегда
+// проходят model.Validate; опции WithCorruption намеренно нарушают выбранные
+// правила для негативных проверок.
+//
+// Генератор детерминирован: одинаковые seed и опции дают одинаковую
+// последовательность заказов.
+package fake
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+const (
+	// defaultCustomers размер пула покупателей по умолчанию.
+	defaultCustomers = 1000
+	// meanOrderInterval средний интервал между заказами по date_created.
+	meanOrderInterval = 30 * time.Second
+)
+
+// defaultStart время первого заказа по умолчанию; фиксировано, чтобы
+// последовательность не зависела от момента запуска.
+var defaultStart = time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+// Generator создает заказы. Не предназначен для одновременного использования
+// из нескольких горутин.
+type Generator interface {
+	// Order возвращает следующий заказ последовательности.
+	Order() *model.OrderDetails
+}
+
+// generator реализует Generator.
+type generator struct {
+	seed      uint64
+	rnd       *rand.Rand
+	customers int
+	clock     time.Time
+
+	corruptions    []Corruption
+	corruptionRate float64
+}
+
+// Option настраивает генератор.
+type Option func(*generator)
+
+// WithStart задает date_created первого заказа; следующие заказы идут позже
+// со случайными интервалами.
+func WithStart(start time.Time) Option {
+	return func(g *generator) {
+		g.clock = start.UTC()
+	}
+}
+
+// WithCustomers задает число разных покупателей. Покупатель всегда с одним
+// именем, адресом и валютой, часть покупателей заказывает заметно чаще.
+func WithCustomers(n int) Option {
+	return func(g *generator) {
+		if n > 0 {
+			g.customers = n
+		}
+	}
+}
+
+// WithCorruption портит долю rate заказов (от 0 до 1): каждому испорченному
+// заказу достается одно из перечисленных нарушений.
+func WithCorruption(rate float64, corruptions ...Corruption) Option {
+	return func(g *generator) {
+		g.corruptionRate = rate
+		g.corruptions = corruptions
+	}
+}
+
+// NewGenerator создает генератор с заданным seed.
+func NewGenerator(seed int64, opts ...Option) Generator {
+	g := &generator{
+		seed:      uint64(seed),
+		rnd:       rand.New(rand.NewPCG(uint64(seed), 0x9e3779b97f4a7c15)),
+		customers: defaultCustomers,
+		clock:     defaultStart,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// customer покупатель: одни и те же данные во всех его заказах.
+type customer struct {
+	id      string
+	market  *market
+	address model.AddressDetails
+	bank    string
+}
+
+// customer строит покупателя по номеру из собственного генератора, поэтому
+// его данные не зависят от того, какие заказы были до этого.
+func (g *generator) customer(n int) customer {
+	rnd := rand.New(rand.NewPCG(g.seed, uint64(n)))
+	m := pickWeighted(rnd, markets, func(m market) int { return m.weight })
+
+	first, last := pick(rnd, m.firstNames), pick(rnd, m.lastNames)
+	r := pick(rnd, m.regions)
+	return customer{
+		id:     fmt.Sprintf("%s-%06d", m.locale, n),
+		market: m,
+		address: model.AddressDetails{
+			FullName: first.name + " " + last.name,
+			Phone:    m.phonePrefix + digits(rnd, m.phoneDigits),
+			ZipCode:  digits(rnd, m.zipDigits),
+			City:     pick(rnd, r.cities),
+			Street:   fmt.Sprintf("%s %d", pick(rnd, m.streets), 1+rnd.IntN(150)),
+			Region:   r.name,
+			Email:    fmt.Sprintf("%s.%s%d@%s", first.latin, last.latin, rnd.IntN(100), pick(rnd, m.emailDomain)),
+		},
+		bank: pick(rnd, m.banks),
+	}
+}
+
+// stage этап заказа, от которого зависят статусы товаров и оплата.
+type stage struct {
+	status model.ItemStatus
+	weight int
+}
+
+var stages = []stage{
+	{model.StatusCreated, 5},
+	{model.StatusPaid, 8},
+	{model.StatusAssembling, 10},
+	{model.StatusInTransit, 20},
+	{model.StatusReadyForPickup, 10},
+	{model.StatusDelivered, 40},
+	{model.StatusReturnRequested, 4},
+	{model.StatusReturned, 3},
+}
+
+func (g *generator) Order() *model.OrderDetails {
+	rnd := g.rnd
+
+	// Частые покупатели: номер смещен к началу пула
+	c := g.customer(int(float64(g.customers) * math.Pow(rnd.Float64(), 2)))
+	m := c.market
+	exponent, err := model.CurrencyExponent(m.currency)
+	if err != nil {
+		panic("fake: market currency is not a known ISO 4217 code: " + m.currency)
+	}
+
+	g.clock = g.clock.Add(time.Duration(rnd.ExpFloat64() * float64(meanOrderInterval))).Truncate(time.Millisecond)
+	orderUID := hexString(rnd, 16)
+	track := "WB" + strings.ToUpper(hexString(rnd, 6))
+	st := pickWeighted(rnd, stages, func(s stage) int { return s.weight })
+
+	order := &model.OrderDetails{
+		OrderID:           orderUID,
+		TrackingNumber:    track,
+		EntryPoint:        pickWeighted(rnd, entryPoints, weightOf[string]).value,
+		Address:           c.address,
+		Locale:            m.locale,
+		CustomerID:        c.id,
+		DeliveryService:   pick(rnd, m.services),
+		ShardKey:          fmt.Sprint(rnd.IntN(10)),
+		SMID:              rnd.IntN(100),
+		CreationTimestamp: model.ISO8601Time(g.clock),
+		OutOfShard:        fmt.Sprint(1 + rnd.IntN(2)),
+	}
+
+	count := pickWeighted(rnd, itemCounts, weightOf[int]).value
+	var goods model.Money
+	for i := 0; i < count; i++ {
+		p := pickWeighted(rnd, catalog, func(p product) int { return p.weight })
+		price := g.price(p, m, exponent)
+		sale := pickWeighted(rnd, sales, weightOf[int]).value
+		item := model.ProductItem{
+			ChartID:     1_000_000 + rnd.IntN(9_000_000),
+			TrackingNum: track,
+			Price:       price,
+			RID:         hexString(rnd, 10),
+			Name:        p.name,
+			Discount:    sale,
+			Size:        pick(rnd, sizes[p.size]),
+			TotalPrice:  price * model.Money(100-sale) / 100,
+			ProductID:   1_000_000 + rnd.IntN(9_000_000),
+			Brand:       p.brand,
+			Status:      g.itemStatus(st.status),
+		}
+		goods += item.TotalPrice
+		order.Products = append(order.Products, item)
+	}
+	g.settleReturns(order, st.status)
+
+	scale := math.Pow10(exponent)
+	payment := model.PaymentDetails{
+		TransactionID: orderUID,
+		Currency:      m.currency,
+		Provider:      "wbpay",
+		Bank:          c.bank,
+		TotalGoods:    goods,
+	}
+	if float64(goods) < m.freeFrom*scale {
+		payment.DeliveryCost = model.Money(m.deliveryCost * scale)
+	}
+	payment.CustomFee = goods * model.Money(m.feePercent) / 100
+	payment.Amount = payment.TotalGoods + payment.DeliveryCost + payment.CustomFee
+	if st.status != model.StatusCreated {
+		paid := g.clock.Add(time.Duration(10+rnd.IntN(600)) * time.Second)
+		payment.PaymentDate = model.UnixTime(paid.Truncate(time.Second))
+	}
+	order.Payment = payment
+
+	if g.corruptionRate > 0 && len(g.corruptions) > 0 && rnd.Float64() < g.corruptionRate {
+		corrupt(rnd, order, pick(rnd, g.corruptions))
+	}
+	return order
+}
+
+// price выбирает цену позиции в валюте рынка: в долларах из диапазона
+// каталога, переводится по курсу и округляется, как на витрине.
+func (g *generator) price(p *product, m *market, exponent int) model.Money {
+	usd := p.minUSD + g.rnd.Float64()*(p.maxUSD-p.minUSD)
+	major := usd * m.rate
+
+	// Крупные суммы округляются до десятков, остальные до целых
+	step := 1.0
+	if major >= 1000 {
+		step = 10
+	}
+	major = math.Max(step, math.Round(major/step)*step)
+	minor := model.Money(major * math.Pow10(exponent))
+	// Цены вида 9.99 — у валют с копейками
+	if exponent > 0 && g.rnd.IntN(2) == 0 {
+		minor--
+	}
+	return minor
+}
+
+// itemStatus статус товара на этапе заказа; до получения часть товаров
+// отменяется.
+func (g *generator) itemStatus(stage model.ItemStatus) model.ItemStatus {
+	switch stage {
+	case model.StatusReturnRequested, model.StatusReturned:
+		return model.StatusDelivered
+	case model.StatusDelivered:
+		if g.rnd.IntN(20) == 0 {
+			return model.StatusCancelled
+		}
+	default:
+		if g.rnd.IntN(25) == 0 {
+			return model.StatusCancelled
+		}
+	}
+	return stage
+}
+
+// settleReturns на этапах возврата переводит в возврат часть полученных
+// товаров, хотя бы один.
+func (g *generator) settleReturns(order *model.OrderDetails, stage model.ItemStatus) {
+	if stage != model.StatusReturnRequested && stage != model.StatusReturned {
+		return
+	}
+	returned := false
+	for i := range order.Products {
+		if g.rnd.IntN(3) == 0 || (!returned && i == len(order.Products)-1) {
+			order.Products[i].Status = stage
+			returned = true
+		}
+	}
+}
+
+// weighted значение с весом для случайного выбора.
+type weighted[T any] struct {
+	value  T
+	weight int
+}
+
+func weightOf[T any](w weighted[T]) int {
+	return w.weight
+}
+
+// pickWeighted выбирает элемент с вероятностью, пропорциональной весу.
+func pickWeighted[T any](rnd *rand.Rand, items []T, weight func(T) int) *T {
+	total := 0
+	for _, item := range items {
+		total += weight(item)
+	}
+	n := rnd.IntN(total)
+	for i := range items {
+		if n -= weight(items[i]); n < 0 {
+			return &items[i]
+		}
+	}
+	return &items[len(items)-1]
+}
+
+// pick выбирает элемент равновероятно.
+func pick[T any](rnd *rand.Rand, items []T) T {
+	return items[rnd.IntN(len(items))]
+}
+
+func digits(rnd *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('0' + rnd.IntN(10))
+	}
+	// Номера и индексы не начинаются с нуля
+	if n > 0 && b[0] == '0' {
+		b[0] = '1'
+	}
+	return string(b)
+}
+
+func hexString(rnd *rand.Rand, bytes int) string {
+	b := make([]byte, bytes)
+	for i := range b {
+		b[i] = byte(rnd.Uint32())
+	}
+	return hex.EncodeToString(b)
+}
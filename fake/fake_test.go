@@ -0,0 +1,123 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"testing"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// ordersToCheck сколько заказов проверяется; в -short меньше.
+func ordersToCheck(t *testing.T) int {
+	if testing.Short() {
+		return 1000
+	}
+	return 20000
+}
+
+func TestOrdersPassValidate(t *testing.T) {
+	g := NewGenerator(1, WithCustomers(500))
+	for i := range ordersToCheck(t) {
+		order := g.Order()
+		if err := order.Validate(); err != nil {
+			t.Fatalf("order %d (%s): %v", i, order.OrderID, err)
+		}
+
+		// Заказ переживает передачу через Kafka без изменений
+		data, err := model.MarshalOrder(order)
+		if err != nil {
+			t.Fatalf("order %d: MarshalOrder: %v", i, err)
+		}
+		parsed, err := model.ParseOrder(data, model.MaxItems)
+		if err != nil {
+			t.Fatalf("order %d: ParseOrder: %v", i, err)
+		}
+		if diff := model.Diff(order, parsed); len(diff) > 0 {
+			t.Fatalf("order %d changed in transit: %+v", i, diff)
+		}
+
+		var goods model.Money
+		for _, item := range order.Products {
+			if want := item.Price * model.Money(100-item.Discount) / 100; item.TotalPrice != want {
+				t.Fatalf("order %d: total_price %d, want %d", i, item.TotalPrice, want)
+			}
+			if item.TrackingNum != order.TrackingNumber {
+				t.Fatalf("order %d: item track %s, want %s", i, item.TrackingNum, order.TrackingNumber)
+			}
+			if !item.Status.Known() {
+				t.Fatalf("order %d: unknown status %d", i, item.Status)
+			}
+			goods += item.TotalPrice
+		}
+		payment := order.Payment
+		if payment.TotalGoods != goods {
+			t.Fatalf("order %d: goods_total %d, want %d", i, payment.TotalGoods, goods)
+		}
+		if want := payment.TotalGoods + payment.DeliveryCost + payment.CustomFee; payment.Amount != want {
+			t.Fatalf("order %d: amount %d, want %d", i, payment.Amount, want)
+		}
+	}
+}
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	opts := []Option{WithStart(start), WithCustomers(50), WithCorruption(0.2, Corruptions()...)}
+	sequence := func(seed int64) []byte {
+		g := NewGenerator(seed, opts...)
+		orders := make([]*model.OrderDetails, 0, 1000)
+		for range 1000 {
+			orders = append(orders, g.Order())
+		}
+		data, err := json.Marshal(orders)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return data
+	}
+
+	first, second := sequence(42), sequence(42)
+	if !bytes.Equal(first, second) {
+		t.Error("the same seed produced different orders")
+	}
+	if bytes.Equal(first, sequence(43)) {
+		t.Error("different seeds produced the same orders")
+	}
+}
+
+func TestCustomerIsConsistent(t *testing.T) {
+	g := NewGenerator(7, WithCustomers(20))
+	seen := make(map[string]*model.OrderDetails)
+	for range 2000 {
+		order := g.Order()
+		previous, ok := seen[order.CustomerID]
+		if !ok {
+			seen[order.CustomerID] = order
+			continue
+		}
+		if order.Address != previous.Address || order.Locale != previous.Locale ||
+			order.Payment.Currency != previous.Payment.Currency || order.Payment.Bank != previous.Payment.Bank {
+			t.Fatalf("customer %s changed between orders %s and %s", order.CustomerID, previous.OrderID, order.OrderID)
+		}
+	}
+}
+
+func TestCorruptionsMatchInvalid(t *testing.T) {
+	for _, corruption := range Corruptions() {
+		t.Run(string(corruption), func(t *testing.T) {
+			g := NewGenerator(3, WithCorruption(1, corruption))
+			for i := range 500 {
+				order := g.Order()
+				err := order.Validate()
+				if corruption.Invalid() && err == nil {
+					t.Fatalf("order %d passed Validate", i)
+				}
+				if !corruption.Invalid() && err != nil {
+					t.Fatalf("order %d failed Validate: %v", i, err)
+				}
+			}
+		})
+	}
+}
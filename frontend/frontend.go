@@ -0,0 +1,9 @@
+// Package frontend содержит встроенный в бинарный файл пользовательский интерфейс.
+package frontend
+
+import "embed"
+
+// Files страница просмотра заказов (index.html) и статические файлы (static/).
+//
+//go:embed index.html static
+var Files embed.FS
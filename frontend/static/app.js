@@ -0,0 +1,292 @@
+"use strict";
+
+// Данные заказа выводятся только через textContent, чтобы строки из заказа
+// никогда не интерпретировались как HTML.
+
+// Ключ API или токен хранится в sessionStorage: он живет до закрытия вкладки
+// и отправляется в заголовке Authorization. Без ключа запросы выполняются с
+// анонимными областями доступа сервера.
+const CREDENTIALS_KEY = "orders.credentials";
+
+const credentials = () => sessionStorage.getItem(CREDENTIALS_KEY) || "";
+
+const apiFetch = (path) => {
+  const headers = { Accept: "application/json" };
+  const token = credentials();
+  if (token) {
+    headers.Authorization = `Bearer ${token}`;
+  }
+  return fetch(path, { headers });
+};
+
+// Имена статусов товара; справочник загружается из /api/v1/statuses, здесь —
+// запасной вариант на случай его недоступности.
+let STATUS_LABELS = {
+  202: { name: "delivered", description: "Delivered" },
+};
+
+const loadStatuses = async () => {
+  try {
+    const response = await apiFetch("/api/v1/statuses");
+    if (response.ok) {
+      const catalog = await response.json();
+      STATUS_LABELS = Object.fromEntries(
+        catalog.item_statuses.map((status) => [status.code, status]),
+      );
+    }
+  } catch {
+    // Остаются запасные имена
+  }
+};
+
+// Число знаков после запятой у валют; таблица загружается из
+// /api/v1/currencies. Пока она не загружена, суммы выводятся с двумя знаками.
+let CURRENCY_EXPONENTS = {};
+
+const loadCurrencies = async () => {
+  try {
+    const response = await apiFetch("/api/v1/currencies");
+    if (response.ok) {
+      CURRENCY_EXPONENTS = (await response.json()).exponents;
+    }
+  } catch {
+    // Суммы выводятся с двумя знаками
+  }
+};
+
+const orderPath = /^\/orders\/([^/]+)\/?$/;
+
+const $ = (selector) => document.querySelector(selector);
+
+const get = (obj, path) =>
+  path.split(".").reduce((value, key) => (value == null ? undefined : value[key]), obj);
+
+// Суммы приходят в минимальных единицах валюты (центах).
+const formatMoney = (amount, currency) => {
+  if (amount == null) {
+    return "";
+  }
+  const exponent = CURRENCY_EXPONENTS[currency] ?? 2;
+  try {
+    return new Intl.NumberFormat(undefined, {
+      style: "currency",
+      currency,
+      minimumFractionDigits: exponent,
+      maximumFractionDigits: exponent,
+    }).format(amount / 10 ** exponent);
+  } catch {
+    return `${amount.toLocaleString()} ${currency || ""}`.trim();
+  }
+};
+
+const formatDate = (value) => {
+  if (value == null || value === "") {
+    return "";
+  }
+  // payment_dt приходит в секундах Unix, date_created — строкой RFC 3339;
+  // отсутствующее время приходит как null
+  const date = typeof value === "number" ? new Date(value * 1000) : new Date(value);
+  return isNaN(date) ? String(value) : date.toLocaleString();
+};
+
+const statusLabel = (status) => STATUS_LABELS[status]?.description || `Status ${status}`;
+
+const statusName = (status) => STATUS_LABELS[status]?.name || "unknown";
+
+const showMessage = (text) => {
+  const message = $("#message");
+  message.textContent = text;
+  message.hidden = !text;
+};
+
+const render = (order) => {
+  const currency = get(order, "payment.currency");
+
+  document.querySelectorAll("#order [data-field]").forEach((element) => {
+    const field = element.dataset.field;
+    const value = get(order, field);
+    if (element.classList.contains("money")) {
+      element.textContent = formatMoney(value, currency);
+    } else if (field === "date_created" || field === "payment.payment_dt") {
+      element.textContent = formatDate(value);
+    } else {
+      element.textContent = value == null ? "" : String(value);
+    }
+  });
+
+  const tbody = $("#items");
+  tbody.replaceChildren();
+  let total = 0;
+  for (const item of order.items || []) {
+    const row = document.createElement("tr");
+    const cells = [
+      [item.name],
+      [item.brand],
+      [item.size],
+      [formatMoney(item.price, currency), "num"],
+      [item.sale ? `${item.sale}%` : "", "num"],
+      [formatMoney(item.total_price, currency), "num"],
+    ];
+    for (const [text, className] of cells) {
+      const cell = document.createElement("td");
+      cell.textContent = text == null ? "" : String(text);
+      if (className) {
+        cell.className = className;
+      }
+      row.append(cell);
+    }
+
+    const status = document.createElement("span");
+    status.className = "status";
+    status.dataset.status = statusName(item.status);
+    status.title = `${item.status}`;
+    status.textContent = statusLabel(item.status);
+    const statusCell = document.createElement("td");
+    statusCell.append(status);
+    row.append(statusCell);
+
+    tbody.append(row);
+    total += item.total_price || 0;
+  }
+  $("#items_total").textContent = formatMoney(total, currency);
+  $("#invoice_link").href = `/orders/${encodeURIComponent(order.order_uid)}/invoice`;
+
+  $("#order").hidden = false;
+};
+
+// renderState показывает состояние заказа, выведенное сервером из статусов товаров.
+const renderState = async (uid) => {
+  const element = $("#order_state");
+  element.textContent = "";
+  try {
+    const response = await apiFetch(`/api/v1/orders/${encodeURIComponent(uid)}/state`);
+    if (response.ok) {
+      const state = await response.json();
+      element.textContent = state.description;
+      element.dataset.state = state.state;
+    }
+  } catch {
+    // Состояние необязательно для просмотра заказа
+  }
+};
+
+const loadOrder = async (uid) => {
+  $("#order").hidden = true;
+  $("#order_uid").value = uid;
+  if (!uid) {
+    showMessage("");
+    return;
+  }
+
+  showMessage("Loading…");
+  try {
+    const response = await apiFetch(`/api/v1/orders/${encodeURIComponent(uid)}`);
+    if (response.status === 401 || response.status === 403) {
+      showMessage("Access denied: enter an API key with the orders:read scope");
+      return;
+    }
+    if (!response.ok) {
+      showMessage(response.status === 404 ? "Order not found" : `Error ${response.status}: ${(await response.text()).trim()}`);
+      return;
+    }
+    render(await response.json());
+    showMessage("");
+    renderState(uid);
+  } catch (err) {
+    showMessage(`Failed to fetch order: ${err}`);
+  }
+};
+
+// INVOICE_URL_TTL_MS запасной срок жизни blob-адреса счета, если вкладка не
+// сообщила о загрузке.
+const INVOICE_URL_TTL_MS = 60_000;
+
+// invoiceDocument добавляет в счет <base>: у документа с адресом blob: нет
+// базового адреса, и без него не загрузится /static/invoice.css.
+const invoiceDocument = (html) => {
+  const doc = new DOMParser().parseFromString(html, "text/html");
+  const base = doc.createElement("base");
+  base.href = `${window.location.origin}/`;
+  doc.head.prepend(base);
+  return new Blob([`<!DOCTYPE html>\n${doc.documentElement.outerHTML}`], { type: "text/html" });
+};
+
+// openInvoice открывает счет в новой вкладке. Переход по ссылке не передает
+// заголовок Authorization, поэтому с ключом счет загружается запросом.
+// Документ blob: наследует политику CSP этой страницы, поэтому стили счета
+// подключаются файлом, а не встроенным <style>.
+const openInvoice = async (event) => {
+  if (!credentials()) {
+    return;
+  }
+  event.preventDefault();
+  const tab = window.open("", "_blank");
+  try {
+    const response = await apiFetch(event.currentTarget.getAttribute("href"));
+    if (!response.ok) {
+      tab?.close();
+      showMessage(`Error ${response.status}: ${(await response.text()).trim()}`);
+      return;
+    }
+    const url = URL.createObjectURL(invoiceDocument(await response.text()));
+    if (!tab) {
+      URL.revokeObjectURL(url);
+      return;
+    }
+    let revoked = false;
+    const revoke = () => {
+      if (!revoked) {
+        revoked = true;
+        URL.revokeObjectURL(url);
+      }
+    };
+    // Событие load пустой вкладки, открытой window.open, пропускается
+    const onLoad = () => {
+      if (tab.location.href === url) {
+        tab.removeEventListener("load", onLoad);
+        revoke();
+      }
+    };
+    tab.addEventListener("load", onLoad);
+    setTimeout(revoke, INVOICE_URL_TTL_MS);
+    tab.location = url;
+  } catch (err) {
+    tab?.close();
+    showMessage(`Failed to fetch invoice: ${err}`);
+  }
+};
+
+const uidFromLocation = () => {
+  const match = orderPath.exec(window.location.pathname);
+  return match ? decodeURIComponent(match[1]) : "";
+};
+
+document.addEventListener("DOMContentLoaded", () => {
+  $("#credentials").value = credentials();
+  $("#credentials_form").addEventListener("submit", (event) => {
+    event.preventDefault();
+    const token = $("#credentials").value.trim();
+    if (token) {
+      sessionStorage.setItem(CREDENTIALS_KEY, token);
+    } else {
+      sessionStorage.removeItem(CREDENTIALS_KEY);
+    }
+    Promise.all([loadStatuses(), loadCurrencies()]).then(() => loadOrder(uidFromLocation()));
+  });
+
+  $("#search").addEventListener("submit", (event) => {
+    event.preventDefault();
+    const uid = $("#order_uid").value.trim();
+    if (!uid) {
+      return;
+    }
+    history.pushState(null, "", `/orders/${encodeURIComponent(uid)}`);
+    loadOrder(uid);
+  });
+
+  $("#invoice_link").addEventListener("click", openInvoice);
+
+  window.addEventListener("popstate", () => loadOrder(uidFromLocation()));
+
+  Promise.all([loadStatuses(), loadCurrencies()]).then(() => loadOrder(uidFromLocation()));
+});
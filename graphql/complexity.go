@@ -0,0 +1,634 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultCustomerOrders значение first по умолчанию в Customer.orders.
+	defaultCustomerOrders = 20
+	// estimatedItemsPerOrder ожидаемое число товаров в заказе для оценки стоимости.
+	estimatedItemsPerOrder = 10
+)
+
+// queryComplexity оценивает стоимость запроса до выполнения: каждое поле
+// стоит 1, а стоимость вложенных полей списка умножается на ожидаемое число
+// элементов (длину order_uids, значение first, estimatedItemsPerOrder для
+// items). Для документа из нескольких операций возвращается максимум.
+//
+// graphql-go не дает доступа к разобранному запросу, поэтому здесь свой
+// разбор исполняемого документа; запрос к этому моменту уже проверен схемой.
+func queryComplexity(query string, variables map[string]any) (int, error) {
+	doc, err := parseDocument(query)
+	if err != nil {
+		return 0, err
+	}
+
+	estimator := &complexityEstimator{
+		doc:       doc,
+		variables: variables,
+		fragments: make(map[string]int),
+		visiting:  make(map[string]bool),
+	}
+	highest := 0
+	for _, operation := range doc.operations {
+		cost, err := estimator.cost(operation)
+		if err != nil {
+			return 0, err
+		}
+		highest = max(highest, cost)
+	}
+	return highest, nil
+}
+
+// selection поле, вставка фрагмента или встроенный фрагмент.
+type selection struct {
+	// name имя поля (без псевдонима); пустое для фрагментов.
+	name string
+	args map[string]any
+	// fragment имя вставляемого фрагмента (...Name).
+	fragment string
+	children []selection
+}
+
+// variableRef ссылка на переменную ($name) в значении аргумента.
+type variableRef string
+
+type document struct {
+	operations [][]selection
+	fragments  map[string][]selection
+}
+
+type complexityEstimator struct {
+	doc       *document
+	variables map[string]any
+	// fragments стоимость уже оцененных фрагментов.
+	fragments map[string]int
+	visiting  map[string]bool
+}
+
+func (e *complexityEstimator) cost(set []selection) (int, error) {
+	total := 0
+	for _, s := range set {
+		var cost int
+		switch {
+		case s.fragment != "":
+			var err error
+			if cost, err = e.fragmentCost(s.fragment); err != nil {
+				return 0, err
+			}
+		case s.name == "":
+			var err error
+			if cost, err = e.cost(s.children); err != nil {
+				return 0, err
+			}
+		default:
+			children, err := e.cost(s.children)
+			if err != nil {
+				return 0, err
+			}
+			cost = saturatingAdd(1, saturatingMul(e.listSize(s), children))
+		}
+		total = saturatingAdd(total, cost)
+	}
+	return total, nil
+}
+
+func (e *complexityEstimator) fragmentCost(name string) (int, error) {
+	if cost, ok := e.fragments[name]; ok {
+		return cost, nil
+	}
+	set, ok := e.doc.fragments[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown fragment %q", name)
+	}
+	if e.visiting[name] {
+		return 0, fmt.Errorf("fragment %q spreads itself", name)
+	}
+
+	e.visiting[name] = true
+	cost, err := e.cost(set)
+	delete(e.visiting, name)
+	if err != nil {
+		return 0, err
+	}
+	e.fragments[name] = cost
+	return cost, nil
+}
+
+// listSize ожидаемое число элементов, которое вернет поле.
+func (e *complexityEstimator) listSize(s selection) int {
+	switch s.name {
+	case "orders":
+		if uids, ok := s.args["order_uids"]; ok {
+			switch value := e.resolve(uids).(type) {
+			case []any:
+				return len(value)
+			case nil:
+				return 0
+			default:
+				// Одиночное значение приводится к списку из одного элемента
+				return 1
+			}
+		}
+		if first, ok := e.resolve(s.args["first"]).(int); ok && first >= 0 {
+			return first
+		}
+		return defaultCustomerOrders
+	case "items":
+		return estimatedItemsPerOrder
+	default:
+		return 1
+	}
+}
+
+// resolve подставляет значение переменной; числа из JSON приводятся к int.
+func (e *complexityEstimator) resolve(value any) any {
+	if ref, ok := value.(variableRef); ok {
+		value = e.variables[string(ref)]
+	}
+	switch number := value.(type) {
+	case float64:
+		return int(math.Min(number, math.MaxInt32))
+	case int64:
+		return int(min(number, math.MaxInt32))
+	}
+	return value
+}
+
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt32-b {
+		return math.MaxInt32
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > math.MaxInt32/a {
+		return math.MaxInt32
+	}
+	return a * b
+}
+
+// parser разбирает исполняемый документ GraphQL в объеме, нужном для оценки
+// стоимости: поля, аргументы и фрагменты. Определения переменных и директивы
+// пропускаются.
+type parser struct {
+	lexer lexer
+	token token
+}
+
+func parseDocument(query string) (*document, error) {
+	p := &parser{lexer: lexer{src: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string][]selection)}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.token.is(tokenName, "fragment"):
+			name, set, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = set
+		case p.token.is(tokenPunct, "{"):
+			set, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, set)
+		case p.token.kind == tokenName:
+			set, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, set)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	return doc, nil
+}
+
+// parseOperation: query|mutation|subscription Name? VariableDefinitions? Directives? SelectionSet.
+func (p *parser) parseOperation() ([]selection, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenName {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.token.is(tokenPunct, "(") {
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.skipDirectives(); err != nil {
+		return nil, err
+	}
+	return p.parseSelectionSet()
+}
+
+// parseFragment: fragment Name on Type Directives? SelectionSet.
+func (p *parser) parseFragment() (string, []selection, error) {
+	if err := p.advance(); err != nil {
+		return "", nil, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return "", nil, err
+	}
+	if !p.token.is(tokenName, "on") {
+		return "", nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return "", nil, err
+	}
+	if _, err := p.expectName(); err != nil {
+		return "", nil, err
+	}
+	if err := p.skipDirectives(); err != nil {
+		return "", nil, err
+	}
+	set, err := p.parseSelectionSet()
+	return name, set, err
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var set []selection
+	for !p.token.is(tokenPunct, "}") {
+		if p.token.kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+		s, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, s)
+	}
+	return set, p.advance()
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	var s selection
+
+	if p.token.is(tokenPunct, "...") {
+		if err := p.advance(); err != nil {
+			return s, err
+		}
+		// Вставка именованного фрагмента
+		if p.token.kind == tokenName && p.token.value != "on" {
+			s.fragment = p.token.value
+			if err := p.advance(); err != nil {
+				return s, err
+			}
+			return s, p.skipDirectives()
+		}
+		// Встроенный фрагмент с необязательным условием типа
+		if p.token.is(tokenName, "on") {
+			if err := p.advance(); err != nil {
+				return s, err
+			}
+			if _, err := p.expectName(); err != nil {
+				return s, err
+			}
+		}
+		if err := p.skipDirectives(); err != nil {
+			return s, err
+		}
+		var err error
+		s.children, err = p.parseSelectionSet()
+		return s, err
+	}
+
+	name, err := p.expectName()
+	if err != nil {
+		return s, err
+	}
+	// Псевдоним: alias: name
+	if p.token.is(tokenPunct, ":") {
+		if err := p.advance(); err != nil {
+			return s, err
+		}
+		if name, err = p.expectName(); err != nil {
+			return s, err
+		}
+	}
+	s.name = name
+
+	if p.token.is(tokenPunct, "(") {
+		if s.args, err = p.parseArguments(); err != nil {
+			return s, err
+		}
+	}
+	if err := p.skipDirectives(); err != nil {
+		return s, err
+	}
+	if p.token.is(tokenPunct, "{") {
+		if s.children, err = p.parseSelectionSet(); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) parseArguments() (map[string]any, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	args := make(map[string]any)
+	for !p.token.is(tokenPunct, ")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.parseValue(); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+// parseValue возвращает int64, float64, string, []any, map[string]any или variableRef.
+func (p *parser) parseValue() (any, error) {
+	current := p.token
+	switch {
+	case current.is(tokenPunct, "$"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		return variableRef(name), err
+	case current.is(tokenPunct, "["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []any{}
+		for !p.token.is(tokenPunct, "]") {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, p.advance()
+	case current.is(tokenPunct, "{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		object := make(map[string]any)
+		for !p.token.is(tokenPunct, "}") {
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		return object, p.advance()
+	case current.kind == tokenInt:
+		value, err := strconv.ParseInt(current.value, 10, 64)
+		if err != nil {
+			value = math.MaxInt64
+		}
+		return value, p.advance()
+	case current.kind == tokenFloat:
+		value, _ := strconv.ParseFloat(current.value, 64)
+		return value, p.advance()
+	case current.kind == tokenString, current.kind == tokenName:
+		return current.value, p.advance()
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+func (p *parser) skipDirectives() error {
+	for p.token.is(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if p.token.is(tokenPunct, "(") {
+			if _, err := p.parseArguments(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipBalanced пропускает скобки open ... close вместе с вложенными.
+func (p *parser) skipBalanced(open, closing string) error {
+	depth := 0
+	for {
+		switch {
+		case p.token.kind == tokenEOF:
+			return p.unexpected()
+		case p.token.is(tokenPunct, open):
+			depth++
+		case p.token.is(tokenPunct, closing):
+			depth--
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (p *parser) expectName() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+func (p *parser) expectPunct(value string) error {
+	if !p.token.is(tokenPunct, value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) advance() error {
+	var err error
+	p.token, err = p.lexer.next()
+	return err
+}
+
+func (p *parser) unexpected() error {
+	if p.token.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of query")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", p.token.value, p.token.offset)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind   tokenKind
+	value  string
+	offset int
+}
+
+func (t token) is(kind tokenKind, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+// lexer разбивает запрос на лексемы по правилам спецификации GraphQL.
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, offset: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunct, value: "...", offset: start}, nil
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, value: string(c), offset: start}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], offset: start}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		end := l.pos + 3
+		for {
+			i := strings.Index(l.src[end:], `"""`)
+			if i < 0 {
+				return token{}, fmt.Errorf("unterminated block string at offset %d", start)
+			}
+			end += i
+			// \""" внутри блочной строки не закрывает ее
+			if l.src[end-1] != '\\' {
+				break
+			}
+			end += 3
+		}
+		l.pos = end + 3
+		return token{kind: tokenString, value: l.src[start+3 : end], offset: start}, nil
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\n' {
+				break
+			}
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) || l.src[l.pos] != '"' {
+			return token{}, fmt.Errorf("unterminated string at offset %d", start)
+		}
+		l.pos++
+		return token{kind: tokenString, value: l.src[start+1 : l.pos-1], offset: start}, nil
+	default:
+		return token{}, fmt.Errorf("unexpected character %q at offset %d", c, start)
+	}
+}
+
+// skipIgnored пропускает пробелы, запятые, BOM и комментарии.
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		from := l.pos
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		return l.pos - from
+	}
+	if digits() == 0 {
+		return token{}, fmt.Errorf("invalid number at offset %d", start)
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	return token{kind: kind, value: l.src[start:l.pos], offset: start}, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
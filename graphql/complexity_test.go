@@ -0,0 +1,136 @@
+package graphqlapi
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestQueryComplexity(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		want      int
+		wantErr   string
+	}{
+		{
+			name:  "single order",
+			query: `{ order(order_uid: "a") { order_uid } }`,
+			want:  2,
+		},
+		{
+			name:  "aliases count every field",
+			query: `{ a: order(order_uid: "a") { order_uid } b: order(order_uid: "b") { order_uid } }`,
+			want:  4,
+		},
+		{
+			name:  "alias does not change the field",
+			query: `{ orders: order(order_uid: "a") { items { name } } }`,
+			want:  12,
+		},
+		{
+			name:  "order_uids literal",
+			query: `{ orders(order_uids: ["a", "b", "c"]) { order_uid items { name } } }`,
+			want:  37,
+		},
+		{
+			name:      "order_uids variable",
+			query:     `query Q($ids: [String!]!) { orders(order_uids: $ids) { order_uid } }`,
+			variables: map[string]any{"ids": []any{"a", "b"}},
+			want:      3,
+		},
+		{
+			name:      "single order_uid variable is coerced to a list",
+			query:     `query Q($ids: [String!]!) { orders(order_uids: $ids) { order_uid } }`,
+			variables: map[string]any{"ids": "a"},
+			want:      2,
+		},
+		{
+			name:  "first defaults to 20",
+			query: `{ customer(customer_id: "c") { orders { order_uid } } }`,
+			want:  22,
+		},
+		{
+			name:  "first literal",
+			query: `{ customer(customer_id: "c") { orders(first: 5) { order_uid } } }`,
+			want:  7,
+		},
+		{
+			name:      "first variable",
+			query:     `query Q($n: Int) { customer(customer_id: "c") { orders(first: $n) { order_uid } } }`,
+			variables: map[string]any{"n": float64(3)},
+			want:      5,
+		},
+		{
+			name: "named fragments",
+			query: `query { customer(customer_id: "c") { ...C } }
+				fragment C on Customer { orders(first: 2) { ...O } }
+				fragment O on Order { order_uid items { name } }`,
+			want: 26,
+		},
+		{
+			name: "fragment spread twice",
+			query: `{ a: order(order_uid: "a") { ...O } b: order(order_uid: "b") { ...O } }
+				fragment O on Order { order_uid track_number }`,
+			want: 6,
+		},
+		{
+			name:  "inline fragment",
+			query: `{ order(order_uid: "a") { ... on Order { order_uid } ... @include(if: true) { track_number } } }`,
+			want:  3,
+		},
+		{
+			name: "most expensive operation",
+			query: `query Cheap { order(order_uid: "a") { order_uid } }
+				query Costly { customer(customer_id: "c") { orders { order_uid } } }`,
+			want: 22,
+		},
+		{
+			name:  "strings and comments are not syntax",
+			query: "{ order(order_uid: \"} { ...\") { order_uid } # } items { name }\n}",
+			want:  2,
+		},
+		{
+			name: "saturates instead of overflowing",
+			query: `{ customer(customer_id: "c") { orders(first: 100000) { customer {
+				orders(first: 100000) { items { name } } } } } }`,
+			want: math.MaxInt32,
+		},
+		{
+			name: "cyclic spread",
+			query: `{ order(order_uid: "a") { ...A } }
+				fragment A on Order { customer { orders { ...B } } }
+				fragment B on Order { ...A }`,
+			wantErr: "spreads itself",
+		},
+		{
+			name:    "unknown fragment",
+			query:   `{ order(order_uid: "a") { ...Missing } }`,
+			wantErr: `unknown fragment "Missing"`,
+		},
+		{
+			name:    "unterminated selection set",
+			query:   `{ order(order_uid: "a") { order_uid }`,
+			wantErr: "unexpected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryComplexity(tt.query, tt.variables)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("queryComplexity: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("complexity = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Команда gen строит из структур пакета model схему GraphQL (model.graphqls)
+// и методы резолверов (model_gen.go). Имена полей GraphQL совпадают с
+// JSON-тегами, поэтому запросы возвращают заказ в том же виде, что и REST API.
+//
+// Запускается через go generate в каталоге graphql; с флагом -check только
+// проверяет, что сгенерированные файлы соответствуют model.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// objectTypes названия типов GraphQL для структур model в порядке вывода.
+var objectTypes = []struct {
+	name string
+	typ  reflect.Type
+}{
+	{"Order", reflect.TypeOf(model.OrderDetails{})},
+	{"Delivery", reflect.TypeOf(model.AddressDetails{})},
+	{"Payment", reflect.TypeOf(model.PaymentDetails{})},
+	{"Item", reflect.TypeOf(model.ProductItem{})},
+}
+
+const header = "Code generated by go run ./gen; DO NOT EDIT."
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the graphql package")
+	check := flag.Bool("check", false, "only verify that generated files are up to date")
+	flag.Parse()
+
+	schema, code, err := generate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+
+	files := map[string][]byte{"model.graphqls": schema, "model_gen.go": code}
+	stale := false
+	for name, content := range files {
+		path := filepath.Join(*dir, name)
+		if *check {
+			current, err := os.ReadFile(path)
+			if err != nil || !bytes.Equal(current, content) {
+				fmt.Fprintf(os.Stderr, "gen: %s is out of date with package model, run go generate\n", path)
+				stale = true
+			}
+			continue
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, "gen:", err)
+			os.Exit(1)
+		}
+	}
+	if stale {
+		os.Exit(1)
+	}
+}
+
+// field поле структуры model и его представление в GraphQL и Go.
+type field struct {
+	graphQLName string
+	graphQLType string
+	method      string
+	goType      string
+	body        string
+}
+
+func generate() ([]byte, []byte, error) {
+	names := make(map[reflect.Type]string, len(objectTypes))
+	for _, object := range objectTypes {
+		names[object.typ] = object.name
+	}
+
+	var schema, code bytes.Buffer
+	fmt.Fprintf(&schema, "# %s\n", header)
+	fmt.Fprintf(&code, "// %s\n\npackage graphqlapi\n\n", header)
+	code.WriteString("import (\n\t\"time\"\n\n\t\"github.com/Sh1ni-Gami/WB_Tech_L0/model\"\n\tgraphql \"github.com/graph-gophers/graphql-go\"\n)\n")
+
+	for _, object := range objectTypes {
+		receiver := lowerFirst(object.name)
+		fmt.Fprintf(&schema, "\ntype %s {\n", object.name)
+		fmt.Fprintf(&code, "\n// %sResolver поля GraphQL-типа %s.\ntype %sResolver struct {\n\t%s *model.%s\n}\n",
+			receiver, object.name, receiver, receiver, object.typ.Name())
+
+		for i := 0; i < object.typ.NumField(); i++ {
+			f, err := describeField(object.typ.Field(i), receiver, names)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s.%s: %w", object.typ.Name(), object.typ.Field(i).Name, err)
+			}
+			if f == nil {
+				continue
+			}
+			fmt.Fprintf(&schema, "  %s: %s\n", f.graphQLName, f.graphQLType)
+			fmt.Fprintf(&code, "\nfunc (r *%sResolver) %s() %s {\n\t%s\n}\n", receiver, f.method, f.goType, f.body)
+		}
+		schema.WriteString("}\n")
+	}
+
+	formatted, err := format.Source(code.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	return schema.Bytes(), formatted, nil
+}
+
+// describeField сопоставляет поле структуры полю GraphQL; поля без
+// JSON-тега пропускаются.
+func describeField(sf reflect.StructField, receiver string, names map[reflect.Type]string) (*field, error) {
+	tag := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if tag == "" || tag == "-" {
+		return nil, nil
+	}
+
+	f := &field{graphQLName: tag, method: methodName(tag)}
+	value := "r." + receiver + "." + sf.Name
+
+	switch {
+	case sf.Type == reflect.TypeOf(model.ISO8601Time{}) || sf.Type == reflect.TypeOf(model.UnixTime{}):
+		// Отсутствующее время возвращается как null
+		f.graphQLType, f.goType = "Time", "*graphql.Time"
+		f.body = fmt.Sprintf("return optionalTime(time.Time(%s))", value)
+	case sf.Type.Kind() == reflect.String:
+		f.graphQLType, f.goType = "String!", "string"
+		f.body = "return " + value
+	case sf.Type.Kind() == reflect.Int || sf.Type.Kind() == reflect.Int64:
+		// Int в GraphQL 32-битный, выход за диапазон возвращается ошибкой поля
+		f.graphQLType, f.goType = "Int!", "(int32, error)"
+		f.body = fmt.Sprintf("return toInt32(%s)", value)
+	case sf.Type.Kind() == reflect.Struct && names[sf.Type] != "":
+		name := names[sf.Type]
+		f.graphQLType, f.goType = name+"!", "*"+lowerFirst(name)+"Resolver"
+		f.body = fmt.Sprintf("return &%sResolver{%s: &%s}", lowerFirst(name), lowerFirst(name), value)
+	case sf.Type.Kind() == reflect.Slice && names[sf.Type.Elem()] != "":
+		name := names[sf.Type.Elem()]
+		element := lowerFirst(name)
+		f.graphQLType, f.goType = "["+name+"!]!", "[]*"+element+"Resolver"
+		f.body = fmt.Sprintf("resolvers := make([]*%sResolver, len(%s))\n\tfor i := range %s {\n\t\tresolvers[i] = &%sResolver{%s: &%s[i]}\n\t}\n\treturn resolvers",
+			element, value, value, element, element, value)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", sf.Type)
+	}
+	return f, nil
+}
+
+// methodName имя метода резолвера по имени поля: "order_uid" -> "OrderUID".
+// graphql-go сопоставляет их без учета регистра и подчеркиваний.
+func methodName(tag string) string {
+	var name strings.Builder
+	for _, part := range strings.Split(tag, "_") {
+		switch part {
+		case "id", "uid", "rid":
+			name.WriteString(strings.ToUpper(part))
+		default:
+			name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return name.String()
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
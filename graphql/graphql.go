@@ -0,0 +1,231 @@
+// Package graphqlapi предоставляет GraphQL-эндпоинт поверх хранилища заказов:
+// клиент сам выбирает поля заказа, доставки, оплаты и товаров и может пройти
+// от покупателя к его заказам и их товарам одним запросом.
+//
+// Типы заказа генерируются из пакета model (go generate), повторные и
+// параллельные обращения к заказам одного запроса объединяются в пачки
+// GetOrders, глубина и оценочная стоимость запроса ограничены.
+package graphqlapi
+
+//go:generate go run ./gen
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+const (
+	// DefaultMaxDepth максимальная вложенность полей запроса по умолчанию.
+	DefaultMaxDepth = 8
+	// DefaultMaxComplexity максимальная оценочная стоимость запроса по умолчанию.
+	DefaultMaxComplexity = 5000
+)
+
+var (
+	//go:embed schema.graphqls
+	rootSchema string
+	//go:embed model.graphqls
+	modelSchema string
+)
+
+// Store хранилище заказов с пакетной загрузкой.
+type Store interface {
+	GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error)
+}
+
+// CustomerIndex поиск заказов покупателя; идентификаторы возвращаются новыми первыми.
+type CustomerIndex interface {
+	GetOrderIDsByCustomer(ctx context.Context, customerID string) ([]string, error)
+}
+
+// Option настраивает GraphQL-эндпоинт.
+type Option func(*handler)
+
+// WithMaxDepth ограничивает вложенность полей запроса.
+func WithMaxDepth(depth int) Option {
+	return func(h *handler) {
+		h.maxDepth = depth
+	}
+}
+
+// WithMaxComplexity ограничивает оценочную стоимость запроса: каждое поле
+// стоит 1, стоимость полей внутри списков умножается на размер списка.
+func WithMaxComplexity(complexity int) Option {
+	return func(h *handler) {
+		h.maxComplexity = complexity
+	}
+}
+
+type handler struct {
+	schema    *graphql.Schema
+	store     Store
+	customers CustomerIndex
+	logger    *slog.Logger
+
+	maxDepth      int
+	maxComplexity int
+	// batchWait окно сбора пачки заказов; в тестах увеличивается.
+	batchWait time.Duration
+}
+
+// NewHandler создает HTTP-обработчик GraphQL. Запрос принимается методом POST
+// (JSON с полями query, operationName, variables) или GET с теми же параметрами.
+func NewHandler(store Store, customers CustomerIndex, logger *slog.Logger, opts ...Option) (http.Handler, error) {
+	h := &handler{
+		store:         store,
+		customers:     customers,
+		logger:        logger,
+		maxDepth:      DefaultMaxDepth,
+		maxComplexity: DefaultMaxComplexity,
+		batchWait:     batchWait,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.maxDepth <= 0 || h.maxComplexity <= 0 {
+		return nil, fmt.Errorf("GraphQL depth and complexity limits must be positive")
+	}
+
+	schema, err := graphql.ParseSchema(rootSchema+modelSchema, &queryResolver{},
+		graphql.MaxDepth(h.maxDepth),
+		graphql.Logger(panicLogger{logger: logger}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GraphQL schema: %w", err)
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// request параметры запроса GraphQL по HTTP.
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Проверка схемы и глубины до оценки стоимости: оценка рассчитывает на
+	// синтаксически верный запрос
+	if errs := h.schema.ValidateWithVariables(params.Query, params.Variables); len(errs) > 0 {
+		h.writeResponse(w, r, &graphql.Response{Errors: errs})
+		return
+	}
+	complexity, err := queryComplexity(params.Query, params.Variables)
+	if err != nil {
+		h.writeResponse(w, r, errorResponse(err.Error()))
+		return
+	}
+	if complexity > h.maxComplexity {
+		logging.FromContext(r.Context(), h.logger).Warn("GraphQL query rejected by complexity limit",
+			slog.Int("complexity", complexity), slog.Int("limit", h.maxComplexity))
+		h.writeResponse(w, r, errorResponse(fmt.Sprintf("query complexity %d exceeds limit %d", complexity, h.maxComplexity)))
+		return
+	}
+
+	state := &requestState{
+		loaders: newLoaders(h.store, h.customers, h.logger, h.batchWait),
+		mask:    maskFrom(r.Context()),
+	}
+	ctx := context.WithValue(r.Context(), requestStateKey{}, state)
+	h.writeResponse(w, r, h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+}
+
+// parseRequest читает параметры запроса из тела POST или строки запроса GET.
+func parseRequest(r *http.Request) (request, error) {
+	var params request
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			return params, fmt.Errorf("invalid JSON request body")
+		}
+	default:
+		query := r.URL.Query()
+		params.Query = query.Get("query")
+		params.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+				return params, fmt.Errorf("variables must be a JSON object")
+			}
+		}
+	}
+	if params.Query == "" {
+		return params, fmt.Errorf("query is required")
+	}
+	return params, nil
+}
+
+// writeResponse отвечает 200 и в случае ошибок запроса: по соглашению GraphQL
+// они передаются в поле errors.
+func (h *handler) writeResponse(w http.ResponseWriter, r *http.Request, response *graphql.Response) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		logging.FromContext(r.Context(), h.logger).Error("Failed to encode GraphQL response", slog.Any("error", err))
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write(body); err != nil {
+		logging.FromContext(r.Context(), h.logger).Error("Failed to write GraphQL response", slog.Any("error", err))
+	}
+}
+
+func errorResponse(message string) *graphql.Response {
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{{Message: message}}}
+}
+
+// requestState загрузчики и маскирование одного запроса.
+type requestState struct {
+	loaders *loaders
+	mask    func(*model.OrderDetails) *model.OrderDetails
+}
+
+type requestStateKey struct{}
+
+func stateFrom(ctx context.Context) *requestState {
+	return ctx.Value(requestStateKey{}).(*requestState)
+}
+
+type maskKey struct{}
+
+// ContextWithMask задает маскирование персональных данных для заказов,
+// возвращаемых в ответ на запрос с этим контекстом.
+func ContextWithMask(ctx context.Context, mask func(*model.OrderDetails) *model.OrderDetails) context.Context {
+	return context.WithValue(ctx, maskKey{}, mask)
+}
+
+func maskFrom(ctx context.Context) func(*model.OrderDetails) *model.OrderDetails {
+	mask, _ := ctx.Value(maskKey{}).(func(*model.OrderDetails) *model.OrderDetails)
+	return mask
+}
+
+// panicLogger пишет панику резолвера в журнал запроса со стеком.
+type panicLogger struct {
+	logger *slog.Logger
+}
+
+func (l panicLogger) LogPanic(ctx context.Context, value any) {
+	logging.FromContext(ctx, l.logger).Error("Panic while resolving GraphQL query",
+		slog.Any("panic", value),
+		slog.String("stack", string(debug.Stack())),
+	)
+}
@@ -0,0 +1,152 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// stubStore заказы и индекс покупателей в памяти; запоминает вызовы GetOrders.
+type stubStore struct {
+	orders    map[string]*model.OrderDetails
+	customers map[string][]string
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (s *stubStore) GetOrders(_ context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, slices.Clone(orderUIDs))
+	s.mu.Unlock()
+
+	found := make(map[string]*model.OrderDetails)
+	for _, orderUID := range orderUIDs {
+		if order, ok := s.orders[orderUID]; ok {
+			found[orderUID] = order
+		}
+	}
+	return found, nil
+}
+
+func (s *stubStore) GetOrderIDsByCustomer(_ context.Context, customerID string) ([]string, error) {
+	return s.customers[customerID], nil
+}
+
+func newStubStore() *stubStore {
+	orders := make(map[string]*model.OrderDetails)
+	for _, orderUID := range []string{"o1", "o2", "o3"} {
+		orders[orderUID] = &model.OrderDetails{OrderID: orderUID, CustomerID: "c1"}
+	}
+	return &stubStore{
+		orders:    orders,
+		customers: map[string][]string{"c1": {"o3", "o2", "o1"}},
+	}
+}
+
+// execute выполняет запрос и возвращает ответ GraphQL.
+func execute(t *testing.T, h http.Handler, query string) (data map[string]any, errs []string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var response struct {
+		Data   map[string]any `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	for _, e := range response.Errors {
+		errs = append(errs, e.Message)
+	}
+	return response.Data, errs
+}
+
+func newTestHandler(t *testing.T, store *stubStore, opts ...Option) http.Handler {
+	t.Helper()
+	h, err := NewHandler(store, store, slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	return h
+}
+
+func TestOrdersBatchIntoSingleGetOrders(t *testing.T) {
+	store := newStubStore()
+	h := newTestHandler(t, store)
+	// Широкое окно, чтобы исход не зависел от планировщика
+	h.(*handler).batchWait = 100 * time.Millisecond
+
+	data, errs := execute(t, h, `{
+		orders(order_uids: ["o1", "o2"]) { order_uid }
+		again: orders(order_uids: ["o2"]) { order_uid }
+		customer(customer_id: "c1") { orders(first: 2) { order_uid } }
+	}`)
+	if len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+
+	if len(store.calls) != 1 {
+		t.Fatalf("GetOrders called %d times (%v), want once", len(store.calls), store.calls)
+	}
+	keys := slices.Clone(store.calls[0])
+	slices.Sort(keys)
+	if want := []string{"o1", "o2", "o3"}; !slices.Equal(keys, want) {
+		t.Errorf("GetOrders keys = %v, want %v", keys, want)
+	}
+
+	customer := data["customer"].(map[string]any)
+	if got := len(customer["orders"].([]any)); got != 2 {
+		t.Errorf("customer orders = %d, want 2", got)
+	}
+}
+
+func TestComplexityLimitRejectsQuery(t *testing.T) {
+	store := newStubStore()
+	h := newTestHandler(t, store, WithMaxComplexity(30))
+
+	_, errs := execute(t, h, `{ orders(order_uids: ["o1", "o2", "o3"]) { order_uid items { name } } }`)
+	if len(errs) != 1 || errs[0] != "query complexity 37 exceeds limit 30" {
+		t.Errorf("errors = %v, want the complexity limit error", errs)
+	}
+	if len(store.calls) != 0 {
+		t.Errorf("rejected query loaded orders: %v", store.calls)
+	}
+
+	if _, errs := execute(t, h, `{ orders(order_uids: ["o1", "o2"]) { order_uid items { name } } }`); len(errs) > 0 {
+		t.Errorf("query within the limit failed: %v", errs)
+	}
+}
+
+func TestDepthLimitRejectsQuery(t *testing.T) {
+	query := `{ order(order_uid: "o1") { customer { orders { customer { customer_id } } } } }`
+
+	store := newStubStore()
+	if _, errs := execute(t, newTestHandler(t, store, WithMaxDepth(4)), query); len(errs) == 0 {
+		t.Error("query deeper than the limit was accepted")
+	}
+	if len(store.calls) != 0 {
+		t.Errorf("rejected query loaded orders: %v", store.calls)
+	}
+
+	if _, errs := execute(t, newTestHandler(t, newStubStore(), WithMaxDepth(5)), query); len(errs) > 0 {
+		t.Errorf("query within the limit failed: %v", errs)
+	}
+}
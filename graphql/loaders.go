@@ -0,0 +1,76 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// batchWait сколько загрузчик ждет другие запросы перед обращением к хранилищу.
+const batchWait = 2 * time.Millisecond
+
+// errLoadFailed ошибка, которую видит клиент; подробности пишутся в журнал.
+var errLoadFailed = errors.New("failed to load orders")
+
+// loaders загрузчики одного запроса GraphQL: одинаковые ключи загружаются
+// один раз, а ключи, запрошенные параллельными резолверами, — одной пачкой.
+type loaders struct {
+	orders    *dataloader.Loader[string, *model.OrderDetails]
+	customers *dataloader.Loader[string, []string]
+}
+
+// newLoaders создает загрузчики запроса; wait — окно сбора пачки заказов.
+// Заказы покупателей ищутся по одному, поэтому их загрузчик не ждет: так
+// Customer.orders успевает попасть в ту же пачку GetOrders, что и Query.orders.
+func newLoaders(store Store, customers CustomerIndex, logger *slog.Logger, wait time.Duration) *loaders {
+	return &loaders{
+		orders: dataloader.NewBatchedLoader(ordersBatch(store, logger),
+			dataloader.WithWait[string, *model.OrderDetails](wait)),
+		customers: dataloader.NewBatchedLoader(customersBatch(customers, logger),
+			dataloader.WithWait[string, []string](0)),
+	}
+}
+
+// ordersBatch загружает пачку заказов одним вызовом GetOrders. Для
+// отсутствующих заказов результат nil без ошибки.
+func ordersBatch(store Store, logger *slog.Logger) dataloader.BatchFunc[string, *model.OrderDetails] {
+	return func(ctx context.Context, orderUIDs []string) []*dataloader.Result[*model.OrderDetails] {
+		results := make([]*dataloader.Result[*model.OrderDetails], len(orderUIDs))
+
+		orders, err := store.GetOrders(ctx, orderUIDs)
+		if err != nil {
+			logging.FromContext(ctx, logger).Error("Failed to load orders for GraphQL",
+				slog.Int("count", len(orderUIDs)), slog.Any("error", err))
+		}
+		for i, orderUID := range orderUIDs {
+			if err != nil {
+				results[i] = &dataloader.Result[*model.OrderDetails]{Error: errLoadFailed}
+				continue
+			}
+			results[i] = &dataloader.Result[*model.OrderDetails]{Data: orders[orderUID]}
+		}
+		return results
+	}
+}
+
+// customersBatch загружает идентификаторы заказов покупателей, новые первыми.
+func customersBatch(customers CustomerIndex, logger *slog.Logger) dataloader.BatchFunc[string, []string] {
+	return func(ctx context.Context, customerIDs []string) []*dataloader.Result[[]string] {
+		results := make([]*dataloader.Result[[]string], len(customerIDs))
+		for i, customerID := range customerIDs {
+			orderUIDs, err := customers.GetOrderIDsByCustomer(ctx, customerID)
+			if err != nil {
+				logging.FromContext(ctx, logger).Error("Failed to load customer orders for GraphQL",
+					slog.String("customer_id", customerID), slog.Any("error", err))
+				err = errLoadFailed
+			}
+			results[i] = &dataloader.Result[[]string]{Data: orderUIDs, Error: err}
+		}
+		return results
+	}
+}
@@ -0,0 +1,147 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// maxOrdersPerQuery максимальное количество order_uid в Query.orders.
+	maxOrdersPerQuery = 100
+	// maxCustomerOrders максимальное значение first в Customer.orders.
+	maxCustomerOrders = 100
+)
+
+// queryResolver корневой тип Query.
+type queryResolver struct{}
+
+func (queryResolver) Order(ctx context.Context, args struct{ OrderUID string }) (*orderResolver, error) {
+	order, err := stateFrom(ctx).loaders.orders.Load(ctx, args.OrderUID)()
+	if err != nil || order == nil {
+		return nil, err
+	}
+	return newOrderResolver(ctx, order), nil
+}
+
+func (queryResolver) Orders(ctx context.Context, args struct{ OrderUIDs []string }) ([]*orderResolver, error) {
+	if len(args.OrderUIDs) > maxOrdersPerQuery {
+		return nil, fmt.Errorf("at most %d order_uids per query", maxOrdersPerQuery)
+	}
+
+	orders, errs := stateFrom(ctx).loaders.orders.LoadMany(ctx, args.OrderUIDs)()
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+	resolvers := make([]*orderResolver, len(orders))
+	for i, order := range orders {
+		if order != nil {
+			resolvers[i] = newOrderResolver(ctx, order)
+		}
+	}
+	return resolvers, nil
+}
+
+func (queryResolver) Customer(args struct{ CustomerID string }) *customerResolver {
+	return &customerResolver{customerID: args.CustomerID}
+}
+
+// Customer связь заказа с покупателем; дополняет сгенерированный orderResolver.
+func (r *orderResolver) Customer() *customerResolver {
+	if r.order.CustomerID == "" {
+		return nil
+	}
+	return &customerResolver{customerID: r.order.CustomerID}
+}
+
+// State состояние заказа, выведенное из статусов товаров.
+func (r *orderResolver) State() string {
+	return string(r.order.State())
+}
+
+func (r *orderResolver) StateDescription() string {
+	return r.order.State().Description()
+}
+
+// StatusName имя статуса товара; дополняет сгенерированный itemResolver.
+func (r *itemResolver) StatusName() string {
+	return r.item.Status.Name()
+}
+
+func (r *itemResolver) StatusDescription() string {
+	return r.item.Status.Description()
+}
+
+// customerResolver тип Customer.
+type customerResolver struct {
+	customerID string
+}
+
+func (r *customerResolver) CustomerID() string {
+	return r.customerID
+}
+
+func (r *customerResolver) Orders(ctx context.Context, args struct{ First int32 }) ([]*orderResolver, error) {
+	if args.First < 0 || args.First > maxCustomerOrders {
+		return nil, fmt.Errorf("first must be between 0 and %d", maxCustomerOrders)
+	}
+
+	loaders := stateFrom(ctx).loaders
+	orderUIDs, err := loaders.customers.Load(ctx, r.customerID)()
+	if err != nil {
+		return nil, err
+	}
+	if len(orderUIDs) > int(args.First) {
+		orderUIDs = orderUIDs[:args.First]
+	}
+
+	orders, errs := loaders.orders.LoadMany(ctx, orderUIDs)()
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+	resolvers := make([]*orderResolver, 0, len(orders))
+	for _, order := range orders {
+		// Заказ мог быть удален между запросами
+		if order != nil {
+			resolvers = append(resolvers, newOrderResolver(ctx, order))
+		}
+	}
+	return resolvers, nil
+}
+
+// newOrderResolver применяет к заказу маскирование персональных данных запроса.
+func newOrderResolver(ctx context.Context, order *model.OrderDetails) *orderResolver {
+	if mask := stateFrom(ctx).mask; mask != nil {
+		order = mask(order)
+	}
+	return &orderResolver{order: order}
+}
+
+// toInt32 переводит значение в Int GraphQL, который по спецификации 32-битный.
+func toInt32[T ~int | ~int64](value T) (int32, error) {
+	if int64(value) < math.MinInt32 || int64(value) > math.MaxInt32 {
+		return 0, fmt.Errorf("value %d does not fit into GraphQL Int", value)
+	}
+	return int32(value), nil
+}
+
+// optionalTime возвращает nil для отсутствующего времени.
+func optionalTime(t time.Time) *graphql.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package grpctransport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	httptransport "github.com/Sh1ni-Gami/WB_Tech_L0/transport"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// credentialKeys метаданные с учетными данными, которые передаются
+// аутентификаторам как одноименные заголовки HTTP.
+var credentialKeys = []string{"authorization", "x-api-key"}
+
+type principalKey struct{}
+
+// authEnabled включена ли проверка доступа.
+func (t *grpcTransport) authEnabled() bool {
+	return len(t.authenticators) > 0
+}
+
+// authorize проверяет учетные данные вызова и область orders:read и кладет
+// клиента в контекст. Проверка здоровья доступна без учетных данных.
+func (t *grpcTransport) authorize(ctx context.Context, method string) (context.Context, error) {
+	if !t.authEnabled() || strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return ctx, nil
+	}
+
+	// Аутентификаторы HTTP API читают заголовки запроса
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range credentialKeys {
+			for _, value := range md.Get(key) {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+
+	principal, err := httptransport.AuthenticateRequest(req, t.anonymousScopes, t.authenticators)
+	if errors.Is(err, httptransport.ErrAuthUnavailable) {
+		logging.FromContext(ctx, t.logger).Error("Failed to authenticate call", slog.String("method", method), slog.Any("error", err))
+		return nil, status.Error(codes.Unavailable, "authentication is temporarily unavailable")
+	}
+	if err != nil {
+		t.auditDenied(ctx, method, nil, err.Error())
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+	}
+	if !principal.HasScope(httptransport.ScopeOrdersRead) {
+		t.auditDenied(ctx, method, principal, "missing scope")
+		if principal.Method == "anonymous" {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "scope %s is required", httptransport.ScopeOrdersRead)
+	}
+
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// auditDenied пишет в журнал отказ в доступе, как HTTP API.
+func (t *grpcTransport) auditDenied(ctx context.Context, method string, principal *httptransport.Principal, reason string) {
+	attrs := []any{
+		slog.Bool("audit", true),
+		slog.String("method", method),
+		slog.String("required_scope", httptransport.ScopeOrdersRead),
+		slog.String("reason", reason),
+	}
+	if principal != nil {
+		attrs = append(attrs, slog.String("subject", principal.Subject), slog.String("auth_method", principal.Method))
+	}
+	logging.FromContext(ctx, t.logger).Warn("Access denied", attrs...)
+}
+
+// mask применяет к заказу профиль маскирования клиента вызова.
+func (t *grpcTransport) mask(ctx context.Context, order *model.OrderDetails) *model.OrderDetails {
+	if t.masking == nil {
+		return order
+	}
+	principal, _ := ctx.Value(principalKey{}).(*httptransport.Principal)
+	return order.Masked(t.masking.ProfileFor(principal))
+}
@@ -0,0 +1,118 @@
+package grpctransport
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	ordersv1 "github.com/Sh1ni-Gami/WB_Tech_L0/proto/orders/v1"
+	httptransport "github.com/Sh1ni-Gami/WB_Tech_L0/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stubStore хранилище заказов в памяти, считающее обращения.
+type stubStore struct {
+	orders     map[string]*model.OrderDetails
+	getCalls   int
+	batchCalls int
+}
+
+func (s *stubStore) GetOrder(_ context.Context, orderUID string) (*model.OrderDetails, error) {
+	s.getCalls++
+	order, ok := s.orders[orderUID]
+	if !ok {
+		return nil, model.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (s *stubStore) GetOrders(_ context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error) {
+	s.batchCalls++
+	orders := make(map[string]*model.OrderDetails)
+	for _, orderUID := range orderUIDs {
+		if order, ok := s.orders[orderUID]; ok {
+			orders[orderUID] = order
+		}
+	}
+	return orders, nil
+}
+
+func newTestTransport(t *testing.T, store Store, opts ...Option) *grpcTransport {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewGRPCTransport(store, nil, logger, opts...).(*grpcTransport)
+}
+
+func TestUnaryInterceptorAuthorization(t *testing.T) {
+	keys := httptransport.StaticAPIKeys{
+		httptransport.HashAPIKey("reader"): {Name: "reader", Scopes: []string{httptransport.ScopeOrdersRead}},
+		httptransport.HashAPIKey("writer"): {Name: "writer", Scopes: []string{httptransport.ScopeOrdersWrite}},
+	}
+	transport := newTestTransport(t, &stubStore{},
+		WithAuthentication(nil, httptransport.NewAPIKeyAuthenticator(keys)))
+
+	tests := []struct {
+		name   string
+		method string
+		md     metadata.MD
+		want   codes.Code
+	}{
+		{"reader key", "/orders.v1.OrderService/GetOrder", metadata.Pairs("x-api-key", "reader"), codes.OK},
+		{"bearer key", "/orders.v1.OrderService/GetOrder", metadata.Pairs("authorization", "Bearer reader"), codes.OK},
+		{"no credentials", "/orders.v1.OrderService/GetOrder", nil, codes.Unauthenticated},
+		{"unknown key", "/orders.v1.OrderService/GetOrder", metadata.Pairs("x-api-key", "other"), codes.Unauthenticated},
+		{"missing scope", "/orders.v1.OrderService/GetOrder", metadata.Pairs("x-api-key", "writer"), codes.PermissionDenied},
+		{"health check", "/grpc.health.v1.Health/Check", nil, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+			_, err := transport.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchGetOrdersUsesBatchLookupAndMasking(t *testing.T) {
+	store := &stubStore{orders: map[string]*model.OrderDetails{
+		"o1": {OrderID: "o1", Address: model.AddressDetails{Phone: "+9720000000", Email: "test@gmail.com"}},
+		"o2": {OrderID: "o2", Address: model.AddressDetails{Phone: "+9720000001", Email: "test@gmail.com"}},
+	}}
+	policy, err := httptransport.NewMaskingPolicy("redacted", "", "")
+	if err != nil {
+		t.Fatalf("NewMaskingPolicy: %v", err)
+	}
+	transport := newTestTransport(t, store, WithMasking(policy))
+
+	resp, err := transport.BatchGetOrders(context.Background(), &ordersv1.BatchGetOrdersRequest{
+		OrderUids: []string{"o2", "missing", "o1", "o2"},
+	})
+	if err != nil {
+		t.Fatalf("BatchGetOrders: %v", err)
+	}
+
+	if store.batchCalls != 1 || store.getCalls != 0 {
+		t.Errorf("store calls: %d batch, %d single, want one batch lookup", store.batchCalls, store.getCalls)
+	}
+	if len(resp.GetOrders()) != 2 || resp.GetOrders()[0].GetOrderUid() != "o2" || resp.GetOrders()[1].GetOrderUid() != "o1" {
+		t.Errorf("orders %v, want o2 and o1 in request order", resp.GetOrders())
+	}
+	if missing := resp.GetMissingOrderUids(); len(missing) != 1 || missing[0] != "missing" {
+		t.Errorf("missing %v, want [missing]", missing)
+	}
+	for _, order := range resp.GetOrders() {
+		if phone := order.GetDelivery().GetPhone(); phone == store.orders[order.GetOrderUid()].Address.Phone {
+			t.Errorf("order %s: phone %q is not masked", order.GetOrderUid(), phone)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package grpctransport
+
+import (
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	ordersv1 "github.com/Sh1ni-Gami/WB_Tech_L0/proto/orders/v1"
+)
+
+// fromProtoFilter переводит orders.v1.OrderFilter в фильтр хранилища.
+func fromProtoFilter(filter *ordersv1.OrderFilter) model.OrderFilter {
+	var result model.OrderFilter
+	if filter == nil {
+		return result
+	}
+	if filter.GetCreatedFrom() != nil {
+		result.CreatedFrom = filter.GetCreatedFrom().AsTime()
+	}
+	if filter.GetCreatedTo() != nil {
+		result.CreatedTo = filter.GetCreatedTo().AsTime()
+	}
+	result.CustomerID = filter.GetCustomerId()
+	result.DeliveryService = filter.GetDeliveryService()
+	return result
+}
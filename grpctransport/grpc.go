@@ -0,0 +1,147 @@
+// Package grpctransport предоставляет API заказов по gRPC (orders.v1.OrderService)
+// рядом с HTTP API: с тем же кэшем и хранилищем, на отдельном порту.
+package grpctransport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	ordersv1 "github.com/Sh1ni-Gami/WB_Tech_L0/proto/orders/v1"
+	httptransport "github.com/Sh1ni-Gami/WB_Tech_L0/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// shutdownTimeout время на завершение активных вызовов при остановке,
+// после которого соединения закрываются принудительно.
+const shutdownTimeout = 5 * time.Second
+
+// Store хранилище заказов (кэш поверх базы данных).
+type Store interface {
+	GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error)
+	GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error)
+}
+
+// OrderSource база данных: списки заказов и уведомления о новых заказах.
+type OrderSource interface {
+	ListOrders(ctx context.Context, filter model.OrderFilter) ([]*model.OrderDetails, error)
+	ListenNewOrders(ctx context.Context, fn func(orderUID string)) error
+}
+
+// GRPCTransport интерфейс для работы с gRPC-сервером.
+type GRPCTransport interface {
+	Start(ctx context.Context, addr string) error
+}
+
+// grpcTransport реализует GRPCTransport и orders.v1.OrderService.
+type grpcTransport struct {
+	ordersv1.UnimplementedOrderServiceServer
+
+	store  Store
+	source OrderSource
+	logger *slog.Logger
+	hub    *orderHub
+
+	// authenticators способы аутентификации; пустой список отключает проверку доступа.
+	authenticators  []httptransport.Authenticator
+	anonymousScopes []string
+	// masking маскирование персональных данных, nil если отключено.
+	masking *httptransport.MaskingPolicy
+}
+
+// Option настраивает grpcTransport.
+type Option func(*grpcTransport)
+
+// WithAuthentication включает проверку доступа теми же аутентификаторами,
+// что и в HTTP API: учетные данные берутся из метаданных authorization и
+// x-api-key, для вызовов нужна область orders:read. Вызовы без учетных
+// данных получают anonymousScopes.
+func WithAuthentication(anonymousScopes []string, authenticators ...httptransport.Authenticator) Option {
+	return func(t *grpcTransport) {
+		t.authenticators = authenticators
+		t.anonymousScopes = anonymousScopes
+	}
+}
+
+// WithMasking включает маскирование персональных данных в ответах.
+func WithMasking(policy *httptransport.MaskingPolicy) Option {
+	return func(t *grpcTransport) {
+		t.masking = policy
+	}
+}
+
+// NewGRPCTransport создает экземпляр GRPCTransport.
+func NewGRPCTransport(store Store, source OrderSource, logger *slog.Logger, opts ...Option) GRPCTransport {
+	t := &grpcTransport{
+		store:  store,
+		source: source,
+		logger: logger,
+		hub:    newOrderHub(),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Start запускает gRPC-сервер и блокируется до его остановки. Сервер
+// корректно завершает работу при отмене ctx.
+func (t *grpcTransport) Start(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.logger.Error("gRPC server failed to listen", slog.String("address", addr), slog.Any("error", err))
+		return err
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(t.unaryInterceptor),
+		grpc.ChainStreamInterceptor(t.streamInterceptor),
+	)
+	ordersv1.RegisterOrderServiceServer(server, t)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(ordersv1.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	go t.hub.run(ctx, t.source, t.logger)
+	go t.listenForShutdown(ctx, server, healthServer)
+
+	t.logger.Info("gRPC server starting", slog.String("address", addr))
+	if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		t.logger.Error("gRPC server failed", slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
+
+// listenForShutdown ожидает отмены ctx и останавливает сервер: сначала
+// проверка здоровья переводится в NOT_SERVING, затем ожидаются активные вызовы.
+func (t *grpcTransport) listenForShutdown(ctx context.Context, server *grpc.Server, healthServer *health.Server) {
+	<-ctx.Done()
+
+	t.logger.Info("Shutting down gRPC server gracefully...")
+	healthServer.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.logger.Info("gRPC server shut down successfully")
+	case <-time.After(shutdownTimeout):
+		server.Stop()
+		t.logger.Warn("gRPC server shut down forcibly after timeout")
+	}
+}
@@ -0,0 +1,85 @@
+package grpctransport
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// subscriberBuffer сколько уведомлений может ждать одного подписчика
+	// WatchOrders, прежде чем он будет отключен как отстающий.
+	subscriberBuffer = 256
+	// listenRetryDelay пауза перед повторной подпиской на уведомления базы.
+	listenRetryDelay = time.Second
+)
+
+// subscription подписка одного вызова WatchOrders.
+type subscription struct {
+	orders chan string
+	// lagged закрывается, если подписчик не успевал читать уведомления.
+	lagged chan struct{}
+}
+
+// orderHub получает из базы уведомления о новых заказах и раздает их
+// подписчикам WatchOrders. Уведомления идут через LISTEN/NOTIFY, поэтому
+// подписчики видят заказы, сохраненные любой репликой сервиса.
+type orderHub struct {
+	mu          sync.Mutex
+	subscribers map[*subscription]struct{}
+}
+
+func newOrderHub() *orderHub {
+	return &orderHub{subscribers: make(map[*subscription]struct{})}
+}
+
+func (h *orderHub) subscribe() *subscription {
+	sub := &subscription{
+		orders: make(chan string, subscriberBuffer),
+		lagged: make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *orderHub) unsubscribe(sub *subscription) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	h.mu.Unlock()
+}
+
+// publish раздает order_uid подписчикам. Отстающий подписчик отключается,
+// а не пропускает заказы молча.
+func (h *orderHub) publish(orderUID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		select {
+		case sub.orders <- orderUID:
+		default:
+			close(sub.lagged)
+			delete(h.subscribers, sub)
+		}
+	}
+}
+
+// run слушает уведомления базы до отмены ctx, переподключаясь после ошибок.
+func (h *orderHub) run(ctx context.Context, source OrderSource, logger *slog.Logger) {
+	for {
+		err := source.ListenNewOrders(ctx, h.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("Listening for new orders failed, retrying", slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
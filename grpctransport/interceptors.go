@@ -0,0 +1,108 @@
+package grpctransport
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey ключ метаданных с идентификатором запроса, аналог заголовка
+// X-Request-ID в HTTP API.
+const requestIDKey = "x-request-id"
+
+// unaryInterceptor назначает вызову идентификатор, проверяет доступ, пишет
+// запись журнала и перехватывает панику обработчика.
+func (t *grpcTransport) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	ctx = t.withRequestID(ctx)
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = t.recovered(ctx, recovered)
+		}
+		t.logCall(ctx, info.FullMethod, start, err)
+	}()
+
+	authorized, err := t.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(authorized, req)
+}
+
+// streamInterceptor то же для потоковых вызовов, включая отражение схемы;
+// запись журнала пишется по завершении потока.
+func (t *grpcTransport) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx := t.withRequestID(stream.Context())
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = t.recovered(ctx, recovered)
+		}
+		t.logCall(ctx, info.FullMethod, start, err)
+	}()
+
+	authorized, err := t.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: authorized})
+}
+
+// withRequestID берет корректный x-request-id из метаданных клиента или
+// генерирует новый, возвращает его в заголовке ответа и кладет логгер с
+// идентификатором в контекст.
+func (t *grpcTransport) withRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !logging.ValidRequestID(requestID) {
+		requestID = logging.NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+
+	return logging.WithLogger(ctx, t.logger.With(slog.String("request_id", requestID)))
+}
+
+// recovered пишет панику в журнал со стеком и превращает ее в ошибку Internal.
+func (t *grpcTransport) recovered(ctx context.Context, recovered any) error {
+	logging.FromContext(ctx, t.logger).Error("Panic while handling gRPC call",
+		slog.Any("panic", recovered),
+		slog.String("stack", string(debug.Stack())),
+	)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// logCall пишет одну запись журнала на вызов: метод, код и время обработки.
+func (t *grpcTransport) logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	logging.FromContext(ctx, t.logger).Log(ctx, level, "gRPC call",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+	)
+}
+
+// contextStream подменяет контекст потока контекстом с логгером запроса и
+// клиентом.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
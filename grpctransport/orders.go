@@ -0,0 +1,201 @@
+package grpctransport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	ordersv1 "github.com/Sh1ni-Gami/WB_Tech_L0/proto/orders/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxBatchOrders максимальное количество order_uid в BatchGetOrders.
+	maxBatchOrders = 1000
+	// defaultPageSize и maxPageSize размер страницы ListOrders.
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// GetOrder возвращает заказ по order_uid.
+func (t *grpcTransport) GetOrder(ctx context.Context, req *ordersv1.GetOrderRequest) (*ordersv1.GetOrderResponse, error) {
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+
+	order, err := t.store.GetOrder(ctx, req.GetOrderUid())
+	if err != nil {
+		return nil, t.orderError(ctx, req.GetOrderUid(), err)
+	}
+	return &ordersv1.GetOrderResponse{Order: ordersv1.FromModel(t.mask(ctx, order))}, nil
+}
+
+// BatchGetOrders возвращает заказы по списку order_uid в порядке запроса.
+// Заказы загружаются одним обращением к кэшу, промахи — одним запросом к
+// базе. Отсутствующие заказы перечисляются в missing_order_uids, а не
+// считаются ошибкой.
+func (t *grpcTransport) BatchGetOrders(ctx context.Context, req *ordersv1.BatchGetOrdersRequest) (*ordersv1.BatchGetOrdersResponse, error) {
+	if len(req.GetOrderUids()) > maxBatchOrders {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d order_uids per request", maxBatchOrders)
+	}
+
+	orderUIDs := make([]string, 0, len(req.GetOrderUids()))
+	seen := make(map[string]bool, len(req.GetOrderUids()))
+	for _, orderUID := range req.GetOrderUids() {
+		if orderUID == "" || seen[orderUID] {
+			continue
+		}
+		seen[orderUID] = true
+		orderUIDs = append(orderUIDs, orderUID)
+	}
+
+	response := &ordersv1.BatchGetOrdersResponse{}
+	if len(orderUIDs) == 0 {
+		return response, nil
+	}
+	orders, err := t.store.GetOrders(ctx, orderUIDs)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error("Failed to fetch orders", slog.Int("count", len(orderUIDs)), slog.Any("error", err))
+		return nil, status.Error(codes.Internal, "failed to fetch orders")
+	}
+	for _, orderUID := range orderUIDs {
+		order, ok := orders[orderUID]
+		if !ok {
+			response.MissingOrderUids = append(response.MissingOrderUids, orderUID)
+			continue
+		}
+		response.Orders = append(response.Orders, ordersv1.FromModel(t.mask(ctx, order)))
+	}
+	return response, nil
+}
+
+// ListOrders возвращает страницу заказов в порядке (date_created, order_uid).
+func (t *grpcTransport) ListOrders(ctx context.Context, req *ordersv1.ListOrdersRequest) (*ordersv1.ListOrdersResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	filter := fromProtoFilter(req.GetFilter())
+	if token := req.GetPageToken(); token != "" {
+		after, err := decodePageToken(token)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		filter.After = after
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Лишний заказ показывает, есть ли следующая страница
+	filter.Limit = pageSize + 1
+	orders, err := t.source.ListOrders(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error("Failed to list orders", slog.Any("error", err))
+		return nil, status.Error(codes.Internal, "failed to list orders")
+	}
+
+	response := &ordersv1.ListOrdersResponse{}
+	if len(orders) > pageSize {
+		orders = orders[:pageSize]
+		last := orders[len(orders)-1]
+		response.NextPageToken = encodePageToken(model.OrderKey{
+			CreatedAt: time.Time(last.CreationTimestamp),
+			OrderUID:  last.OrderID,
+		})
+	}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, ordersv1.FromModel(t.mask(ctx, order)))
+	}
+	return response, nil
+}
+
+// WatchOrders передает клиенту новые заказы, сохраненные любой репликой,
+// пока клиент не отменит вызов.
+func (t *grpcTransport) WatchOrders(req *ordersv1.WatchOrdersRequest, stream ordersv1.OrderService_WatchOrdersServer) error {
+	ctx := stream.Context()
+	filter := fromProtoFilter(req.GetFilter())
+
+	subscription := t.hub.subscribe()
+	defer t.hub.unsubscribe(subscription)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-subscription.lagged:
+			return status.Error(codes.ResourceExhausted, "client is too slow to receive orders, resubscribe")
+		case orderUID := <-subscription.orders:
+			order, err := t.store.GetOrder(ctx, orderUID)
+			if err != nil {
+				logging.FromContext(ctx, t.logger).Warn("Failed to load new order for watcher",
+					slog.String("orderUID", orderUID), slog.Any("error", err))
+				continue
+			}
+			if !matchesFilter(order, filter) {
+				continue
+			}
+			if err := stream.Send(&ordersv1.WatchOrdersResponse{Order: ordersv1.FromModel(t.mask(ctx, order))}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// orderError переводит ошибку хранилища в статус gRPC.
+func (t *grpcTransport) orderError(ctx context.Context, orderUID string, err error) error {
+	if errors.Is(err, model.ErrOrderNotFound) {
+		return status.Error(codes.NotFound, "order not found")
+	}
+	logging.FromContext(ctx, t.logger).Error("Failed to fetch order", slog.String("orderUID", orderUID), slog.Any("error", err))
+	return status.Error(codes.Internal, "failed to fetch order")
+}
+
+// matchesFilter проверяет заказ по условиям WatchOrders.
+func matchesFilter(order *model.OrderDetails, filter model.OrderFilter) bool {
+	if filter.CustomerID != "" && order.CustomerID != filter.CustomerID {
+		return false
+	}
+	if filter.DeliveryService != "" && order.DeliveryService != filter.DeliveryService {
+		return false
+	}
+	return true
+}
+
+// pageToken содержимое next_page_token; для клиента токен непрозрачен.
+type pageToken struct {
+	CreatedAt int64  `json:"t"`
+	OrderUID  string `json:"u"`
+}
+
+func encodePageToken(key model.OrderKey) string {
+	data, _ := json.Marshal(pageToken{CreatedAt: key.CreatedAt.UnixMicro(), OrderUID: key.OrderUID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (*model.OrderKey, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var decoded pageToken
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	if decoded.OrderUID == "" {
+		return nil, errors.New("empty order_uid in page token")
+	}
+	return &model.OrderKey{CreatedAt: time.UnixMicro(decoded.CreatedAt).UTC(), OrderUID: decoded.OrderUID}, nil
+}
@@ -0,0 +1,144 @@
+// Package invoice формирует печатную накладную заказа для склада.
+package invoice
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+//go:embed invoice.html.tmpl
+var templates embed.FS
+
+var invoiceTemplate = template.Must(template.ParseFS(templates, "invoice.html.tmpl"))
+
+// page данные шаблона накладной.
+type page struct {
+	Lang   string
+	Labels labels
+	Order  *model.OrderDetails
+
+	Created string
+	Items   []itemRow
+
+	GoodsTotal   string
+	DeliveryCost string
+	CustomFee    string
+	GrandTotal   string
+}
+
+// itemRow строка таблицы товаров с отформатированными суммами.
+type itemRow struct {
+	Name  string
+	Brand string
+	Size  string
+	Track string
+	Price string
+	Sale  string
+	Total string
+}
+
+// Render выводит накладную заказа в HTML. Язык подписей и формат чисел
+// выбираются по locale заказа, число знаков после запятой — по валюте
+// оплаты (ISO 4217). Неизвестная локаль заменяется английской.
+func Render(w io.Writer, order *model.OrderDetails) error {
+	lang, text := localeFor(order.Locale)
+	money := newMoneyFormatter(lang, order.Payment.Currency)
+
+	data := page{
+		Lang:   lang.String(),
+		Labels: text,
+		Order:  order,
+		Items:  make([]itemRow, 0, len(order.Products)),
+	}
+	if created := time.Time(order.CreationTimestamp); !created.IsZero() {
+		data.Created = created.Format(text.DateLayout)
+	}
+
+	var goods model.Money
+	for _, item := range order.Products {
+		row := itemRow{
+			Name:  item.Name,
+			Brand: item.Brand,
+			Size:  item.Size,
+			Track: item.TrackingNum,
+			Price: money.format(item.Price),
+			Total: money.format(item.TotalPrice),
+		}
+		if item.Discount != 0 {
+			row.Sale = strconv.Itoa(item.Discount) + "%"
+		}
+		data.Items = append(data.Items, row)
+		goods += item.TotalPrice
+	}
+
+	data.GoodsTotal = money.format(goods)
+	data.DeliveryCost = money.format(order.Payment.DeliveryCost)
+	data.CustomFee = money.format(order.Payment.CustomFee)
+	data.GrandTotal = money.format(goods + order.Payment.DeliveryCost + order.Payment.CustomFee)
+
+	return invoiceTemplate.Execute(w, data)
+}
+
+// moneyFormatter форматирует суммы заказа в его валюте.
+type moneyFormatter struct {
+	printer *message.Printer
+	known   bool
+	scale   int
+	code    string
+	// symbol символ валюты в локали накладной.
+	symbol string
+	// separator десятичный разделитель локали.
+	separator string
+}
+
+func newMoneyFormatter(lang language.Tag, code string) moneyFormatter {
+	printer := message.NewPrinter(lang)
+	f := moneyFormatter{printer: printer, code: code}
+	if unit, err := currency.ParseISO(code); err == nil {
+		f.known = true
+		f.scale, _ = model.CurrencyExponent(code)
+		f.symbol = printer.Sprint(currency.Symbol(unit))
+		// Разделитель берется из образца: у x/text нет для него отдельного API
+		sample := printer.Sprint(number.Decimal(1.5, number.Scale(1)))
+		f.separator = strings.TrimSuffix(strings.TrimPrefix(sample, "1"), "5")
+	}
+	return f
+}
+
+// format выводит сумму, заданную в минимальных единицах, с символом валюты и
+// числом знаков после запятой по ISO 4217 (USD 1,817.00, JPY 1,817,
+// BHD 1,817.000). Точность CLDR, которую использует currency.Amount, для
+// части валют другая (IQD, AMD), поэтому сумма собирается из
+// Money.Decimal без округления и без перехода через float64: целая часть
+// группируется по правилам локали, дробная выводится целиком, с ведущими
+// нулями. Сумма в неизвестной валюте выводится в минимальных единицах.
+func (f moneyFormatter) format(amount model.Money) string {
+	if !f.known {
+		return f.printer.Sprint(number.Decimal(int64(amount))) + " " + f.code
+	}
+
+	whole, fraction, _ := strings.Cut(amount.Decimal(f.scale), ".")
+	sign := ""
+	if strings.HasPrefix(whole, "-") {
+		sign, whole = "-", whole[1:]
+	}
+	// Обе части не длиннее int64, поэтому разбор всегда успешен
+	units, _ := strconv.ParseInt(whole, 10, 64)
+	formatted := f.symbol + " " + sign + f.printer.Sprint(number.Decimal(units))
+	if fraction != "" {
+		minor, _ := strconv.ParseInt(fraction, 10, 64)
+		formatted += f.separator + f.printer.Sprint(number.Decimal(minor,
+			number.MinIntegerDigits(len(fraction)), number.NoSeparator()))
+	}
+	return formatted
+}
@@ -0,0 +1,38 @@
+package invoice
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"golang.org/x/text/language"
+)
+
+func TestMoneyFormatterUsesISOExponent(t *testing.T) {
+	tests := []struct {
+		locale   string
+		currency string
+		amount   model.Money
+		want     string
+	}{
+		{"en", "USD", 181700, "$ 1,817.00"},
+		{"en", "JPY", 123456, "¥ 123,456"},
+		{"en", "BHD", 123456, "BHD 123.456"},
+		{"en", "IQD", 123456, "IQD 123.456"},
+		{"en", "AMD", 123456, "AMD 1,234.56"},
+		{"en", "USD", 5, "$ 0.05"},
+		{"en", "USD", -181705, "$ -1,817.05"},
+		{"en", "USD", math.MaxInt64, "$ 92,233,720,368,547,758.07"},
+		{"ru", "RUB", 123456789, "₽ 1\u00a0234\u00a0567,89"},
+		{"en", "XXY", 123456, "123,456 XXY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.currency, func(t *testing.T) {
+			f := newMoneyFormatter(language.MustParse(tt.locale), tt.currency)
+			if got := f.format(tt.amount); got != tt.want {
+				t.Errorf("format(%d) = %q, want %q", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
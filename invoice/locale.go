@@ -0,0 +1,95 @@
+package invoice
+
+import "golang.org/x/text/language"
+
+// labels подписи накладной на одном языке.
+type labels struct {
+	Title        string
+	Order        string
+	Track        string
+	Created      string
+	Customer     string
+	Service      string
+	ShipTo       string
+	Phone        string
+	Email        string
+	Item         string
+	Brand        string
+	Size         string
+	Price        string
+	Sale         string
+	Total        string
+	GoodsTotal   string
+	DeliveryCost string
+	CustomFee    string
+	GrandTotal   string
+	DateLayout   string
+}
+
+// translations подписи по базовому языку.
+var translations = map[language.Base]labels{
+	language.MustParseBase("en"): {
+		Title:        "Invoice",
+		Order:        "Order",
+		Track:        "Track number",
+		Created:      "Date",
+		Customer:     "Customer",
+		Service:      "Delivery service",
+		ShipTo:       "Ship to",
+		Phone:        "Phone",
+		Email:        "Email",
+		Item:         "Item",
+		Brand:        "Brand",
+		Size:         "Size",
+		Price:        "Price",
+		Sale:         "Sale",
+		Total:        "Total",
+		GoodsTotal:   "Goods total",
+		DeliveryCost: "Delivery",
+		CustomFee:    "Custom fee",
+		GrandTotal:   "Grand total",
+		DateLayout:   "Jan 2, 2006 15:04",
+	},
+	language.MustParseBase("ru"): {
+		Title:        "Накладная",
+		Order:        "Заказ",
+		Track:        "Трек-номер",
+		Created:      "Дата",
+		Customer:     "Покупатель",
+		Service:      "Служба доставки",
+		ShipTo:       "Получатель",
+		Phone:        "Телефон",
+		Email:        "Почта",
+		Item:         "Товар",
+		Brand:        "Бренд",
+		Size:         "Размер",
+		Price:        "Цена",
+		Sale:         "Скидка",
+		Total:        "Сумма",
+		GoodsTotal:   "Товары",
+		DeliveryCost: "Доставка",
+		CustomFee:    "Пошлина",
+		GrandTotal:   "Итого",
+		DateLayout:   "02.01.2006 15:04",
+	},
+}
+
+// fallbackLanguage язык накладной для неизвестной или пустой локали.
+var fallbackLanguage = language.English
+
+// localeFor разбирает locale заказа ("en", "ru", "ru-RU") и возвращает тег
+// для форматирования чисел и подписи на ближайшем поддерживаемом языке.
+func localeFor(locale string) (language.Tag, labels) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return fallbackLanguage, translations[language.MustParseBase("en")]
+	}
+
+	base, _ := tag.Base()
+	text, ok := translations[base]
+	if !ok {
+		// Числа форматируются по локали заказа, подписи — на английском
+		return tag, translations[language.MustParseBase("en")]
+	}
+	return tag, text
+}
@@ -0,0 +1,323 @@
+// Package jsonschema строит JSON Schema (draft 2020-12) по Go-типам. Имена
+// свойств берутся из JSON-тегов, ограничения — из тега jsonschema:
+//
+//	Name  string `json:"name" jsonschema:"required"`
+//	Sale  int    `json:"sale" jsonschema:"minimum=0,maximum=100"`
+//
+// required делает поле обязательным и непустым (не только из пробелов),
+// minimum и maximum ограничивают число. Типы со своим представлением в JSON
+// описывают себя методом JSONSchema, структуры могут дополнить построенную
+// схему методом JSONSchemaExtend.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Draft идентификатор версии JSON Schema для поля $schema.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema схема JSON-значения. Поля перечислены в порядке вывода.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 Type               `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Properties           *Properties        `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+
+	// deny запрещает лишние свойства: additionalProperties: false.
+	deny bool
+}
+
+// MarshalJSON выводит additionalProperties: false для закрытых объектов.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	if !s.deny {
+		return json.Marshal((*plain)(s))
+	}
+	return json.Marshal(struct {
+		*plain
+		AdditionalProperties bool `json:"additionalProperties"`
+	}{plain: (*plain)(s)})
+}
+
+// Type тип JSON-значения: одно имя или список, например ["string", "null"].
+type Type []string
+
+// MarshalJSON выводит единственный тип строкой.
+func (t Type) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Nullable сообщает, что значение может быть null.
+func (t Type) Nullable() bool {
+	for _, name := range t {
+		if name == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// Properties свойства объекта в порядке полей структуры.
+type Properties struct {
+	names   []string
+	schemas map[string]*Schema
+}
+
+// Set добавляет свойство или заменяет существующее.
+func (p *Properties) Set(name string, schema *Schema) {
+	if p.schemas == nil {
+		p.schemas = make(map[string]*Schema)
+	}
+	if _, ok := p.schemas[name]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.schemas[name] = schema
+}
+
+// Get возвращает схему свойства или nil.
+func (p *Properties) Get(name string) *Schema {
+	return p.schemas[name]
+}
+
+// MarshalJSON выводит свойства в порядке добавления, чтобы сгенерированные
+// файлы совпадали с порядком полей в коде.
+func (p *Properties) MarshalJSON() ([]byte, error) {
+	var buf strings.Builder
+	buf.WriteByte('{')
+	for i, name := range p.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(p.schemas[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return []byte(buf.String()), nil
+}
+
+// Schemer тип со своим представлением в JSON.
+type Schemer interface {
+	JSONSchema() *Schema
+}
+
+// Extender структура, дополняющая построенную по полям схему.
+type Extender interface {
+	JSONSchemaExtend(schema *Schema)
+}
+
+// Ptr возвращает указатель на значение для числовых ограничений.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Reflector строит схемы по типам. Структуры из Names выносятся в Defs и
+// подставляются ссылкой RefPrefix+имя, остальные описываются на месте.
+type Reflector struct {
+	Names     map[reflect.Type]string
+	RefPrefix string
+	Defs      map[string]*Schema
+}
+
+// Reflect возвращает схему значения типа t.
+func (r *Reflector) Reflect(t reflect.Type) (*Schema, error) {
+	if name, ok := r.Names[t]; ok {
+		if _, done := r.Defs[name]; !done {
+			if r.Defs == nil {
+				r.Defs = make(map[string]*Schema)
+			}
+			// Заглушка на время построения допускает рекурсивные типы
+			r.Defs[name] = &Schema{}
+			schema, err := r.reflectType(t)
+			if err != nil {
+				return nil, err
+			}
+			r.Defs[name] = schema
+		}
+		return &Schema{Ref: r.RefPrefix + name}, nil
+	}
+	return r.reflectType(t)
+}
+
+var (
+	schemerType  = reflect.TypeOf((*Schemer)(nil)).Elem()
+	extenderType = reflect.TypeOf((*Extender)(nil)).Elem()
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+func (r *Reflector) reflectType(t reflect.Type) (*Schema, error) {
+	if t.Implements(schemerType) {
+		return reflect.Zero(t).Interface().(Schemer).JSONSchema(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema, err := r.Reflect(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullable(schema), nil
+	case reflect.String:
+		return &Schema{Type: Type{"string"}}, nil
+	case reflect.Bool:
+		return &Schema{Type: Type{"boolean"}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: Type{"integer"}}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Type{"integer"}, Minimum: Ptr(0.0)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Type{"number"}}, nil
+	case reflect.Interface:
+		// Любое значение
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := r.Reflect(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Type{"array"}, Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: map keys must be strings", t)
+		}
+		values, err := r.Reflect(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Type{"object"}, AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: Type{"string"}, Format: "date-time"}, nil
+		}
+		return r.reflectStruct(t)
+	}
+	return nil, fmt.Errorf("%s: unsupported type", t)
+}
+
+func (r *Reflector) reflectStruct(t reflect.Type) (*Schema, error) {
+	schema := &Schema{Type: Type{"object"}, Properties: &Properties{}, deny: true}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := r.Reflect(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		required, err := applyTag(property, field.Tag.Get("jsonschema"))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		if required && strings.Contains(options, "omitempty") {
+			return nil, fmt.Errorf("%s.%s: required field must not be omitempty", t.Name(), field.Name)
+		}
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties.Set(name, property)
+	}
+
+	if t.Implements(extenderType) {
+		reflect.Zero(t).Interface().(Extender).JSONSchemaExtend(schema)
+	}
+	return schema, nil
+}
+
+// applyTag применяет ограничения из тега jsonschema и сообщает, обязательно
+// ли поле.
+func applyTag(schema *Schema, tag string) (bool, error) {
+	if tag == "" {
+		return false, nil
+	}
+	required := false
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "required":
+			required = true
+			switch {
+			case schema.Ref != "":
+			case len(schema.Type) == 1 && schema.Type[0] == "string" && schema.Enum == nil && schema.Format == "":
+				schema.MinLength = Ptr(1)
+				schema.Pattern = `\S`
+			default:
+				schema.Type = nonNull(schema.Type)
+			}
+		case "minimum", "maximum":
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid jsonschema %s %q", key, value)
+			}
+			if key == "minimum" {
+				schema.Minimum = &bound
+			} else {
+				schema.Maximum = &bound
+			}
+		default:
+			return false, fmt.Errorf("unknown jsonschema option %q", key)
+		}
+	}
+	return required, nil
+}
+
+// nullable разрешает null в дополнение к типу схемы.
+func nullable(schema *Schema) *Schema {
+	switch {
+	case schema.Ref != "":
+		return &Schema{AnyOf: []*Schema{schema, {Type: Type{"null"}}}}
+	case schema.Type.Nullable():
+		return schema
+	}
+	schema.Type = append(schema.Type, "null")
+	return schema
+}
+
+// nonNull убирает null из списка типов.
+func nonNull(t Type) Type {
+	out := make(Type, 0, len(t))
+	for _, name := range t {
+		if name != "null" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	// avroMagicByte первый байт сообщения в формате Schema Registry.
+	avroMagicByte = 0
+	// avroHeaderSize магический байт и идентификатор схемы.
+	avroHeaderSize = 5
+)
+
+// avroCodec реализует Codec для Avro в формате Schema Registry. Сообщение
+// читается по схеме, с которой оно записано; схемы загружаются из реестра
+// один раз.
+//
+// Версия схемы заказа (model.SchemaVersion) передается полем записи
+// schema_version; записи схем без этого поля считаются версией 1.
+type avroCodec struct {
+	registry SchemaRegistry
+	// schemaID схема для записи, 0 — запись отключена.
+	schemaID int
+
+	mu      sync.Mutex
+	schemas map[int]*avroSchema
+}
+
+// avroSchema загруженная схема Avro.
+type avroSchema struct {
+	codec *goavro.Codec
+	// versioned схема содержит поле schema_version
+	versioned bool
+}
+
+// NewAvroCodec создает Codec для Avro. schemaID — идентификатор схемы в
+// реестре, с которой записываются заказы; 0, если сервис только читает Avro.
+func NewAvroCodec(registry SchemaRegistry, schemaID int) Codec {
+	return &avroCodec{
+		registry: registry,
+		schemaID: schemaID,
+		schemas:  make(map[int]*avroSchema),
+	}
+}
+
+func (c *avroCodec) ContentType() string {
+	return ContentTypeAvro
+}
+
+func (c *avroCodec) Encode(ctx context.Context, order *model.OrderDetails) ([]byte, error) {
+	if c.schemaID <= 0 {
+		return nil, errors.New("avro schema ID for writing is not configured")
+	}
+	schema, err := c.schema(ctx, c.schemaID)
+	if err != nil {
+		return nil, err
+	}
+	if !schema.versioned {
+		return nil, fmt.Errorf("avro schema %d has no %s field and cannot be used for writing", c.schemaID, model.SchemaVersionField)
+	}
+
+	buf := make([]byte, avroHeaderSize, 512)
+	buf[0] = avroMagicByte
+	binary.BigEndian.PutUint32(buf[1:avroHeaderSize], uint32(c.schemaID))
+	return schema.codec.BinaryFromNative(buf, avroRecord(order))
+}
+
+func (c *avroCodec) Decode(ctx context.Context, data []byte) (*model.OrderDetails, error) {
+	if len(data) < avroHeaderSize || data[0] != avroMagicByte {
+		return nil, errors.New("invalid avro message: missing schema registry header")
+	}
+	schemaID := int(binary.BigEndian.Uint32(data[1:avroHeaderSize]))
+
+	schema, err := c.schema(ctx, schemaID)
+	if err != nil {
+		return nil, err
+	}
+	native, rest, err := schema.codec.NativeFromBinary(data[avroHeaderSize:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro message for schema %d: %w", schemaID, err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("invalid avro message for schema %d: %d trailing bytes", schemaID, len(rest))
+	}
+
+	// Запись приводится к JSON, чтобы проверка структуры (неизвестные поля,
+	// число товаров) совпадала с JSON-сообщениями
+	data, err = json.Marshal(native)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro message for schema %d: %w", schemaID, err)
+	}
+	return model.ParseOrder(data, model.MaxItems)
+}
+
+// schema возвращает схему, загружая ее из реестра при первом обращении.
+func (c *avroCodec) schema(ctx context.Context, schemaID int) (*avroSchema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if schema, ok := c.schemas[schemaID]; ok {
+		return schema, nil
+	}
+
+	text, err := c.registry.Schema(ctx, schemaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load avro schema %d: %w", schemaID, err)
+	}
+	codec, err := goavro.NewCodec(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema %d: %w", schemaID, err)
+	}
+
+	var record struct {
+		Fields []struct {
+			Name string `json:"name"`
+		} `json:"fields"`
+	}
+	// Схема уже проверена goavro, поэтому ошибка разбора здесь невозможна
+	_ = json.Unmarshal([]byte(text), &record)
+
+	schema := &avroSchema{codec: codec}
+	for _, field := range record.Fields {
+		if field.Name == model.SchemaVersionField {
+			schema.versioned = true
+		}
+	}
+	c.schemas[schemaID] = schema
+	return schema, nil
+}
+
+// avroRecord представляет заказ в виде, который принимает goavro.
+func avroRecord(order *model.OrderDetails) map[string]any {
+	items := make([]any, 0, len(order.Products))
+	for _, item := range order.Products {
+		items = append(items, map[string]any{
+			"chrt_id":      int64(item.ChartID),
+			"track_number": item.TrackingNum,
+			"price":        int64(item.Price),
+			"rid":          item.RID,
+			"name":         item.Name,
+			"sale":         int32(item.Discount),
+			"size":         item.Size,
+			"total_price":  int64(item.TotalPrice),
+			"nm_id":        int64(item.ProductID),
+			"brand":        item.Brand,
+			"status":       int32(item.Status),
+		})
+	}
+
+	return map[string]any{
+		"schema_version": int32(model.SchemaVersion),
+		"order_uid":      order.OrderID,
+		"track_number":   order.TrackingNumber,
+		"entry":          order.EntryPoint,
+		"delivery": map[string]any{
+			"name":    order.Address.FullName,
+			"phone":   order.Address.Phone,
+			"zip":     order.Address.ZipCode,
+			"city":    order.Address.City,
+			"address": order.Address.Street,
+			"region":  order.Address.Region,
+			"email":   order.Address.Email,
+		},
+		"payment": map[string]any{
+			"transaction":   order.Payment.TransactionID,
+			"request_id":    order.Payment.RequestID,
+			"currency":      order.Payment.Currency,
+			"provider":      order.Payment.Provider,
+			"amount":        int64(order.Payment.Amount),
+			"payment_dt":    order.Payment.PaymentDate.Unix(),
+			"bank":          order.Payment.Bank,
+			"delivery_cost": int64(order.Payment.DeliveryCost),
+			"goods_total":   int64(order.Payment.TotalGoods),
+			"custom_fee":    int64(order.Payment.CustomFee),
+		},
+		"items":              items,
+		"locale":             order.Locale,
+		"internal_signature": order.Signature,
+		"customer_id":        order.CustomerID,
+		"delivery_service":   order.DeliveryService,
+		"shardkey":           order.ShardKey,
+		"sm_id":              int64(order.SMID),
+		"date_created":       time.Time(order.CreationTimestamp),
+		"oof_shard":          order.OutOfShard,
+	}
+}
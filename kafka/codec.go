@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	ordersv1 "github.com/Sh1ni-Gami/WB_Tech_L0/proto/orders/v1"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// contentTypeHeader заголовок сообщения Kafka с форматом заказа.
+const contentTypeHeader = "content-type"
+
+// Форматы сообщений с заказами.
+const (
+	// ContentTypeJSON заказ в JSON с версией схемы в поле schema_version;
+	// используется и для сообщений без заголовка.
+	ContentTypeJSON = "application/json"
+	// ContentTypeProtobuf сообщение orders.v1.Order.
+	ContentTypeProtobuf = "application/x-protobuf"
+	// ContentTypeAvro запись Avro в формате Schema Registry: нулевой байт,
+	// идентификатор схемы (4 байта, big-endian) и данные.
+	ContentTypeAvro = "application/vnd.apache.avro+binary"
+)
+
+// Codec формат сообщений с заказами в Kafka. Decode проверяет структуру
+// сообщения; правила заказа проверяет kafkaService.
+type Codec interface {
+	ContentType() string
+	Encode(ctx context.Context, order *model.OrderDetails) ([]byte, error)
+	Decode(ctx context.Context, data []byte) (*model.OrderDetails, error)
+}
+
+// codecFor выбирает формат по заголовку content-type сообщения.
+func (k *kafkaService) codecFor(headers []kafka.Header) (Codec, error) {
+	contentType := ContentTypeJSON
+	for _, header := range headers {
+		if header.Key == contentTypeHeader {
+			mediaType, _, err := mime.ParseMediaType(string(header.Value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid content-type %q: %w", header.Value, err)
+			}
+			contentType = mediaType
+		}
+	}
+
+	codec, ok := k.codecs[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content-type %q", contentType)
+	}
+	return codec, nil
+}
+
+// jsonCodec реализует Codec для JSON.
+type jsonCodec struct{}
+
+// NewJSONCodec создает Codec для JSON.
+func NewJSONCodec() Codec {
+	return jsonCodec{}
+}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Encode(_ context.Context, order *model.OrderDetails) ([]byte, error) {
+	return model.MarshalOrder(order)
+}
+
+func (jsonCodec) Decode(_ context.Context, data []byte) (*model.OrderDetails, error) {
+	return model.ParseOrder(data, model.MaxItems)
+}
+
+// protobufCodec реализует Codec для orders.v1.Order.
+type protobufCodec struct{}
+
+// NewProtobufCodec создает Codec для Protobuf.
+func NewProtobufCodec() Codec {
+	return protobufCodec{}
+}
+
+func (protobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufCodec) Encode(_ context.Context, order *model.OrderDetails) ([]byte, error) {
+	return proto.Marshal(ordersv1.FromModel(order))
+}
+
+func (protobufCodec) Decode(_ context.Context, data []byte) (*model.OrderDetails, error) {
+	var message ordersv1.Order
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("invalid protobuf message: %w", err)
+	}
+	if len(message.GetItems()) > model.MaxItems {
+		return nil, errors.New("too many products in the order")
+	}
+	return message.ToModel()
+}
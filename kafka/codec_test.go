@@ -0,0 +1,226 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	ordersv1 "github.com/Sh1ni-Gami/WB_Tech_L0/proto/orders/v1"
+	"github.com/linkedin/goavro/v2"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// schemasDir локальный реестр схем Avro, который используется и при разработке.
+const schemasDir = "schemas"
+
+func testOrder(t *testing.T) *model.OrderDetails {
+	t.Helper()
+	data, err := os.ReadFile("../scripts/order.json")
+	if err != nil {
+		t.Fatalf("read order: %v", err)
+	}
+	order, err := model.ParseOrder(data, model.MaxItems)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+	return order
+}
+
+// testService сервис только с форматами сообщений, без брокера и хранилища.
+func testService(codecs ...Codec) *kafkaService {
+	k := &kafkaService{codecs: map[string]Codec{ContentTypeJSON: NewJSONCodec()}}
+	WithCodecs(codecs...)(k)
+	return k
+}
+
+func TestCodecRoundTripByContentType(t *testing.T) {
+	avro := NewAvroCodec(NewFileRegistry(schemasDir), 2)
+	k := testService(NewProtobufCodec(), avro)
+	order := testOrder(t)
+
+	tests := []struct {
+		name        string
+		encoder     Codec
+		contentType string
+	}{
+		{"json without header", NewJSONCodec(), ""},
+		{"json", NewJSONCodec(), "application/json; charset=utf-8"},
+		{"protobuf", NewProtobufCodec(), ContentTypeProtobuf},
+		{"avro", avro, ContentTypeAvro},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.encoder.Encode(context.Background(), order)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			var headers []kafka.Header
+			if tt.contentType != "" {
+				headers = append(headers, kafka.Header{Key: contentTypeHeader, Value: []byte(tt.contentType)})
+			}
+			codec, err := k.codecFor(headers)
+			if err != nil {
+				t.Fatalf("codecFor: %v", err)
+			}
+			if codec.ContentType() != tt.encoder.ContentType() {
+				t.Fatalf("codecFor picked %s, want %s", codec.ContentType(), tt.encoder.ContentType())
+			}
+
+			decoded, err := codec.Decode(context.Background(), data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if diff := model.Diff(order, decoded); len(diff) > 0 {
+				t.Errorf("decoded order differs: %+v", diff)
+			}
+		})
+	}
+}
+
+func TestCodecForRejectsUnknownContentType(t *testing.T) {
+	k := testService()
+	for _, contentType := range []string{ContentTypeAvro, "text/plain", "application/json; charset"} {
+		_, err := k.codecFor([]kafka.Header{{Key: contentTypeHeader, Value: []byte(contentType)}})
+		if err == nil {
+			t.Errorf("codecFor(%q) accepted the message", contentType)
+		}
+	}
+}
+
+func TestAvroFraming(t *testing.T) {
+	codec := NewAvroCodec(NewFileRegistry(schemasDir), 2)
+	data, err := codec.Encode(context.Background(), testOrder(t))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if data[0] != avroMagicByte {
+		t.Errorf("magic byte = %d, want %d", data[0], avroMagicByte)
+	}
+	if id := binary.BigEndian.Uint32(data[1:avroHeaderSize]); id != 2 {
+		t.Errorf("schema ID = %d, want 2", id)
+	}
+
+	withSchemaID := func(id uint32) []byte {
+		framed := append([]byte(nil), data...)
+		binary.BigEndian.PutUint32(framed[1:avroHeaderSize], id)
+		return framed
+	}
+	badMagic := append([]byte{1}, data[1:]...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+		wantMsg string
+	}{
+		{"unknown schema ID", withSchemaID(99), ErrSchemaNotFound, "schema 99"},
+		{"bad magic byte", badMagic, nil, "missing schema registry header"},
+		{"truncated header", data[:3], nil, "missing schema registry header"},
+		{"trailing bytes", append(append([]byte(nil), data...), 0), nil, "trailing bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := codec.Decode(context.Background(), tt.data)
+			if err == nil {
+				t.Fatal("Decode accepted the message")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestAvroDecodesVersion1Schema(t *testing.T) {
+	order := testOrder(t)
+	text, err := NewFileRegistry(schemasDir).Schema(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Schema: %v", err)
+	}
+	writer, err := goavro.NewCodec(text)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+
+	// Запись схемы 1: без schema_version, суммы в основных единицах
+	record := avroRecord(order)
+	delete(record, model.SchemaVersionField)
+	payment := record["payment"].(map[string]any)
+	for _, field := range []string{"amount", "delivery_cost", "goods_total", "custom_fee"} {
+		payment[field] = payment[field].(int64) / 100
+	}
+	for _, item := range record["items"].([]any) {
+		item := item.(map[string]any)
+		item["price"], item["total_price"] = item["price"].(int64)/100, item["total_price"].(int64)/100
+	}
+
+	header := []byte{avroMagicByte, 0, 0, 0, 1}
+	data, err := writer.BinaryFromNative(header, record)
+	if err != nil {
+		t.Fatalf("BinaryFromNative: %v", err)
+	}
+
+	codec := NewAvroCodec(NewFileRegistry(schemasDir), 1)
+	decoded, err := codec.Decode(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if diff := model.Diff(order, decoded); len(diff) > 0 {
+		t.Errorf("upcast order differs: %+v", diff)
+	}
+	if decoded.SchemaVersion != 1 {
+		t.Errorf("SchemaVersion = %d, want 1", decoded.SchemaVersion)
+	}
+
+	// Схема без schema_version не подходит для записи
+	if _, err := codec.Encode(context.Background(), order); err == nil {
+		t.Error("Encode with schema 1 succeeded")
+	}
+}
+
+func TestProtobufUpcastsVersion1Message(t *testing.T) {
+	order := testOrder(t)
+
+	// Сообщение, записанное до появления schema_version: суммы в основных единицах
+	message := ordersv1.FromModel(order)
+	message.SchemaVersion = 0
+	payment := message.GetPayment()
+	payment.Amount, payment.DeliveryCost = payment.Amount/100, payment.DeliveryCost/100
+	payment.GoodsTotal, payment.CustomFee = payment.GoodsTotal/100, payment.CustomFee/100
+	for _, item := range message.GetItems() {
+		item.Price, item.TotalPrice = item.Price/100, item.TotalPrice/100
+	}
+	data, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	decoded, err := NewProtobufCodec().Decode(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if diff := model.Diff(order, decoded); len(diff) > 0 {
+		t.Errorf("upcast order differs: %+v", diff)
+	}
+	if decoded.SchemaVersion != 1 {
+		t.Errorf("SchemaVersion = %d, want 1", decoded.SchemaVersion)
+	}
+
+	message.SchemaVersion = model.SchemaVersion + 1
+	if data, err = proto.Marshal(message); err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if _, err := NewProtobufCodec().Decode(context.Background(), data); !errors.Is(err, model.ErrUnsupportedSchemaVersion) {
+		t.Errorf("future version: error = %v, want ErrUnsupportedSchemaVersion", err)
+	}
+}
@@ -2,40 +2,166 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
-	"os"
 	"strconv"
 	"time"
 
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
 	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
 	"github.com/segmentio/kafka-go"
 )
 
 // Store интерфейс для взаимодействия с хранилищем.
 type Store interface {
-	AddOrder(order *model.OrderDetails) error
-	GetOrder(orderUID string) (*model.OrderDetails, error)
+	AddOrder(ctx context.Context, order *model.OrderDetails) error
+	GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error)
 }
 
+// Signer подписывает отправляемые заказы (реализуется signing.Keyset).
+type Signer interface {
+	Sign(order *model.OrderDetails) (string, error)
+}
+
+// Verifier проверяет internal_signature принятых заказов (реализуется signing.Keyset).
+type Verifier interface {
+	Verify(order *model.OrderDetails) error
+}
+
+// MessageWriter публикует сообщения; по умолчанию это kafka.Writer топика
+// заказов.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// MessageReader читает сообщения топика заказов; по умолчанию это
+// kafka.Reader раздела сервиса.
+type MessageReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+// KeyFunc выбирает ключ сообщения для заказа. Сообщения с одинаковым ключом
+// попадают в один раздел топика.
+type KeyFunc func(order *model.OrderDetails) []byte
+
+// quarantineReasonHeader заголовок сообщения в карантинном топике с причиной.
+const quarantineReasonHeader = "quarantine-reason"
+
 // KafkaService интерфейс для работы с Kafka.
 type KafkaService interface {
 	StartListening(ctx context.Context)
 	SendOrder(ctx context.Context, order *model.OrderDetails) error
+	Offsets(ctx context.Context) (first, last int64, err error)
+	ScanOrders(ctx context.Context, from, to int64, fn ScanFunc) error
 }
 
+// ScanFunc получает заказ из сообщения с данным смещением либо ошибку его
+// декодирования, проверки или подписи. Ошибка, возвращенная ScanFunc,
+// прерывает чтение.
+type ScanFunc func(offset int64, order *model.OrderDetails, err error) error
+
 type kafkaService struct {
-	reader    *kafka.Reader
-	writer    *kafka.Writer
+	reader    MessageReader
+	writer    MessageWriter
 	store     Store
 	logger    *slog.Logger
 	topic     string
 	partition int
+	brokerURL string
+
+	// codecs форматы, принимаемые по заголовку content-type
+	codecs map[string]Codec
+	// producer формат отправляемых заказов
+	producer Codec
+	// key ключ отправляемых сообщений; nil — сообщения без ключа
+	key KeyFunc
+	// headers дополнительные заголовки отправляемых сообщений
+	headers []kafka.Header
+
+	signer   Signer
+	verifier Verifier
+	// quarantine писатель карантинного топика; nil — заказы с неверной
+	// подписью только отклоняются
+	quarantine      *kafka.Writer
+	quarantineTopic string
+}
+
+// Option настраивает KafkaService.
+type Option func(*kafkaService)
+
+// WithCodecs добавляет форматы принимаемых сообщений. JSON принимается всегда.
+func WithCodecs(codecs ...Codec) Option {
+	return func(k *kafkaService) {
+		for _, codec := range codecs {
+			k.codecs[codec.ContentType()] = codec
+		}
+	}
+}
+
+// WithProducerCodec задает формат отправляемых заказов; по умолчанию JSON.
+// Формат также принимается при чтении.
+func WithProducerCodec(codec Codec) Option {
+	return func(k *kafkaService) {
+		k.producer = codec
+		k.codecs[codec.ContentType()] = codec
+	}
+}
+
+// WithSigner подписывает отправляемые заказы: internal_signature заменяется
+// подписью signer.
+func WithSigner(signer Signer) Option {
+	return func(k *kafkaService) {
+		k.signer = signer
+	}
+}
+
+// WithKey задает ключ отправляемых сообщений. Разделы топика выбираются по
+// хешу ключа.
+func WithKey(key KeyFunc) Option {
+	return func(k *kafkaService) {
+		k.key = key
+	}
+}
+
+// WithHeader добавляет заголовок к отправляемым сообщениям. Заголовок
+// content-type задается форматом отправки и не переопределяется.
+func WithHeader(key, value string) Option {
+	return func(k *kafkaService) {
+		k.headers = append(k.headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+}
+
+// WithWriter заменяет писателя отправляемых сообщений, например для пробного
+// запуска без брокера.
+func WithWriter(writer MessageWriter) Option {
+	return func(k *kafkaService) {
+		k.writer = writer
+	}
+}
+
+// WithReader заменяет источник принимаемых сообщений, например топиком
+// в памяти для нагрузочных проверок без брокера.
+func WithReader(reader MessageReader) Option {
+	return func(k *kafkaService) {
+		k.reader = reader
+	}
+}
+
+// WithVerifier включает проверку internal_signature принятых заказов. Заказы
+// без подписи или с неверной подписью не сохраняются; если quarantineTopic не
+// пуст, исходное сообщение публикуется в этот топик с заголовком
+// quarantine-reason.
+func WithVerifier(verifier Verifier, quarantineTopic string) Option {
+	return func(k *kafkaService) {
+		k.verifier = verifier
+		k.quarantineTopic = quarantineTopic
+	}
 }
 
 // NewKafkaService создает новый экземпляр KafkaService.
-func NewKafkaService(topic, brokerURL string, partition string, logger *slog.Logger, store Store) (KafkaService, error) {
+func NewKafkaService(topic, brokerURL string, partition string, logger *slog.Logger, store Store, opts ...Option) (KafkaService, error) {
 	part, err := strconv.Atoi(partition)
 	if err != nil {
 		return nil, errors.New("invalid partition: must be an integer")
@@ -54,14 +180,33 @@ func NewKafkaService(topic, brokerURL string, partition string, logger *slog.Log
 		BatchTimeout: 10 * time.Millisecond,
 	}
 
-	return &kafkaService{
+	jsonCodec := NewJSONCodec()
+	k := &kafkaService{
 		reader:    reader,
 		writer:    writer,
 		store:     store,
 		logger:    logger,
 		topic:     topic,
 		partition: part,
-	}, nil
+		brokerURL: brokerURL,
+		codecs:    map[string]Codec{jsonCodec.ContentType(): jsonCodec},
+		producer:  jsonCodec,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if k.key != nil && k.writer == writer {
+		writer.Balancer = &kafka.Hash{}
+	}
+	if k.quarantineTopic != "" {
+		k.quarantine = &kafka.Writer{
+			Addr:         kafka.TCP(brokerURL),
+			Topic:        k.quarantineTopic,
+			RequiredAcks: kafka.RequireOne,
+			BatchTimeout: 10 * time.Millisecond,
+		}
+	}
+	return k, nil
 }
 
 // StartListening начинает прослушивание Kafka и обработку сообщений.
@@ -74,6 +219,11 @@ func (k *kafkaService) StartListening(ctx context.Context) {
 				if err := k.reader.Close(); err != nil {
 					k.logger.Error("Error closing Kafka reader", slog.Any("error", err))
 				}
+				if k.quarantine != nil {
+					if err := k.quarantine.Close(); err != nil {
+						k.logger.Error("Error closing Kafka quarantine writer", slog.Any("error", err))
+					}
+				}
 				return
 			default:
 				msg, err := k.reader.ReadMessage(ctx)
@@ -82,20 +232,43 @@ func (k *kafkaService) StartListening(ctx context.Context) {
 					continue
 				}
 
-				k.logger.Debug("Message received from Kafka", slog.String("topic", msg.Topic), slog.Int("partition", msg.Partition))
+				// Записи всех слоев, обработавших сообщение, содержат его координаты
+				logger := k.logger.With(slog.Int("partition", msg.Partition), slog.Int64("offset", msg.Offset))
+				msgCtx := logging.WithLogger(ctx, logger)
+
+				logger.Debug("Message received from Kafka", slog.String("topic", msg.Topic))
 
-				order, err := k.decodeOrder(msg.Value)
+				order, err := k.decodeOrder(msgCtx, msg.Headers, msg.Value)
 				if err != nil {
-					k.logger.Error("Failed to decode order message", slog.Any("error", err))
+					if errors.Is(err, model.ErrUnsupportedSchemaVersion) {
+						logger.Warn("Order message rejected: unsupported schema version", slog.Any("error", err))
+						continue
+					}
+					logger.Error("Failed to decode order message", slog.Any("error", err))
 					continue
 				}
 
-				if err := k.store.AddOrder(order); err != nil {
-					k.logger.Error("Failed to save order to store", slog.Any("error", err))
+				if k.verifier != nil {
+					if err := k.verifier.Verify(order); err != nil {
+						k.quarantineMessage(msgCtx, msg, order.OrderID, err)
+						continue
+					}
+				}
+
+				if err := k.store.AddOrder(msgCtx, order); err != nil {
+					if errors.Is(err, model.ErrDuplicateOrder) {
+						logger.Info("Duplicate order skipped", slog.String("orderID", order.OrderID))
+						continue
+					}
+					if errors.Is(err, model.ErrConflictingData) {
+						logger.Warn("Order rejected: conflicts with stored data", slog.String("orderID", order.OrderID), slog.Any("error", err))
+						continue
+					}
+					logger.Error("Failed to save order to store", slog.Any("error", err))
 					continue
 				}
 
-				k.logger.Info("Order processed successfully", slog.String("orderID", order.OrderID))
+				logger.Info("Order processed successfully", slog.String("orderID", order.OrderID))
 			}
 		}
 	}()
@@ -103,15 +276,29 @@ func (k *kafkaService) StartListening(ctx context.Context) {
 
 // SendOrder отправляет заказ в Kafka.
 func (k *kafkaService) SendOrder(ctx context.Context, order *model.OrderDetails) error {
-	orderBytes, err := json.Marshal(order)
-	if err != nil {
-		return errors.New("failed to serialize order to JSON")
+	if k.signer != nil {
+		signature, err := k.signer.Sign(order)
+		if err != nil {
+			return fmt.Errorf("failed to sign order: %w", err)
+		}
+		signed := *order
+		signed.Signature = signature
+		order = &signed
 	}
 
-	err = k.writer.WriteMessages(ctx, kafka.Message{
-		Value: orderBytes,
-	})
+	orderBytes, err := k.producer.Encode(ctx, order)
 	if err != nil {
+		return fmt.Errorf("failed to serialize order to %s: %w", k.producer.ContentType(), err)
+	}
+
+	msg := kafka.Message{
+		Value:   orderBytes,
+		Headers: append([]kafka.Header{{Key: contentTypeHeader, Value: []byte(k.producer.ContentType())}}, k.headers...),
+	}
+	if k.key != nil {
+		msg.Key = k.key(order)
+	}
+	if err := k.writer.WriteMessages(ctx, msg); err != nil {
 		k.logger.Error("Failed to send order to Kafka", slog.Any("error", err))
 		return err
 	}
@@ -120,19 +307,102 @@ func (k *kafkaService) SendOrder(ctx context.Context, order *model.OrderDetails)
 	return nil
 }
 
-// decodeOrder декодирует сообщение Kafka в структуру OrderDetails.
-func (k *kafkaService) decodeOrder(data []byte) (*model.OrderDetails, error) {
-	var order model.OrderDetails
-	if err := json.Unmarshal(data, &order); err != nil {
-		return nil, errors.New("invalid order format in Kafka message")
+// Offsets возвращает смещение первого хранимого сообщения раздела и смещение,
+// которое получит следующее сообщение.
+func (k *kafkaService) Offsets(ctx context.Context) (int64, int64, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", k.brokerURL, k.topic, k.partition)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	first, err := conn.ReadFirstOffset()
+	if err != nil {
+		return 0, 0, err
+	}
+	last, err := conn.ReadLastOffset()
+	if err != nil {
+		return 0, 0, err
+	}
+	return first, last, nil
+}
+
+// ScanOrders читает сообщения раздела со смещениями из [from, to) отдельным
+// читателем, не сдвигая позицию слушателя, и передает заказы в fn. Заказы
+// проверяются так же, как при приеме, но не сохраняются.
+func (k *kafkaService) ScanOrders(ctx context.Context, from, to int64, fn ScanFunc) error {
+	if from >= to {
+		return nil
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Topic:     k.topic,
+		Partition: k.partition,
+		Brokers:   []string{k.brokerURL},
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(from); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		if msg.Offset >= to {
+			return nil
+		}
+
+		order, err := k.decodeOrder(ctx, msg.Headers, msg.Value)
+		if err == nil && k.verifier != nil {
+			if err = k.verifier.Verify(order); err != nil {
+				err = fmt.Errorf("signature verification failed: %w", err)
+			}
+		}
+		if err != nil {
+			order = nil
+		}
+		if err := fn(msg.Offset, order, err); err != nil {
+			return err
+		}
+		if msg.Offset+1 >= to {
+			return nil
+		}
+	}
+}
+
+// quarantineMessage отклоняет заказ, не прошедший проверку подписи, и
+// публикует исходное сообщение в карантинный топик, если он задан.
+func (k *kafkaService) quarantineMessage(ctx context.Context, msg kafka.Message, orderID string, reason error) {
+	logger := logging.FromContext(ctx, k.logger).With(slog.String("orderID", orderID), slog.Any("error", reason))
+	if k.quarantine == nil {
+		logger.Warn("Order rejected: signature verification failed")
+		return
 	}
-	return &order, nil
+
+	headers := append(msg.Headers[:len(msg.Headers):len(msg.Headers)],
+		kafka.Header{Key: quarantineReasonHeader, Value: []byte(reason.Error())})
+	err := k.quarantine.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	if err != nil {
+		logger.Error("Failed to quarantine order with invalid signature", slog.Any("quarantineError", err))
+		return
+	}
+	logger.Warn("Order quarantined: signature verification failed", slog.String("quarantineTopic", k.quarantineTopic))
 }
 
-// Utility function: getEnv возвращает значение переменной окружения или значение по умолчанию.
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// decodeOrder декодирует сообщение Kafka в формате из заголовка content-type
+// и проверяет его по тем же правилам, что и HTTP-прием заказов.
+func (k *kafkaService) decodeOrder(ctx context.Context, headers []kafka.Header, data []byte) (*model.OrderDetails, error) {
+	codec, err := k.codecFor(headers)
+	if err != nil {
+		return nil, err
+	}
+	order, err := codec.Decode(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := order.Validate(); err != nil {
+		return nil, err
 	}
-	return fallback
+	return order, nil
 }
@@ -0,0 +1,539 @@
+// Нагрузочная проверка сервиса: заказы из генератора fake отправляются в
+// Kafka с заданной скоростью, одновременно читатели запрашивают
+// GET /api/v1/order с долей популярных (hot), редких (cold) и несуществующих
+// (unknown) order_uid. Для части заказов измеряется время от отправки до
+// появления в API. Итог — процентили задержек, доли ошибок и попаданий в кэш.
+//
+//	go run ./loadtest -mem -duration 20s -rate 200 -readers 16
+//	go run ./loadtest -broker localhost:9094 -url http://localhost:8080 -token $ADMIN_TOKEN
+//
+// С -mem сервис собирается в процессе из настоящих кэша, слушателя Kafka
+// и HTTP-транспорта поверх топика и базы в памяти, без Docker.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/fake"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/kafka"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/signing"
+	"github.com/joho/godotenv"
+)
+
+// logOutput журнал проверки и сервиса в памяти; stdout занят отчетом.
+var logOutput io.Writer = os.Stderr
+
+// Виды запросов чтения.
+const (
+	readHot     = "hot"
+	readCold    = "cold"
+	readUnknown = "unknown"
+)
+
+// options параметры проверки.
+type options struct {
+	duration    time.Duration
+	rate        float64
+	concurrency int
+	preload     int
+	probe       float64
+	e2eTimeout  time.Duration
+	poll        time.Duration
+	readers     int
+	readRate    float64
+	mix         map[string]int
+	hotSet      int
+	seed        int64
+	jsonReport  bool
+
+	// Настоящий сервис
+	broker string
+	topic  string
+	url    string
+	token  string
+	keyset string
+
+	// Сервис в памяти
+	mem       bool
+	cacheSize int
+	dbLatency time.Duration
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run проводит проверку и возвращает код завершения.
+func run(args []string) int {
+	_ = godotenv.Load()
+	cfg, err := config.NewLoader(config.WithHostDefaults()).Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	opts := options{mix: map[string]int{readHot: 70, readCold: 25, readUnknown: 5}}
+	flags.DurationVar(&opts.duration, "duration", 30*time.Second, "measurement duration")
+	flags.Float64Var(&opts.rate, "rate", 100, "orders produced per second")
+	flags.IntVar(&opts.concurrency, "concurrency", 8, "concurrent producers")
+	flags.IntVar(&opts.preload, "preload", 1000, "orders produced and awaited before measurement; they form the hot and cold sets")
+	flags.Float64Var(&opts.probe, "probe", 0.2, "share of produced orders whose produce-to-queryable latency is measured")
+	flags.DurationVar(&opts.e2eTimeout, "e2e-timeout", 10*time.Second, "how long to wait for an order to become queryable")
+	flags.DurationVar(&opts.poll, "poll", 5*time.Millisecond, "interval between queryability checks")
+	flags.IntVar(&opts.readers, "readers", 16, "concurrent readers of GET /api/v1/order")
+	flags.Float64Var(&opts.readRate, "read-rate", 0, "total reads per second, 0 for as fast as the readers can")
+	flags.Func("mix", "read mix `hot=70,cold=25,unknown=5`", func(value string) error {
+		mix, err := parseMix(value)
+		if err == nil {
+			opts.mix = mix
+		}
+		return err
+	})
+	flags.IntVar(&opts.hotSet, "hot-set", 100, "number of popular orders")
+	flags.Int64Var(&opts.seed, "seed", time.Now().UnixNano(), "order generator seed")
+	flags.BoolVar(&opts.jsonReport, "json", false, "print the report as JSON")
+	flags.StringVar(&opts.broker, "broker", cfg.Kafka.URL, "Kafka broker address")
+	flags.StringVar(&opts.topic, "topic", cfg.Kafka.Topic, "Kafka topic")
+	flags.StringVar(&opts.url, "url", "http://localhost:8080", "service base URL")
+	flags.StringVar(&opts.token, "token", cfg.Auth.AdminToken, "bearer token for reads and cache stats (orders:admin for the hit ratio)")
+	flags.StringVar(&opts.keyset, "keyset", cfg.Kafka.SigningKeyset, "signing keyset file; orders are signed with its active key")
+	flags.BoolVar(&opts.mem, "mem", false, "run the service in-process with an in-memory topic and database")
+	flags.IntVar(&opts.cacheSize, "mem-cache-size", cfg.Cache.Size, "cache size of the in-memory service")
+	flags.DurationVar(&opts.dbLatency, "mem-db-latency", time.Millisecond, "simulated latency of every in-memory database query")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := slog.New(slog.NewJSONHandler(logOutput, nil))
+	if opts.duration <= 0 || opts.rate <= 0 || opts.concurrency <= 0 || opts.readers <= 0 ||
+		opts.probe < 0 || opts.probe > 1 || opts.preload < 0 || opts.hotSet <= 0 {
+		logger.Error("Invalid flags: -duration, -rate, -concurrency, -readers and -hot-set must be positive, -probe from 0 to 1")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	t, err := newTarget(ctx, opts, logger)
+	if err != nil {
+		logger.Error("Failed to prepare the load test", slog.Any("error", err))
+		return 1
+	}
+
+	report, err := t.run(ctx)
+	if err != nil {
+		logger.Error("Load test failed", slog.Any("error", err))
+		return 1
+	}
+	if opts.jsonReport {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		report.print(os.Stdout)
+	}
+	return 0
+}
+
+// parseMix разбирает доли видов чтения.
+func parseMix(value string) (map[string]int, error) {
+	mix := map[string]int{}
+	total := 0
+	for _, part := range strings.Split(value, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		n, err := strconv.Atoi(weight)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid mix entry %q: must be name=weight", part)
+		}
+		switch name {
+		case readHot, readCold, readUnknown:
+		default:
+			return nil, fmt.Errorf("invalid mix entry %q: must be hot, cold or unknown", part)
+		}
+		mix[name] = n
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("mix weights must not all be zero")
+	}
+	return mix, nil
+}
+
+// target проверяемый сервис: куда отправлять заказы, откуда читать и как
+// узнать статистику кэша.
+type target struct {
+	opts     options
+	logger   *slog.Logger
+	producer kafka.KafkaService
+	baseURL  string
+	client   *http.Client
+	// cacheStats статистика кэша; nil — недоступна
+	cacheStats func(ctx context.Context) (*ristrettocache.CacheStats, error)
+
+	generator fake.Generator
+	known     knownOrders
+
+	produce series
+	e2e     series
+	reads   map[string]*series
+}
+
+func newTarget(ctx context.Context, opts options, logger *slog.Logger) (*target, error) {
+	t := &target{
+		opts:      opts,
+		logger:    logger,
+		baseURL:   strings.TrimSuffix(opts.url, "/"),
+		generator: fake.NewGenerator(opts.seed, fake.WithStart(time.Now())),
+		reads:     map[string]*series{readHot: {}, readCold: {}, readUnknown: {}},
+		client: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &http.Transport{MaxIdleConnsPerHost: opts.readers + opts.concurrency*2},
+		},
+	}
+
+	// Отправитель журналирует только ошибки
+	producerLogger := slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	var producerOpts []kafka.Option
+	if opts.keyset != "" {
+		keyset, err := signing.NewFileKeyset(opts.keyset, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing keyset: %w", err)
+		}
+		producerOpts = append(producerOpts, kafka.WithSigner(keyset))
+	}
+
+	if opts.mem {
+		service, err := startMemService(ctx, opts.cacheSize, opts.dbLatency)
+		if err != nil {
+			return nil, err
+		}
+		t.baseURL = service.url
+		t.cacheStats = func(context.Context) (*ristrettocache.CacheStats, error) {
+			stats := service.cache.Stats()
+			return &stats, nil
+		}
+		producerOpts = append(producerOpts, kafka.WithWriter(service.topic))
+	} else if opts.token != "" {
+		t.cacheStats = t.remoteCacheStats
+	}
+
+	producer, err := kafka.NewKafkaService(opts.topic, opts.broker, "0", producerLogger, nil, producerOpts...)
+	if err != nil {
+		return nil, err
+	}
+	t.producer = producer
+	return t, nil
+}
+
+// remoteCacheStats читает статистику кэша сервиса через API администрирования.
+func (t *target) remoteCacheStats(ctx context.Context) (*ristrettocache.CacheStats, error) {
+	resp, err := t.get(ctx, "/api/v1/admin/cache/stats")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cache stats: unexpected status %s", resp.Status)
+	}
+	var stats ristrettocache.CacheStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("cache stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// get выполняет запрос GET к сервису.
+func (t *target) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.opts.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.opts.token)
+	}
+	return t.client.Do(req)
+}
+
+// fetchOrder запрашивает заказ и возвращает код ответа; тело читается
+// целиком, чтобы соединение вернулось в пул.
+func (t *target) fetchOrder(ctx context.Context, orderUID string) (int, error) {
+	resp, err := t.get(ctx, "/api/v1/order?order_uid="+url.QueryEscape(orderUID))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+// awaitQueryable опрашивает API, пока заказ не станет доступен, и
+// возвращает время от start.
+func (t *target) awaitQueryable(ctx context.Context, orderUID string, start time.Time) (time.Duration, error) {
+	ctx, cancel := context.WithDeadline(ctx, start.Add(t.opts.e2eTimeout))
+	defer cancel()
+	for {
+		status, err := t.fetchOrder(ctx, orderUID)
+		if err == nil && status == http.StatusOK {
+			return time.Since(start), nil
+		}
+		if err == nil && status != http.StatusNotFound {
+			return 0, fmt.Errorf("unexpected status %d", status)
+		}
+		timer := time.NewTimer(t.opts.poll)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, fmt.Errorf("not queryable within %s", t.opts.e2eTimeout)
+		}
+	}
+}
+
+// run проводит подготовку и измерение.
+func (t *target) run(ctx context.Context) (*report, error) {
+	if err := t.preload(ctx); err != nil {
+		return nil, err
+	}
+	if t.known.len() == 0 {
+		return nil, fmt.Errorf("no orders are queryable after preload; check -url, -broker and -topic")
+	}
+
+	before := t.snapshotCache(ctx)
+	t.logger.Info("Measurement started", slog.Duration("duration", t.opts.duration), slog.Int("knownOrders", t.known.len()))
+
+	runCtx, cancel := context.WithTimeout(ctx, t.opts.duration)
+	defer cancel()
+	start := time.Now()
+
+	var readers sync.WaitGroup
+	var readCount atomic.Int64
+	for i := 0; i < t.opts.readers; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			t.read(runCtx, &readCount)
+		}()
+	}
+
+	// Проверки доступности ждут заказ дольше окна измерения
+	var probes sync.WaitGroup
+	t.produceOrders(runCtx, t.opts.rate, 0, func(orderUID string, sent time.Time) {
+		if rand.Float64() >= t.opts.probe {
+			return
+		}
+		probes.Add(1)
+		go func() {
+			defer probes.Done()
+			latency, err := t.awaitQueryable(ctx, orderUID, sent)
+			if err != nil {
+				t.e2e.fail(err.Error())
+				return
+			}
+			t.e2e.observe(latency)
+			t.known.add(orderUID)
+		}()
+	})
+	readers.Wait()
+	elapsed := time.Since(start)
+	probes.Wait()
+
+	after := t.snapshotCache(ctx)
+	return t.report(elapsed, readCount.Load(), before, after), ctx.Err()
+}
+
+// preload отправляет заказы для множеств hot и cold и ждет их появления в API.
+func (t *target) preload(ctx context.Context) error {
+	if t.opts.preload == 0 {
+		return nil
+	}
+	t.logger.Info("Preloading orders", slog.Int("count", t.opts.preload))
+
+	var sent []string
+	var mu sync.Mutex
+	t.produceOrders(ctx, 0, t.opts.preload, func(orderUID string, _ time.Time) {
+		mu.Lock()
+		sent = append(sent, orderUID)
+		mu.Unlock()
+	})
+
+	// Заказы ждутся по порядку отправки: к концу очереди они уже доступны
+	start := time.Now()
+	for _, orderUID := range sent {
+		if _, err := t.awaitQueryable(ctx, orderUID, time.Now()); err != nil {
+			t.logger.Warn("Preloaded order is not queryable", slog.String("orderID", orderUID), slog.Any("error", err))
+			continue
+		}
+		t.known.add(orderUID)
+	}
+	// Замеры предзагрузки не входят в отчет
+	t.produce.reset()
+	t.logger.Info("Preload complete", slog.Int("queryable", t.known.len()), slog.Duration("elapsed", time.Since(start)))
+	return ctx.Err()
+}
+
+// produceOrders отправляет count заказов (0 — до отмены ctx) со скоростью rate
+// (0 — без ограничения) и вызывает sent для каждого отправленного.
+func (t *target) produceOrders(ctx context.Context, rate float64, count int, sent func(orderUID string, at time.Time)) {
+	// Генератор не потокобезопасен: заказы создает одна горутина
+	orders := make(chan *model.OrderDetails, t.opts.concurrency)
+	var workers sync.WaitGroup
+	for i := 0; i < t.opts.concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for order := range orders {
+				start := time.Now()
+				if err := t.producer.SendOrder(ctx, order); err != nil {
+					if ctx.Err() == nil {
+						t.produce.fail("send failed")
+						t.logger.Warn("Failed to produce order", slog.Any("error", err))
+					}
+					continue
+				}
+				t.produce.observe(time.Since(start))
+				sent(order.OrderID, start)
+			}
+		}()
+	}
+
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+	start := time.Now()
+	for i := 0; ctx.Err() == nil && (count == 0 || i < count); i++ {
+		if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+		select {
+		case orders <- t.generator.Order():
+		case <-ctx.Done():
+		}
+	}
+	close(orders)
+	workers.Wait()
+}
+
+// read запрашивает заказы по смеси видов чтения до отмены ctx.
+func (t *target) read(ctx context.Context, count *atomic.Int64) {
+	var interval time.Duration
+	if t.opts.readRate > 0 {
+		interval = time.Duration(float64(time.Second) * float64(t.opts.readers) / t.opts.readRate)
+	}
+	total := 0
+	for _, weight := range t.opts.mix {
+		total += weight
+	}
+
+	next := time.Now()
+	for ctx.Err() == nil {
+		if interval > 0 {
+			if wait := time.Until(next); wait > 0 {
+				time.Sleep(wait)
+			}
+			next = next.Add(interval)
+		}
+
+		kind := readUnknown
+		n := rand.IntN(total)
+		for _, k := range []string{readHot, readCold, readUnknown} {
+			if n -= t.opts.mix[k]; n < 0 {
+				kind = k
+				break
+			}
+		}
+		orderUID, expected := t.known.pick(kind, t.opts.hotSet), http.StatusOK
+		if kind == readUnknown {
+			expected = http.StatusNotFound
+		}
+
+		start := time.Now()
+		status, err := t.fetchOrder(ctx, orderUID)
+		if ctx.Err() != nil {
+			// Запрос прерван окончанием замера
+			return
+		}
+		count.Add(1)
+		if err != nil {
+			t.reads[kind].fail("request failed")
+			continue
+		}
+		if status != expected {
+			t.reads[kind].fail(fmt.Sprintf("status %d", status))
+			continue
+		}
+		t.reads[kind].observe(time.Since(start))
+	}
+}
+
+// snapshotCache статистика кэша или nil, если она недоступна.
+func (t *target) snapshotCache(ctx context.Context) *ristrettocache.CacheStats {
+	if t.cacheStats == nil {
+		return nil
+	}
+	stats, err := t.cacheStats(ctx)
+	if err != nil {
+		t.logger.Warn("Cache stats are unavailable", slog.Any("error", err))
+		return nil
+	}
+	return stats
+}
+
+// knownOrders order_uid заказов, доступных в API, в порядке появления.
+type knownOrders struct {
+	mu  sync.RWMutex
+	ids []string
+}
+
+func (k *knownOrders) add(orderUID string) {
+	k.mu.Lock()
+	k.ids = append(k.ids, orderUID)
+	k.mu.Unlock()
+}
+
+func (k *knownOrders) len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.ids)
+}
+
+// pick выбирает order_uid для вида чтения: hot — из первых hotSet заказов,
+// cold — из всех известных, unknown — случайный несуществующий.
+func (k *knownOrders) pick(kind string, hotSet int) string {
+	if kind == readUnknown {
+		return fmt.Sprintf("unknown-%016x", rand.Uint64())
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	n := len(k.ids)
+	if kind == readHot {
+		n = min(n, hotSet)
+	}
+	return k.ids[rand.IntN(n)]
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/kafka"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	httptransport "github.com/Sh1ni-Gami/WB_Tech_L0/transport"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// memTopic топик Kafka в памяти: реализует kafka.MessageWriter для
+// отправителя и kafka.MessageReader для слушателя сервиса.
+type memTopic struct {
+	messages chan kafkago.Message
+
+	mu     sync.Mutex
+	offset int64
+}
+
+func newMemTopic(buffer int) *memTopic {
+	return &memTopic{messages: make(chan kafkago.Message, buffer)}
+}
+
+func (t *memTopic) WriteMessages(ctx context.Context, msgs ...kafkago.Message) error {
+	for _, msg := range msgs {
+		t.mu.Lock()
+		msg.Offset = t.offset
+		t.offset++
+		t.mu.Unlock()
+		if msg.Time.IsZero() {
+			msg.Time = time.Now()
+		}
+
+		select {
+		case t.messages <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (t *memTopic) ReadMessage(ctx context.Context) (kafkago.Message, error) {
+	select {
+	case msg := <-t.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafkago.Message{}, ctx.Err()
+	}
+}
+
+func (t *memTopic) Close() error {
+	return nil
+}
+
+// memStore база данных в памяти с задержкой каждого запроса, реализует
+// ristrettocache.DBService.
+type memStore struct {
+	latency time.Duration
+
+	mu     sync.RWMutex
+	orders map[string]*model.OrderDetails
+	// ids order_uid в порядке добавления
+	ids []string
+}
+
+func newMemStore(latency time.Duration) *memStore {
+	return &memStore{latency: latency, orders: make(map[string]*model.OrderDetails)}
+}
+
+// wait имитирует обращение к базе.
+func (s *memStore) wait(ctx context.Context) error {
+	if s.latency <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(s.latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *memStore) AddOrder(ctx context.Context, order *model.OrderDetails) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.orders[order.OrderID]; ok {
+		return model.ErrDuplicateOrder
+	}
+	s.orders[order.OrderID] = order
+	s.ids = append(s.ids, order.OrderID)
+	return nil
+}
+
+func (s *memStore) UpdateItemStatuses(ctx context.Context, _, next *model.OrderDetails) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[next.OrderID] = next
+	return nil
+}
+
+func (s *memStore) GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	order, ok := s.orders[orderUID]
+	if !ok {
+		return nil, model.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (s *memStore) GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	orders := make(map[string]*model.OrderDetails, len(orderUIDs))
+	for _, orderUID := range orderUIDs {
+		if order, ok := s.orders[orderUID]; ok {
+			orders[orderUID] = order
+		}
+	}
+	return orders, nil
+}
+
+func (s *memStore) GetRecentOrderIDs(ctx context.Context, limit int) ([]string, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, min(limit, len(s.ids)))
+	for i := len(s.ids) - 1; i >= 0 && len(ids) < limit; i-- {
+		ids = append(ids, s.ids[i])
+	}
+	return ids, nil
+}
+
+func (s *memStore) GetOrderIDsByCustomer(ctx context.Context, customerID string) ([]string, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for _, id := range s.ids {
+		if s.orders[id].CustomerID == customerID {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// memService сервис, собранный в процессе из настоящих кэша, слушателя
+// Kafka и HTTP-транспорта поверх топика и базы в памяти.
+type memService struct {
+	url   string
+	cache ristrettocache.CacheService
+	topic *memTopic
+}
+
+// startMemService запускает сервис в памяти на свободном локальном порту
+// и ждет, пока он начнет отвечать. Журнал сервиса — только предупреждения.
+func startMemService(ctx context.Context, cacheSize int, dbLatency time.Duration) (*memService, error) {
+	logger := slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	store := newMemStore(dbLatency)
+	cache, err := ristrettocache.NewCacheService(logger, cacheSize, store, ristrettocache.WithEncodedResponses())
+	if err != nil {
+		return nil, err
+	}
+
+	topic := newMemTopic(1024)
+	listener, err := kafka.NewKafkaService("orders", "memory", "0", logger, cache, kafka.WithReader(topic))
+	if err != nil {
+		return nil, err
+	}
+	listener.StartListening(ctx)
+
+	// Порт выбирается системой; Start принимает только адрес
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := probe.Addr().String()
+	probe.Close()
+
+	// Ограничение частоты на клиента отключено: вся нагрузка идет с одного адреса
+	limits := httptransport.DefaultLimits()
+	limits.RateLimit = 0
+	transport := httptransport.NewHTTPTransport(cache, logger, httptransport.WithLimits(limits))
+	go func() {
+		if err := transport.Start(ctx, addr); err != nil {
+			logger.Error("In-memory HTTP server failed", slog.Any("error", err))
+		}
+	}()
+
+	url := "http://" + addr
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url + "/api/v1/statuses")
+		if err == nil {
+			resp.Body.Close()
+			return &memService{url: url, cache: cache, topic: topic}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("in-memory HTTP server did not start: %w", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+)
+
+// report итоги проверки.
+type report struct {
+	Mode            string             `json:"mode"`
+	DurationSeconds float64            `json:"duration_s"`
+	Produce         summary            `json:"produce"`
+	ProduceRate     float64            `json:"produce_per_second"`
+	EndToEnd        summary            `json:"end_to_end"`
+	Reads           map[string]summary `json:"reads"`
+	ReadRate        float64            `json:"reads_per_second"`
+	// Cache попадания в локальный кэш сервиса за время замера; nil — статистика
+	// недоступна (нет -token с областью orders:admin)
+	Cache *cacheReport `json:"cache,omitempty"`
+}
+
+// cacheReport прирост счетчиков кэша за время замера. Проверки доступности
+// новых заказов тоже учитываются: до появления заказа они промахиваются.
+type cacheReport struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+func (t *target) report(elapsed time.Duration, reads int64, before, after *ristrettocache.CacheStats) *report {
+	r := &report{
+		Mode:            "kafka",
+		DurationSeconds: elapsed.Seconds(),
+		Produce:         t.produce.summarize(),
+		EndToEnd:        t.e2e.summarize(),
+		Reads:           make(map[string]summary, len(t.reads)),
+		ReadRate:        float64(reads) / elapsed.Seconds(),
+	}
+	if t.opts.mem {
+		r.Mode = "memory"
+	}
+	r.ProduceRate = float64(r.Produce.Count-r.Produce.Errors) / elapsed.Seconds()
+	for kind, s := range t.reads {
+		r.Reads[kind] = s.summarize()
+	}
+
+	if before != nil && after != nil {
+		cache := &cacheReport{Hits: after.Hits - before.Hits, Misses: after.Misses - before.Misses}
+		if total := cache.Hits + cache.Misses; total > 0 {
+			cache.HitRatio = float64(cache.Hits) / float64(total)
+		}
+		r.Cache = cache
+	}
+	return r
+}
+
+// print выводит отчет таблицей.
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "Mode %s, measured %.1fs: %.1f orders/s produced, %.1f reads/s\n\n", r.Mode, r.DurationSeconds, r.ProduceRate, r.ReadRate)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "\tcount\terrors\terror rate\tp50 ms\tp90 ms\tp99 ms\tmax ms\t")
+	rows := []struct {
+		name string
+		summary
+	}{
+		{"produce", r.Produce},
+		{"produce→queryable", r.EndToEnd},
+		{"read " + readHot, r.Reads[readHot]},
+		{"read " + readCold, r.Reads[readCold]},
+		{"read " + readUnknown, r.Reads[readUnknown]},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.2f%%\t%.2f\t%.2f\t%.2f\t%.2f\t\n",
+			row.name, row.Count, row.Errors, row.ErrorRate*100, row.P50, row.P90, row.P99, row.Max)
+	}
+	table.Flush()
+
+	for _, row := range rows {
+		for reason, n := range row.Failures {
+			fmt.Fprintf(w, "%s error: %s (%d)\n", row.name, reason, n)
+		}
+	}
+
+	if r.Cache == nil {
+		fmt.Fprintln(w, "\nCache hit ratio: unavailable (pass -token with the orders:admin scope)")
+		return
+	}
+	fmt.Fprintf(w, "\nCache hit ratio: %.2f%% (%d hits, %d misses)\n", r.Cache.HitRatio*100, r.Cache.Hits, r.Cache.Misses)
+}
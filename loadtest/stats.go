@@ -0,0 +1,89 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// series накапливает длительности операций одного вида и число ошибок.
+// Безопасна для одновременного использования.
+type series struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+	// failures число ошибок по причинам
+	failures map[string]int
+}
+
+// observe учитывает успешную операцию.
+func (s *series) observe(d time.Duration) {
+	s.mu.Lock()
+	s.latencies = append(s.latencies, d)
+	s.mu.Unlock()
+}
+
+// fail учитывает неудачную операцию с причиной, например "status 429".
+func (s *series) fail(reason string) {
+	s.mu.Lock()
+	s.errors++
+	if s.failures == nil {
+		s.failures = make(map[string]int)
+	}
+	s.failures[reason]++
+	s.mu.Unlock()
+}
+
+// reset забывает накопленные замеры.
+func (s *series) reset() {
+	s.mu.Lock()
+	s.latencies, s.errors, s.failures = nil, 0, nil
+	s.mu.Unlock()
+}
+
+// summary итоги серии для отчета.
+type summary struct {
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50       float64 `json:"p50_ms"`
+	P90       float64 `json:"p90_ms"`
+	P99       float64 `json:"p99_ms"`
+	Max       float64 `json:"max_ms"`
+	// Failures число ошибок по причинам
+	Failures map[string]int `json:"failures,omitempty"`
+}
+
+// summarize считает процентили успешных операций и долю ошибок среди всех.
+func (s *series) summarize() summary {
+	s.mu.Lock()
+	latencies := slices.Clone(s.latencies)
+	errors := s.errors
+	failures := maps.Clone(s.failures)
+	s.mu.Unlock()
+
+	slices.Sort(latencies)
+	result := summary{Count: len(latencies) + errors, Errors: errors, Failures: failures}
+	if result.Count > 0 {
+		result.ErrorRate = float64(errors) / float64(result.Count)
+	}
+	if len(latencies) > 0 {
+		result.P50 = milliseconds(percentile(latencies, 0.50))
+		result.P90 = milliseconds(percentile(latencies, 0.90))
+		result.P99 = milliseconds(percentile(latencies, 0.99))
+		result.Max = milliseconds(latencies[len(latencies)-1])
+	}
+	return result
+}
+
+// percentile значение процентиля q (от 0 до 1) отсортированной выборки по
+// методу ближайшего ранга.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(q*float64(len(sorted))+0.999999) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,24 @@
+// Package logging передает slog.Logger через context.Context, чтобы записи
+// всех слоев, вызванных одним запросом, содержали общие атрибуты (например,
+// request_id), и назначает идентификаторы запросов для HTTP и gRPC.
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+// WithLogger возвращает контекст, несущий logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext возвращает логгер из контекста или fallback, если его там нет.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
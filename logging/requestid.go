@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// MaxRequestIDLength максимальная длина идентификатора запроса, принятого от клиента.
+const MaxRequestIDLength = 128
+
+// ValidRequestID проверяет, что идентификатор непустой, не длиннее
+// MaxRequestIDLength и состоит из печатных ASCII-символов.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRequestID генерирует случайный идентификатор запроса.
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
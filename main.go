@@ -2,90 +2,140 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
 	"github.com/Sh1ni-Gami/WB_Tech_L0/data_base"
+	graphqlapi "github.com/Sh1ni-Gami/WB_Tech_L0/graphql"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/grpctransport"
 	"github.com/Sh1ni-Gami/WB_Tech_L0/kafka"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/signing"
 	httptransport "github.com/Sh1ni-Gami/WB_Tech_L0/transport"
 	"github.com/joho/godotenv"
 )
 
-const cacheSize = 1024
-
 // App структура для управления зависимостями приложения.
 type App struct {
 	Logger     *slog.Logger
+	Config     *config.Config
 	DB         data_base.DBService
 	Cache      ristrettocache.CacheService
 	Kafka      kafka.KafkaService
 	Transport  httptransport.HTTPTransport
+	GRPC       grpctransport.GRPCTransport
 	Ctx        context.Context
 	CancelFunc context.CancelFunc
 }
 
 // NewApp создает новое приложение, инициализируя все зависимости.
-func NewApp() (*App, error) {
-	// Создаем контекст и логгер.
+func NewApp(logger *slog.Logger, cfg *config.Config) (*App, error) {
+	// Создаем контекст.
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-
-	// Загружаем переменные окружения.
-	if err := godotenv.Load(); err != nil {
-		logger.Warn("Error loading .env file, using system environment variables")
-	}
 
 	// Инициализируем базу данных.
-	dbConn, err := initDatabase(logger)
+	dbConn, err := initDatabase(logger, cfg.DB)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
 	// Инициализируем кэш.
-	cache, err := ristrettocache.NewCacheService(logger, cacheSize, dbConn)
+	cacheOpts, err := initSharedCache(logger, cfg.Cache)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to initialize shared cache: %w", err)
+	}
+	if cfg.Cache.EncodedResponses {
+		cacheOpts = append(cacheOpts, ristrettocache.WithEncodedResponses())
+	}
+	cache, err := ristrettocache.NewCacheService(logger, cfg.Cache.Size, dbConn, cacheOpts...)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to initialize cache: %w", err)
 	}
 
+	// Набор ключей подписи общий для Kafka и прямого приема заказов по HTTP.
+	keyset, err := initKeyset(logger, cfg.Kafka)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load signing keyset: %w", err)
+	}
+
 	// Инициализируем Kafka.
-	kafkaService, err := initKafka(logger, cache)
+	kafkaService, err := initKafka(logger, cfg.Kafka, cache, keyset)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to initialize Kafka: %w", err)
 	}
 
+	// Проверка доступа и маскирование общие для HTTP и gRPC API.
+	authenticators, err := initAuth(logger, cfg.Auth, dbConn)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to initialize authentication: %w", err)
+	}
+	var (
+		transportOpts []httptransport.Option
+		grpcOpts      []grpctransport.Option
+	)
+	if len(authenticators) > 0 {
+		transportOpts = append(transportOpts, httptransport.WithAuthentication(cfg.Auth.AnonymousScopes, authenticators...))
+		grpcOpts = append(grpcOpts, grpctransport.WithAuthentication(cfg.Auth.AnonymousScopes, authenticators...))
+	}
+	if cfg.PII.DefaultProfile != "" {
+		policy, err := httptransport.NewMaskingPolicy(cfg.PII.DefaultProfile, cfg.PII.ScopeProfiles, cfg.PII.ProfilesFile)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to initialize PII masking: %w", err)
+		}
+		transportOpts = append(transportOpts, httptransport.WithMasking(policy))
+		grpcOpts = append(grpcOpts, grpctransport.WithMasking(policy))
+	}
+
 	// Инициализируем HTTP-транспорт.
-	httpTransport := httptransport.NewHTTPTransport(cache, logger)
+	transportOpts = append(transportOpts, httptransport.WithCacheAdmin(cache), httptransport.WithExport(dbConn))
+	if mode := httptransport.IngestMode(cfg.HTTP.IngestMode); mode != "" {
+		transportOpts = append(transportOpts, httptransport.WithIngestion(mode, kafkaService))
+		// Заказ, сохраненный напрямую, проверяется так же, как принятый из Kafka
+		if keyset != nil && cfg.Kafka.SignatureMode != "off" {
+			transportOpts = append(transportOpts, httptransport.WithVerifier(keyset))
+		}
+	}
+	if cfg.GraphQL.Enabled {
+		graphQLHandler, err := initGraphQL(logger, cfg.GraphQL, cache, dbConn)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to initialize GraphQL: %w", err)
+		}
+		transportOpts = append(transportOpts, httptransport.WithGraphQL(graphQLHandler))
+	}
+	transportOpts = append(transportOpts, httptransport.WithLimits(cfg.HTTP.Limits()))
+	httpTransport := httptransport.NewHTTPTransport(cache, logger, transportOpts...)
 
 	return &App{
 		Logger:     logger,
+		Config:     cfg,
 		DB:         dbConn,
 		Cache:      cache,
 		Kafka:      kafkaService,
 		Transport:  httpTransport,
+		GRPC:       grpctransport.NewGRPCTransport(cache, dbConn, logger, grpcOpts...),
 		Ctx:        ctx,
 		CancelFunc: cancel,
 	}, nil
 }
 
 // initDatabase инициализирует подключение к базе данных.
-func initDatabase(logger *slog.Logger) (data_base.DBService, error) {
-	user := getEnv("POSTGRES_USER", "postgres")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	if password == "" {
-		return nil, fmt.Errorf("POSTGRES_PASSWORD is not set")
-	}
-
-	url := getEnv("POSTGRES_URL", "localhost:5432")
-	dbConnString := fmt.Sprintf("postgres://%s:%s@%s/wb_tech", user, password, url)
-
-	dbConn, err := data_base.New(dbConnString, logger)
+func initDatabase(logger *slog.Logger, cfg config.DBConfig) (data_base.DBService, error) {
+	dbConn, err := data_base.New(cfg.ConnString(), logger)
 	if err != nil {
 		logger.Error("Failed to connect to the database", slog.Any("error", err))
 		return nil, err
@@ -95,13 +145,85 @@ func initDatabase(logger *slog.Logger) (data_base.DBService, error) {
 	return dbConn, nil
 }
 
+// initSharedCache подключает общий (L2) уровень кэша, если задан cache.l2_url.
+func initSharedCache(logger *slog.Logger, cfg config.CacheConfig) ([]ristrettocache.Option, error) {
+	if cfg.L2URL == "" {
+		return nil, nil
+	}
+
+	client, err := ristrettocache.NewRESPClient(cfg.L2URL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	logger.Info("Shared cache connection established")
+	return []ristrettocache.Option{ristrettocache.WithSharedTier(client, cfg.L2TTL)}, nil
+}
+
+// initAuth настраивает проверку доступа к HTTP и gRPC API. Если не задан ни
+// один способ аутентификации, API остается открытым.
+func initAuth(logger *slog.Logger, cfg config.AuthConfig, dbConn data_base.DBService) ([]httptransport.Authenticator, error) {
+	var authenticators []httptransport.Authenticator
+
+	if cfg.JWKSFile != "" {
+		jwtAuth, err := httptransport.NewJWTAuthenticator(cfg.JWKSFile, cfg.JWTIssuer, cfg.JWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwtAuth)
+	}
+
+	staticKeys, err := httptransport.ParseStaticAPIKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AdminToken != "" {
+		staticKeys[httptransport.HashAPIKey(cfg.AdminToken)] = httptransport.StaticAPIKey{
+			Name:   "admin",
+			Scopes: []string{httptransport.ScopeOrdersAdmin},
+		}
+	}
+	if len(staticKeys) > 0 {
+		authenticators = append(authenticators, httptransport.NewAPIKeyAuthenticator(staticKeys))
+	}
+
+	if cfg.DBAPIKeys {
+		authenticators = append(authenticators, httptransport.NewAPIKeyAuthenticator(dbConn))
+	}
+
+	if len(authenticators) == 0 {
+		logger.Warn("API authentication is disabled")
+		return nil, nil
+	}
+
+	logger.Info("API authentication enabled", slog.Int("authenticators", len(authenticators)), slog.Any("anonymous_scopes", cfg.AnonymousScopes))
+	return authenticators, nil
+}
+
+// initGraphQL создает GraphQL-эндпоинт: заказы читаются через кэш, списки
+// заказов покупателей — из базы данных.
+func initGraphQL(logger *slog.Logger, cfg config.GraphQLConfig, cache ristrettocache.CacheService, dbConn data_base.DBService) (http.Handler, error) {
+	return graphqlapi.NewHandler(cache, dbConn, logger,
+		graphqlapi.WithMaxDepth(cfg.MaxDepth),
+		graphqlapi.WithMaxComplexity(cfg.MaxComplexity))
+}
+
 // initKafka инициализирует подключение к Kafka.
-func initKafka(logger *slog.Logger, cache ristrettocache.CacheService) (kafka.KafkaService, error) {
-	partition := getEnv("KAFKA_PARTITION", "0")
-	topic := getEnv("KAFKA_TOPIC", "wb-topic")
-	url := getEnv("KAFKA_URL", "localhost:9092")
+func initKafka(logger *slog.Logger, cfg config.KafkaConfig, store kafka.Store, keyset signing.Keyset) (kafka.KafkaService, error) {
+	opts, err := kafkaCodecOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, kafkaSigningOptions(cfg, keyset)...)
 
-	kafkaService, err := kafka.NewKafkaService(topic, url, partition, logger, cache)
+	kafkaService, err := kafka.NewKafkaService(cfg.Topic, cfg.URL, strconv.Itoa(cfg.Partition), logger, store, opts...)
 	if err != nil {
 		logger.Error("Failed to connect to Kafka", slog.Any("error", err))
 		return nil, err
@@ -111,17 +233,120 @@ func initKafka(logger *slog.Logger, cache ristrettocache.CacheService) (kafka.Ka
 	return kafkaService, nil
 }
 
-// getEnv возвращает значение переменной окружения или значение по умолчанию.
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
+// kafkaCodecOptions настраивает форматы сообщений Kafka: JSON и Protobuf
+// принимаются всегда, Avro — если задан kafka.schema_registry (адрес Schema
+// Registry или каталог со схемами). kafka.codec выбирает формат отправки.
+func kafkaCodecOptions(cfg config.KafkaConfig) ([]kafka.Option, error) {
+	codecs := map[string]kafka.Codec{
+		"json":     kafka.NewJSONCodec(),
+		"protobuf": kafka.NewProtobufCodec(),
 	}
-	return fallback
+
+	if cfg.SchemaRegistry != "" {
+		registry, err := kafka.NewSchemaRegistry(cfg.SchemaRegistry)
+		if err != nil {
+			return nil, err
+		}
+		codecs["avro"] = kafka.NewAvroCodec(registry, cfg.AvroSchemaID)
+	}
+
+	producer, ok := codecs[cfg.Codec]
+	if !ok {
+		return nil, fmt.Errorf("unsupported kafka.codec %q", cfg.Codec)
+	}
+
+	opts := []kafka.Option{kafka.WithProducerCodec(producer)}
+	for _, codec := range codecs {
+		opts = append(opts, kafka.WithCodecs(codec))
+	}
+	return opts, nil
+}
+
+// initKeyset загружает набор ключей из kafka.signing_keyset; nil, если он не задан.
+func initKeyset(logger *slog.Logger, cfg config.KafkaConfig) (signing.Keyset, error) {
+	if cfg.SigningKeyset == "" {
+		return nil, nil
+	}
+	return signing.NewFileKeyset(cfg.SigningKeyset, logger)
+}
+
+// kafkaSigningOptions настраивает подпись заказов по набору ключей из
+// kafka.signing_keyset: отправляемые заказы подписываются активным ключом,
+// а kafka.signature_mode задает проверку принятых: off (по умолчанию),
+// reject — отклонять, quarantine — перекладывать в карантинный топик.
+func kafkaSigningOptions(cfg config.KafkaConfig, keyset signing.Keyset) []kafka.Option {
+	if keyset == nil {
+		return nil
+	}
+
+	var opts []kafka.Option
+	if keyset.ActiveKey() != "" {
+		opts = append(opts, kafka.WithSigner(keyset))
+	}
+	switch cfg.SignatureMode {
+	case "reject":
+		opts = append(opts, kafka.WithVerifier(keyset, ""))
+	case "quarantine":
+		opts = append(opts, kafka.WithVerifier(keyset, cfg.QuarantineTopicName()))
+	}
+	return opts
+}
+
+// loadConfig загружает .env и настройки через loader; флаги к этому моменту
+// уже разобраны. Ошибки настроек выводятся в stderr.
+func loadConfig(logger *slog.Logger, loader *config.Loader) (*config.Config, error) {
+	if err := godotenv.Load(); err != nil {
+		logger.Warn("Error loading .env file, using system environment variables")
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return nil, err
+	}
+	return cfg, nil
 }
 
 func main() {
+	// Выгрузка и сверка заказов запускаются как отдельные команды:
+	// WB_Tech_L0 export ..., WB_Tech_L0 verify ...
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		}
+	}
+
+	// Загружаем настройки.
+	flags := flag.NewFlagSet("WB_Tech_L0", flag.ContinueOnError)
+	printConfig := flags.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	loader := config.NewLoader(config.WithFlags(flags), config.WithRequired("db.password"))
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	// При выводе настроек stdout занят ими, поэтому журнал пишется в stderr
+	logOutput := os.Stdout
+	if *printConfig {
+		logOutput = os.Stderr
+	}
+	logger := slog.New(slog.NewJSONHandler(logOutput, nil))
+	cfg, err := loadConfig(logger, loader)
+	if err != nil {
+		os.Exit(2)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Создаём приложение.
-	app, err := NewApp()
+	app, err := NewApp(logger, cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize app: %v\n", err)
 		os.Exit(1)
@@ -136,15 +361,26 @@ func main() {
 
 	// Запуск HTTP-сервера.
 	go func() {
-		if err := app.Transport.Start(app.Ctx, ":8080"); err != nil {
+		if err := app.Transport.Start(app.Ctx, cfg.HTTP.Addr); err != nil {
 			app.Logger.Error("HTTP server failed", slog.Any("error", err))
 			app.CancelFunc()
 		}
-		app.Logger.Info("HTTP server started on :8080")
+		app.Logger.Info("HTTP server started", slog.String("addr", cfg.HTTP.Addr))
 	}()
 
+	// Запуск gRPC-сервера; пустой grpc.addr отключает его.
+	if cfg.GRPC.Addr != "" {
+		go func() {
+			if err := app.GRPC.Start(app.Ctx, cfg.GRPC.Addr); err != nil {
+				app.Logger.Error("gRPC server failed", slog.Any("error", err))
+				app.CancelFunc()
+			}
+		}()
+	}
+
 	// Ожидание завершения.
 	<-app.Ctx.Done()
+	app.Cache.Close()
 	time.Sleep(1 * time.Second) // Ожидание завершения всех операций.
 	app.Logger.Info("Application shut down gracefully")
 }
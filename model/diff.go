@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldDiff различие поля в двух версиях заказа. Field — путь из JSON-имен
+// полей, товары адресуются по chrt_id: items[chrt_id=9934930].price.
+// Отсутствующий в одной из версий товар передается значением nil.
+type FieldDiff struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// timePrecision точность сравнения времени: база хранит микросекунды.
+const timePrecision = time.Microsecond
+
+// Diff сравнивает две версии заказа поле за полем. Время сравнивается как
+// момент с точностью до микросекунды, независимо от часового пояса; товары
+// сопоставляются по chrt_id, поэтому их порядок не важен.
+func Diff(old, new *OrderDetails) []FieldDiff {
+	var d differ
+	d.value("", reflect.ValueOf(*old), reflect.ValueOf(*new))
+	return d.diffs
+}
+
+// differ накапливает различия при обходе.
+type differ struct {
+	diffs []FieldDiff
+}
+
+var (
+	isoTimeType  = reflect.TypeOf(ISO8601Time{})
+	unixTimeType = reflect.TypeOf(UnixTime{})
+	itemsType    = reflect.TypeOf([]ProductItem(nil))
+)
+
+func (d *differ) add(path string, old, new any) {
+	d.diffs = append(d.diffs, FieldDiff{Field: path, Old: old, New: new})
+}
+
+func (d *differ) value(path string, old, new reflect.Value) {
+	switch {
+	case old.Type() == isoTimeType || old.Type() == unixTimeType:
+		a := old.Convert(reflect.TypeOf(time.Time{})).Interface().(time.Time)
+		b := new.Convert(reflect.TypeOf(time.Time{})).Interface().(time.Time)
+		if a.IsZero() != b.IsZero() || !a.Truncate(timePrecision).Equal(b.Truncate(timePrecision)) {
+			d.add(path, old.Interface(), new.Interface())
+		}
+	case old.Type() == itemsType:
+		d.items(path, old.Interface().([]ProductItem), new.Interface().([]ProductItem))
+	case old.Kind() == reflect.Struct:
+		for i := 0; i < old.NumField(); i++ {
+			name := jsonName(old.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			d.value(name, old.Field(i), new.Field(i))
+		}
+	default:
+		if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+			d.add(path, old.Interface(), new.Interface())
+		}
+	}
+}
+
+// items сравнивает товары по chrt_id; если chrt_id повторяется, товары
+// сравниваются по порядку.
+func (d *differ) items(path string, old, new []ProductItem) {
+	oldByID, oldUnique := itemsByChartID(old)
+	newByID, newUnique := itemsByChartID(new)
+	if !oldUnique || !newUnique {
+		for i := 0; i < max(len(old), len(new)); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(old):
+				d.add(itemPath, nil, new[i])
+			case i >= len(new):
+				d.add(itemPath, old[i], nil)
+			default:
+				d.value(itemPath, reflect.ValueOf(old[i]), reflect.ValueOf(new[i]))
+			}
+		}
+		return
+	}
+
+	for _, item := range old {
+		itemPath := fmt.Sprintf("%s[chrt_id=%d]", path, item.ChartID)
+		other, ok := newByID[item.ChartID]
+		if !ok {
+			d.add(itemPath, item, nil)
+			continue
+		}
+		d.value(itemPath, reflect.ValueOf(item), reflect.ValueOf(other))
+	}
+	for _, item := range new {
+		if _, ok := oldByID[item.ChartID]; !ok {
+			d.add(fmt.Sprintf("%s[chrt_id=%d]", path, item.ChartID), nil, item)
+		}
+	}
+}
+
+// itemsByChartID индексирует товары по chrt_id и сообщает, что повторов нет.
+func itemsByChartID(items []ProductItem) (map[int]ProductItem, bool) {
+	byID := make(map[int]ProductItem, len(items))
+	for _, item := range items {
+		if _, ok := byID[item.ChartID]; ok {
+			return nil, false
+		}
+		byID[item.ChartID] = item
+	}
+	return byID, true
+}
+
+// jsonName имя поля в JSON; пустое для полей, не попадающих в JSON.
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,18 @@
+package model
+
+import "errors"
+
+// ErrOrderNotFound возвращается хранилищем, если заказа с таким order_uid нет.
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrAPIKeyNotFound возвращается хранилищем ключей, если ключа с таким хэшем
+// нет или он отозван.
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// ErrDuplicateOrder возвращается хранилищем, если заказ с таким order_uid уже сохранен.
+var ErrDuplicateOrder = errors.New("order already exists")
+
+// ErrConflictingData возвращается хранилищем, если заказ ссылается на уже
+// сохраненные данные другого содержания: транзакцию оплаты чужого заказа или
+// товар с тем же chrt_id, но другими полями.
+var ErrConflictingData = errors.New("order conflicts with stored data")
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// OrderFilter условия отбора заказов для списков и выгрузок. Пустые поля
+// не ограничивают выборку.
+type OrderFilter struct {
+	// CreatedFrom начало периода по date_created (включительно).
+	CreatedFrom time.Time
+	// CreatedTo конец периода по date_created (не включительно).
+	CreatedTo time.Time
+	// CustomerID заказы одного покупателя.
+	CustomerID string
+	// DeliveryService заказы одной службы доставки.
+	DeliveryService string
+	// Limit максимальное число заказов, 0 — без ограничения.
+	Limit int
+	// After продолжение постраничного списка: заказы строго после этого ключа.
+	After *OrderKey
+}
+
+// OrderKey позиция заказа в порядке (date_created, order_uid).
+type OrderKey struct {
+	CreatedAt time.Time
+	OrderUID  string
+}
+
+// Validate проверяет согласованность условий.
+func (f OrderFilter) Validate() error {
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && !f.CreatedFrom.Before(f.CreatedTo) {
+		return fmt.Errorf("created_from must be before created_to")
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	return nil
+}
+
+// ParseFilterTime разбирает границу периода: дату "2006-01-02" (полночь UTC)
+// или время в формате RFC 3339.
+func ParseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
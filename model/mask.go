@@ -0,0 +1,178 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskRule способ маскирования одного поля.
+type MaskRule string
+
+const (
+	// MaskKeep оставляет значение без изменений.
+	MaskKeep MaskRule = "keep"
+	// MaskPartial оставляет первый символ: "Ploshad Mira 15" -> "P***".
+	MaskPartial MaskRule = "partial"
+	// MaskPhone оставляет код страны и две последние цифры: "+9720000000" -> "+972*****00".
+	MaskPhone MaskRule = "phone"
+	// MaskEmail оставляет первый символ и домен: "test@gmail.com" -> "t***@gmail.com".
+	MaskEmail MaskRule = "email"
+	// MaskRedact заменяет значение пустой строкой.
+	MaskRedact MaskRule = "redact"
+)
+
+// MaskableFields поля заказа, содержащие персональные данные, которые можно маскировать.
+var MaskableFields = []string{
+	"delivery.name",
+	"delivery.phone",
+	"delivery.zip",
+	"delivery.city",
+	"delivery.address",
+	"delivery.region",
+	"delivery.email",
+	"payment.transaction",
+	"payment.request_id",
+	"customer_id",
+}
+
+// MaskProfile правила маскирования по пути поля (например, "delivery.phone").
+// Поля, не указанные в профиле, не изменяются.
+type MaskProfile map[string]MaskRule
+
+// Встроенные профили маскирования.
+var (
+	// MaskProfileFull не скрывает ничего.
+	MaskProfileFull = MaskProfile{}
+	// MaskProfilePartial оставляет части значений, достаточные для поддержки.
+	MaskProfilePartial = MaskProfile{
+		"delivery.name":       MaskPartial,
+		"delivery.phone":      MaskPhone,
+		"delivery.zip":        MaskPartial,
+		"delivery.address":    MaskPartial,
+		"delivery.email":      MaskEmail,
+		"payment.transaction": MaskPartial,
+	}
+	// MaskProfileRedacted полностью удаляет персональные данные.
+	MaskProfileRedacted = MaskProfile{
+		"delivery.name":       MaskRedact,
+		"delivery.phone":      MaskRedact,
+		"delivery.zip":        MaskRedact,
+		"delivery.city":       MaskRedact,
+		"delivery.address":    MaskRedact,
+		"delivery.region":     MaskRedact,
+		"delivery.email":      MaskRedact,
+		"payment.transaction": MaskRedact,
+		"payment.request_id":  MaskRedact,
+		"customer_id":         MaskRedact,
+	}
+)
+
+// Validate проверяет, что профиль ссылается только на известные поля и правила.
+func (p MaskProfile) Validate() error {
+	for field, rule := range p {
+		known := false
+		for _, maskable := range MaskableFields {
+			if field == maskable {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("field %q cannot be masked", field)
+		}
+
+		switch rule {
+		case MaskKeep, MaskPartial, MaskPhone, MaskEmail, MaskRedact:
+		default:
+			return fmt.Errorf("unknown mask rule %q for field %q", rule, field)
+		}
+	}
+	return nil
+}
+
+// Masked возвращает копию заказа с примененным профилем маскирования.
+func (o *OrderDetails) Masked(profile MaskProfile) *OrderDetails {
+	if len(profile) == 0 {
+		return o
+	}
+
+	masked := *o
+	apply := func(field string, value *string) {
+		if rule, ok := profile[field]; ok {
+			*value = rule.Apply(*value)
+		}
+	}
+
+	apply("delivery.name", &masked.Address.FullName)
+	apply("delivery.phone", &masked.Address.Phone)
+	apply("delivery.zip", &masked.Address.ZipCode)
+	apply("delivery.city", &masked.Address.City)
+	apply("delivery.address", &masked.Address.Street)
+	apply("delivery.region", &masked.Address.Region)
+	apply("delivery.email", &masked.Address.Email)
+	apply("payment.transaction", &masked.Payment.TransactionID)
+	apply("payment.request_id", &masked.Payment.RequestID)
+	apply("customer_id", &masked.CustomerID)
+
+	return &masked
+}
+
+// Apply маскирует значение по правилу. Пустые значения не изменяются.
+func (r MaskRule) Apply(value string) string {
+	if value == "" {
+		return value
+	}
+
+	switch r {
+	case MaskPartial:
+		return firstRune(value) + "***"
+	case MaskPhone:
+		return maskPhone(value)
+	case MaskEmail:
+		local, domain, ok := strings.Cut(value, "@")
+		if !ok {
+			return firstRune(value) + "***"
+		}
+		return firstRune(local) + "***@" + domain
+	case MaskRedact:
+		return ""
+	default:
+		return value
+	}
+}
+
+// maskPhone оставляет "+" и первые три цифры, последние две цифры, остальные
+// цифры заменяет звездочками.
+func maskPhone(phone string) string {
+	digits := 0
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	if digits <= 5 {
+		return strings.Repeat("*", utf8.RuneCountInString(phone))
+	}
+
+	var b strings.Builder
+	seen := 0
+	for _, r := range phone {
+		if r < '0' || r > '9' {
+			b.WriteRune(r)
+			continue
+		}
+		seen++
+		if seen <= 3 || seen > digits-2 {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
+
+func firstRune(value string) string {
+	r, _ := utf8.DecodeRuneInString(value)
+	return string(r)
+}
@@ -5,103 +5,92 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
-
-	"github.com/go-faker/faker/v4"
 )
 
 type AddressDetails struct {
-	FullName string `json:"name" faker:"name"`
-	Phone    string `json:"phone" faker:"phone_number"`
-	ZipCode  string `json:"zip" faker:"word"`
-	City     string `json:"city" faker:"word"`
-	Street   string `json:"address" faker:"real_address"`
-	Region   string `json:"region" faker:"word"`
-	Email    string `json:"email" faker:"email"`
+	FullName string `json:"name" jsonschema:"required"`
+	Phone    string `json:"phone" jsonschema:"required"`
+	ZipCode  string `json:"zip"`
+	City     string `json:"city" jsonschema:"required"`
+	Street   string `json:"address" jsonschema:"required"`
+	Region   string `json:"region"`
+	Email    string `json:"email"`
 }
 
 type PaymentDetails struct {
-	TransactionID string `json:"transaction" faker:"uuid_hyphenated"`
-	RequestID     string `json:"request_id" faker:"uuid_hyphenated"`
-	Currency      string `json:"currency" faker:"word"`
-	Provider      string `json:"provider" faker:"word"`
-	Amount        int    `json:"amount"`
-	PaymentDate   int    `json:"payment_dt"`
-	Bank          string `json:"bank" faker:"word"`
-	DeliveryCost  int    `json:"delivery_cost"`
-	TotalGoods    int    `json:"goods_total"`
-	CustomFee     int    `json:"custom_fee"`
+	TransactionID string   `json:"transaction" jsonschema:"required"`
+	RequestID     string   `json:"request_id"`
+	Currency      string   `json:"currency" jsonschema:"required"`
+	Provider      string   `json:"provider" jsonschema:"required"`
+	Amount        Money    `json:"amount" jsonschema:"minimum=0"`
+	PaymentDate   UnixTime `json:"payment_dt"`
+	Bank          string   `json:"bank"`
+	DeliveryCost  Money    `json:"delivery_cost" jsonschema:"minimum=0"`
+	TotalGoods    Money    `json:"goods_total" jsonschema:"minimum=0"`
+	CustomFee     Money    `json:"custom_fee" jsonschema:"minimum=0"`
 }
 
 type ProductItem struct {
-	ChartID     int    `json:"chrt_id"`
-	TrackingNum string `json:"track_number" faker:"uuid_hyphenated"`
-	Price       int    `json:"price"`
-	RID         string `json:"rid"`
-	Name        string `json:"name" faker:"word"`
-	Discount    int    `json:"sale"`
-	Size        string `json:"size" faker:"word"`
-	TotalPrice  int    `json:"total_price"`
-	ProductID   int    `json:"nm_id"`
-	Brand       string `json:"brand" faker:"word"`
-	Status      int    `json:"status"`
-}
-
-type ISO8601Time time.Time
-
-func (t *ISO8601Time) UnmarshalJSON(data []byte) error {
-	str := string(bytes.Trim(data, `"`))
-	parsedTime, err := time.Parse(time.RFC3339, str)
-	if err != nil {
-		return fmt.Errorf("invalid time format: %s", str)
-	}
-	*t = ISO8601Time(parsedTime)
-	return nil
-}
-
-func (t ISO8601Time) MarshalJSON() ([]byte, error) {
-	formatted := fmt.Sprintf("\"%s\"", time.Time(t).Format(time.RFC3339))
-	return []byte(formatted), nil
+	ChartID     int        `json:"chrt_id"`
+	TrackingNum string     `json:"track_number" jsonschema:"required"`
+	Price       Money      `json:"price" jsonschema:"minimum=0"`
+	RID         string     `json:"rid"`
+	Name        string     `json:"name" jsonschema:"required"`
+	Discount    int        `json:"sale" jsonschema:"minimum=0,maximum=100"`
+	Size        string     `json:"size"`
+	TotalPrice  Money      `json:"total_price" jsonschema:"minimum=0"`
+	ProductID   int        `json:"nm_id"`
+	Brand       string     `json:"brand"`
+	Status      ItemStatus `json:"status" jsonschema:"required"`
 }
 
 type OrderDetails struct {
-	OrderID           string         `json:"order_uid" faker:"uuid_hyphenated"`
-	TrackingNumber    string         `json:"track_number" faker:"uuid_hyphenated"`
-	EntryPoint        string         `json:"entry"`
-	Address           AddressDetails `json:"delivery"`
-	Payment           PaymentDetails `json:"payment"`
-	Products          []ProductItem  `json:"items"`
+	OrderID           string         `json:"order_uid" jsonschema:"required"`
+	TrackingNumber    string         `json:"track_number" jsonschema:"required"`
+	EntryPoint        string         `json:"entry" jsonschema:"required"`
+	Address           AddressDetails `json:"delivery" jsonschema:"required"`
+	Payment           PaymentDetails `json:"payment" jsonschema:"required"`
+	Products          []ProductItem  `json:"items" jsonschema:"required"`
 	Locale            string         `json:"locale"`
 	Signature         string         `json:"internal_signature"`
-	CustomerID        string         `json:"customer_id" faker:"uuid_hyphenated"`
-	DeliveryService   string         `json:"delivery_service" faker:"word"`
+	CustomerID        string         `json:"customer_id" jsonschema:"required"`
+	DeliveryService   string         `json:"delivery_service"`
 	ShardKey          string         `json:"shardkey"`
 	SMID              int            `json:"sm_id"`
-	CreationTimestamp ISO8601Time    `json:"date_created"`
+	CreationTimestamp ISO8601Time    `json:"date_created" jsonschema:"required"`
 	OutOfShard        string         `json:"oof_shard"`
+
+	// SchemaVersion версия схемы, в которой заказ был получен; заполняет
+	// ParseOrder. 0 — текущая версия (заказ создан в коде или прочитан из базы).
+	SchemaVersion int `json:"-"`
 }
 
-func NewFakeOrder(maxItems int) (*OrderDetails, error) {
-	order := OrderDetails{}
-	if err := faker.FakeData(&order); err != nil {
-		return nil, fmt.Errorf("failed to generate fake data: %w", err)
+// ParseOrder разбирает JSON-сообщение с заказом. Сообщения прежних версий
+// схемы переводятся в текущую, сообщения неизвестных версий отклоняются
+// с ErrUnsupportedSchemaVersion. Все суммы заказа в валюте payment.currency.
+func ParseOrder(data []byte, maxItems int) (*OrderDetails, error) {
+	version, err := schemaVersionOf(data)
+	if err != nil {
+		return nil, err
 	}
-	if len(order.Products) > maxItems {
-		order.Products = order.Products[:maxItems]
+	if err := checkSingleCurrency(data); err != nil {
+		return nil, err
+	}
+	if version != SchemaVersion {
+		if data, err = upcast(data, version); err != nil {
+			return nil, err
+		}
 	}
-	order.CreationTimestamp = ISO8601Time(time.Now())
-	return &order, nil
-}
 
-func ParseOrder(data []byte, maxItems int) (*OrderDetails, error) {
 	var order OrderDetails
 	decoder := json.NewDecoder(bytes.NewReader(data))
 	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(&order); err != nil {
+	if err := decoder.Decode(&versionedOrder{OrderDetails: &order}); err != nil {
 		return nil, fmt.Errorf("invalid JSON structure: %w", err)
 	}
 	if len(order.Products) > maxItems {
 		return nil, errors.New("too many products in the order")
 	}
+	order.SchemaVersion = version
 	return &order, nil
 }
@@ -0,0 +1,201 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/currency"
+)
+
+// Money денежная сумма в минимальных единицах валюты заказа (центах,
+// копейках); валюта задается полем payment.currency. Число знаков после
+// запятой зависит от валюты: 2 для USD, 0 для JPY, 3 для BHD.
+//
+// Заказ всегда в одной валюте: суммы оплаты и товаров своей валюты не
+// несут, сообщения с валютой у товара отклоняет ParseOrder. Поэтому Money
+// переводится в основные единицы только показателем валюты оплаты
+// (PaymentDetails.Exponent).
+type Money int64
+
+// currencyExponents валюты, у которых минимальная единица по ISO 4217 не
+// сотая доля; у остальных валют два знака после запятой. Точность вывода в
+// CLDR (golang.org/x/text/currency) для части валют отличается от ISO 4217,
+// поэтому для пересчета сумм она не используется.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyExponent возвращает число знаков после запятой у валюты ISO 4217,
+// то есть показатель минимальной единицы: сумма в основных единицах равна
+// Money / 10^exponent.
+func CurrencyExponent(code string) (int, error) {
+	if _, err := currency.ParseISO(code); err != nil {
+		return 0, fmt.Errorf("unknown currency %q", code)
+	}
+	if exponent, ok := currencyExponents[strings.ToUpper(code)]; ok {
+		return exponent, nil
+	}
+	return 2, nil
+}
+
+// Currencies возвращает известные коды валют ISO 4217 по алфавиту — те же,
+// что принимает CurrencyExponent.
+func Currencies() []string {
+	return slices.Clone(currencyCodes())
+}
+
+// currencyCodes перебирает коды один раз: в golang.org/x/text нет их списка.
+var currencyCodes = sync.OnceValue(func() []string {
+	var codes []string
+	code := make([]byte, 3)
+	for a := byte('A'); a <= 'Z'; a++ {
+		for b := byte('A'); b <= 'Z'; b++ {
+			for c := byte('A'); c <= 'Z'; c++ {
+				code[0], code[1], code[2] = a, b, c
+				if _, err := currency.ParseISO(string(code)); err == nil {
+					codes = append(codes, string(code))
+				}
+			}
+		}
+	}
+	return codes
+})
+
+// MoneyFromMajor переводит целую сумму в основных единицах валюты в Money.
+func MoneyFromMajor(amount int64, code string) (Money, error) {
+	exponent, err := CurrencyExponent(code)
+	if err != nil {
+		return 0, err
+	}
+	factor := int64(math.Pow10(exponent))
+	if amount > math.MaxInt64/factor || amount < math.MinInt64/factor {
+		return 0, fmt.Errorf("amount %d %s is out of range", amount, code)
+	}
+	return Money(amount * factor), nil
+}
+
+// Decimal возвращает сумму в основных единицах с exponent знаками после
+// запятой без потери точности: Money(181700).Decimal(2) == "1817.00".
+func (m Money) Decimal(exponent int) string {
+	digits := strconv.FormatInt(int64(m), 10)
+	if exponent <= 0 {
+		return digits
+	}
+
+	sign := ""
+	if m < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for len(digits) <= exponent {
+		digits = "0" + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// Major возвращает сумму в основных единицах валюты для вывода. Для
+// вычислений используются минимальные единицы.
+func (m Money) Major(exponent int) float64 {
+	return float64(m) / math.Pow10(exponent)
+}
+
+// Exponent возвращает число знаков после запятой у валюты оплаты; для
+// неизвестной валюты суммы выводятся в минимальных единицах (0).
+func (p PaymentDetails) Exponent() int {
+	exponent, err := CurrencyExponent(p.Currency)
+	if err != nil {
+		return 0
+	}
+	return exponent
+}
+
+// checkSingleCurrency отклоняет сообщение, в котором у товара указана своя
+// валюта: все суммы заказа задаются в payment.currency.
+func checkSingleCurrency(data []byte) error {
+	var message struct {
+		Items []struct {
+			Currency json.RawMessage `json:"currency"`
+		} `json:"items"`
+	}
+	// Ошибку структуры сообщит строгий разбор
+	if json.Unmarshal(data, &message) != nil {
+		return nil
+	}
+	for i, item := range message.Items {
+		if item.Currency != nil {
+			return fmt.Errorf("items[%d].currency is not allowed: all amounts of an order are in payment.currency", i)
+		}
+	}
+	return nil
+}
+
+// upcastMajorToMinorUnits переводит сообщение версии 1, где суммы указаны в
+// основных единицах валюты, в версию 2 с суммами в минимальных единицах.
+func upcastMajorToMinorUnits(payload map[string]any) error {
+	return scaleAmounts(payload, func(field string, value, factor int64) (int64, error) {
+		if value > math.MaxInt64/factor || value < math.MinInt64/factor {
+			return 0, fmt.Errorf("%s is out of range", field)
+		}
+		return value * factor, nil
+	})
+}
+
+// downcastMinorToMajorUnits переводит сообщение версии 2 в версию 1.
+// Сумма с дробной частью в версии 1 не представима.
+func downcastMinorToMajorUnits(payload map[string]any) error {
+	return scaleAmounts(payload, func(field string, value, factor int64) (int64, error) {
+		if value%factor != 0 {
+			return 0, fmt.Errorf("%s %d is not a whole amount in major units", field, value)
+		}
+		return value / factor, nil
+	})
+}
+
+// scaleAmounts заменяет каждую сумму сообщения результатом convert; factor —
+// число минимальных единиц в основной единице валюты payment.currency.
+func scaleAmounts(payload map[string]any, convert func(field string, value, factor int64) (int64, error)) error {
+	payment, _ := payload["payment"].(map[string]any)
+	code, _ := payment["currency"].(string)
+	exponent, err := CurrencyExponent(code)
+	if err != nil {
+		return err
+	}
+	factor := int64(math.Pow10(exponent))
+
+	scale := func(object map[string]any, fields ...string) error {
+		for _, field := range fields {
+			number, ok := object[field].(json.Number)
+			if !ok {
+				continue
+			}
+			value, err := number.Int64()
+			if err != nil {
+				return fmt.Errorf("%s must be an integer", field)
+			}
+			if object[field], err = convert(field, value, factor); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := scale(payment, "amount", "delivery_cost", "goods_total", "custom_fee"); err != nil {
+		return err
+	}
+	items, _ := payload["items"].([]any)
+	for _, item := range items {
+		if item, ok := item.(map[string]any); ok {
+			if err := scale(item, "price", "total_price"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/jsonschema"
+)
+
+// Описания в схеме адресованы внешним производителям заказов, поэтому они
+// на английском, как и остальные ответы API.
+
+// JSONSchema описывает сумму в минимальных единицах валюты.
+func (Money) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        jsonschema.Type{"integer"},
+		Description: "Amount in minor units of payment.currency (cents for USD, whole yen for JPY); every amount of an order is in that currency",
+	}
+}
+
+// JSONSchema описывает известные коды статусов товара. Неизвестные коды
+// допустимы, поэтому перечисляются только в описании.
+func (ItemStatus) JSONSchema() *jsonschema.Schema {
+	names := make([]string, 0, len(itemStatuses))
+	for _, status := range ItemStatuses() {
+		names = append(names, fmt.Sprintf("%d %s", int(status), status.Name()))
+	}
+	return &jsonschema.Schema{
+		Type:        jsonschema.Type{"integer"},
+		Description: "Item status code: " + strings.Join(names, ", ") + "; other codes are accepted as unknown",
+	}
+}
+
+// JSONSchema перечисляет состояния заказа.
+func (OrderState) JSONSchema() *jsonschema.Schema {
+	schema := &jsonschema.Schema{Type: jsonschema.Type{"string"}, Description: "Order state derived from item statuses"}
+	for _, state := range OrderStates() {
+		schema.Enum = append(schema.Enum, string(state))
+	}
+	return schema
+}
+
+// JSONSchema описывает время ISO 8601; null — время не задано.
+func (ISO8601Time) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        jsonschema.Type{"string", "null"},
+		Format:      "date-time",
+		Description: "ISO 8601 time; the zone may be omitted (UTC) and the date and time may be separated by a space",
+	}
+}
+
+// JSONSchema описывает время в секундах Unix; при приеме допускается и
+// строка ISO 8601.
+func (UnixTime) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        jsonschema.Type{"integer", "string", "null"},
+		Format:      "date-time",
+		Description: "Unix time in seconds; an ISO 8601 string is accepted on input, 0 and null mean the time is not set",
+	}
+}
+
+// JSONSchemaExtend ограничивает валюту известными кодами ISO 4217.
+func (PaymentDetails) JSONSchemaExtend(schema *jsonschema.Schema) {
+	codes := Currencies()
+	currencySchema := &jsonschema.Schema{
+		Type:        jsonschema.Type{"string"},
+		Description: "ISO 4217 currency code in upper case; the currency of every amount in the order, including item prices",
+		Enum:        make([]any, 0, len(codes)),
+	}
+	for _, code := range codes {
+		currencySchema.Enum = append(currencySchema.Enum, code)
+	}
+	schema.Properties.Set("currency", currencySchema)
+}
+
+// JSONSchemaExtend добавляет ограничения, которые не выразить тегами:
+// количество товаров и версию схемы сообщения.
+func (OrderDetails) JSONSchemaExtend(schema *jsonschema.Schema) {
+	items := schema.Properties.Get("items")
+	items.MinItems = jsonschema.Ptr(1)
+	items.MaxItems = jsonschema.Ptr(MaxItems)
+
+	schema.Properties.Set(SchemaVersionField, &jsonschema.Schema{
+		Type:        jsonschema.Type{"integer"},
+		Minimum:     jsonschema.Ptr(1.0),
+		Maximum:     jsonschema.Ptr(float64(SchemaVersion)),
+		Description: fmt.Sprintf("Message schema version; messages without it are version 1 and are upgraded to version %d (amounts in major units become minor units)", SchemaVersion),
+	})
+	schema.Description = fmt.Sprintf("Order message, schema version %d. All amounts are in payment.currency. Unknown fields are rejected.", SchemaVersion)
+}
+
+// SchemaTypes имена структур заказа в схемах; совпадают с типами GraphQL.
+func SchemaTypes() map[reflect.Type]string {
+	return map[reflect.Type]string{
+		reflect.TypeOf(OrderDetails{}):   "Order",
+		reflect.TypeOf(AddressDetails{}): "Delivery",
+		reflect.TypeOf(PaymentDetails{}): "Payment",
+		reflect.TypeOf(ProductItem{}):    "Item",
+	}
+}
+
+// OrderSchema строит JSON Schema сообщения с заказом: тот же контракт, что
+// проверяют ParseOrder и Validate. id — значение $id схемы.
+func OrderSchema(id string) (*jsonschema.Schema, error) {
+	names := SchemaTypes()
+	delete(names, reflect.TypeOf(OrderDetails{}))
+	reflector := &jsonschema.Reflector{Names: names, RefPrefix: "#/$defs/"}
+
+	schema, err := reflector.Reflect(reflect.TypeOf(OrderDetails{}))
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = jsonschema.Draft
+	schema.ID = id
+	schema.Title = "Order"
+	schema.Defs = reflector.Defs
+	return schema, nil
+}
@@ -0,0 +1,236 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ItemStatus статус товара в заказе. В сообщениях передается числом.
+// Неизвестные коды принимаются, чтобы не отклонять заказы производителей,
+// использующих коды вне справочника, и выводятся как "unknown".
+type ItemStatus int
+
+// Известные статусы товара.
+const (
+	// StatusCreated товар заказан и ожидает оплаты.
+	StatusCreated ItemStatus = 100
+	// StatusPaid товар оплачен.
+	StatusPaid ItemStatus = 110
+	// StatusAssembling товар собирается на складе.
+	StatusAssembling ItemStatus = 120
+	// StatusInTransit товар передан в доставку.
+	StatusInTransit ItemStatus = 200
+	// StatusReadyForPickup товар ожидает покупателя в пункте выдачи.
+	StatusReadyForPickup ItemStatus = 201
+	// StatusDelivered товар получен покупателем.
+	StatusDelivered ItemStatus = 202
+	// StatusCancelled товар отменен до получения.
+	StatusCancelled ItemStatus = 300
+	// StatusReturnRequested покупатель оформил возврат товара.
+	StatusReturnRequested ItemStatus = 400
+	// StatusReturned товар возвращен продавцу.
+	StatusReturned ItemStatus = 401
+)
+
+// ErrIllegalTransition возвращается при недопустимой смене статуса товара.
+var ErrIllegalTransition = errors.New("illegal item status transition")
+
+// statusInfo описание статуса и статусы, в которые из него можно перейти.
+type statusInfo struct {
+	name        string
+	description string
+	next        []ItemStatus
+}
+
+// itemStatuses известные статусы. Переходы идут только вперед по жизненному
+// циклу: отмена возможна до получения, возврат — после.
+var itemStatuses = map[ItemStatus]statusInfo{
+	StatusCreated: {"created", "Ordered, awaiting payment",
+		[]ItemStatus{StatusPaid, StatusCancelled}},
+	StatusPaid: {"paid", "Paid",
+		[]ItemStatus{StatusAssembling, StatusCancelled}},
+	StatusAssembling: {"assembling", "Being assembled at the warehouse",
+		[]ItemStatus{StatusInTransit, StatusCancelled}},
+	StatusInTransit: {"in_transit", "Handed over for delivery",
+		[]ItemStatus{StatusReadyForPickup, StatusDelivered, StatusCancelled}},
+	StatusReadyForPickup: {"ready_for_pickup", "Ready for pickup",
+		[]ItemStatus{StatusDelivered, StatusCancelled}},
+	StatusDelivered: {"delivered", "Delivered",
+		[]ItemStatus{StatusReturnRequested}},
+	StatusCancelled: {"cancelled", "Cancelled", nil},
+	StatusReturnRequested: {"return_requested", "Return requested",
+		[]ItemStatus{StatusReturned, StatusDelivered}},
+	StatusReturned: {"returned", "Returned to seller", nil},
+}
+
+// ItemStatuses возвращает известные статусы товара по возрастанию кода.
+func ItemStatuses() []ItemStatus {
+	statuses := make([]ItemStatus, 0, len(itemStatuses))
+	for status := range itemStatuses {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	return statuses
+}
+
+// Known сообщает, что статус входит в список известных.
+func (s ItemStatus) Known() bool {
+	_, ok := itemStatuses[s]
+	return ok
+}
+
+// Name возвращает машинное имя статуса ("delivered"); для неизвестного
+// статуса — "unknown".
+func (s ItemStatus) Name() string {
+	if info, ok := itemStatuses[s]; ok {
+		return info.name
+	}
+	return "unknown"
+}
+
+// Description возвращает описание статуса для людей (на английском, как
+// остальные ответы API).
+func (s ItemStatus) Description() string {
+	if info, ok := itemStatuses[s]; ok {
+		return info.description
+	}
+	return fmt.Sprintf("Unknown status %d", int(s))
+}
+
+// Next возвращает статусы, в которые допустим переход из s.
+func (s ItemStatus) Next() []ItemStatus {
+	return slices.Clone(itemStatuses[s].next)
+}
+
+func (s ItemStatus) String() string {
+	return fmt.Sprintf("%d (%s)", int(s), s.Name())
+}
+
+// ValidateTransition проверяет смену статуса товара. Сохранение прежнего
+// статуса, в том числе неизвестного, допустимо всегда, переходы из
+// неизвестных статусов и в них — нет.
+func ValidateTransition(from, to ItemStatus) error {
+	if from == to {
+		return nil
+	}
+	if !from.Known() || !to.Known() {
+		return fmt.Errorf("%w: %s -> %s: unknown status", ErrIllegalTransition, from, to)
+	}
+	if slices.Contains(itemStatuses[from].next, to) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, from, to)
+}
+
+// ValidateOrderTransition проверяет новую версию заказа относительно
+// сохраненной: статус каждого товара (по chrt_id) должен меняться допустимо.
+// Товары, которых не было в сохраненном заказе, должны быть в начальном
+// статусе, удалять товары нельзя.
+func ValidateOrderTransition(previous, next *OrderDetails) error {
+	before := make(map[int]ItemStatus, len(previous.Products))
+	for _, item := range previous.Products {
+		before[item.ChartID] = item.Status
+	}
+
+	var problems []string
+	for _, item := range next.Products {
+		from, ok := before[item.ChartID]
+		delete(before, item.ChartID)
+		if !ok {
+			from = StatusCreated
+		}
+		if err := ValidateTransition(from, item.Status); err != nil {
+			problems = append(problems, fmt.Sprintf("items[chrt_id=%d]: %v", item.ChartID, err))
+		}
+	}
+	for chartID := range before {
+		problems = append(problems, fmt.Sprintf("items[chrt_id=%d]: item must not be removed", chartID))
+	}
+
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// OrderState состояние заказа, выводимое из статусов его товаров.
+type OrderState string
+
+// Состояния заказа.
+const (
+	// StateCreated заказ создан и не оплачен.
+	StateCreated OrderState = "created"
+	// StatePaid заказ оплачен, товары еще на складе.
+	StatePaid OrderState = "paid"
+	// StateInTransit хотя бы один товар передан в доставку.
+	StateInTransit OrderState = "in_transit"
+	// StateDelivered получены все товары, кроме отмененных.
+	StateDelivered OrderState = "delivered"
+	// StateCancelled отменены все товары.
+	StateCancelled OrderState = "cancelled"
+	// StatePartiallyReturned по части товаров (или по всем) оформлен возврат.
+	StatePartiallyReturned OrderState = "partially_returned"
+)
+
+// orderStateDescriptions описания состояний заказа для людей (на английском).
+var orderStateDescriptions = map[OrderState]string{
+	StateCreated:           "Created, awaiting payment",
+	StatePaid:              "Paid",
+	StateInTransit:         "In transit",
+	StateDelivered:         "Delivered",
+	StateCancelled:         "Cancelled",
+	StatePartiallyReturned: "Partially returned",
+}
+
+// OrderStates возвращает состояния заказа в порядке жизненного цикла.
+func OrderStates() []OrderState {
+	return []OrderState{StateCreated, StatePaid, StateInTransit, StateDelivered, StateCancelled, StatePartiallyReturned}
+}
+
+// Description возвращает описание состояния для людей.
+func (s OrderState) Description() string {
+	return orderStateDescriptions[s]
+}
+
+// State выводит состояние заказа из статусов товаров. Отмененные товары не
+// влияют на состояние, пока в заказе есть другие; заказ без товаров или
+// только с неизвестными статусами считается созданным.
+func (o *OrderDetails) State() OrderState {
+	var active, cancelled, returned, paid, shipped, delivered int
+	for _, item := range o.Products {
+		switch item.Status {
+		case StatusCancelled:
+			cancelled++
+			continue
+		case StatusReturnRequested, StatusReturned:
+			returned++
+		case StatusDelivered:
+			delivered++
+			shipped++
+			paid++
+		case StatusInTransit, StatusReadyForPickup:
+			shipped++
+			paid++
+		case StatusPaid, StatusAssembling:
+			paid++
+		}
+		active++
+	}
+
+	switch {
+	case returned > 0:
+		return StatePartiallyReturned
+	case active == 0 && cancelled > 0:
+		return StateCancelled
+	case active > 0 && delivered == active:
+		return StateDelivered
+	case shipped > 0:
+		return StateInTransit
+	case active > 0 && paid == active:
+		return StatePaid
+	default:
+		return StateCreated
+	}
+}
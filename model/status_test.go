@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to ItemStatus
+		legal    bool
+	}{
+		{"next step", StatusCreated, StatusPaid, true},
+		{"same status", StatusInTransit, StatusInTransit, true},
+		{"same unknown status", 999, 999, true},
+		{"skipped steps", StatusCreated, StatusDelivered, false},
+		{"backwards", StatusDelivered, StatusPaid, false},
+		{"from final status", StatusCancelled, StatusPaid, false},
+		{"to unknown status", StatusPaid, 999, false},
+		{"from unknown status", 999, StatusPaid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTransition(tt.from, tt.to)
+			if tt.legal && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.legal && !errors.Is(err, ErrIllegalTransition) {
+				t.Errorf("error %v, want ErrIllegalTransition", err)
+			}
+		})
+	}
+}
+
+func TestValidateOrderTransition(t *testing.T) {
+	order := func(items ...ProductItem) *OrderDetails {
+		return &OrderDetails{OrderID: "o1", Products: items}
+	}
+	item := func(chartID int, status ItemStatus) ProductItem {
+		return ProductItem{ChartID: chartID, Status: status}
+	}
+
+	tests := []struct {
+		name           string
+		previous, next *OrderDetails
+		legal          bool
+	}{
+		{"statuses advance", order(item(1, StatusPaid), item(2, StatusPaid)), order(item(1, StatusAssembling), item(2, StatusCancelled)), true},
+		{"unchanged", order(item(1, StatusDelivered)), order(item(1, StatusDelivered)), true},
+		{"new item in initial status", order(item(1, StatusPaid)), order(item(1, StatusPaid), item(2, StatusCreated)), true},
+		{"illegal jump", order(item(1, StatusCreated)), order(item(1, StatusDelivered)), false},
+		{"new item in later status", order(item(1, StatusPaid)), order(item(1, StatusPaid), item(2, StatusDelivered)), false},
+		{"removed item", order(item(1, StatusPaid), item(2, StatusPaid)), order(item(1, StatusPaid)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOrderTransition(tt.previous, tt.next)
+			var validationErr *ValidationError
+			switch {
+			case tt.legal && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case !tt.legal && !errors.As(err, &validationErr):
+				t.Errorf("error %v, want a ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsUnknownStatus(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "schema", "v2", "minor_units.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := ParseOrder(data, MaxItems)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+
+	order.Products[0].Status = 999
+	if err := order.Validate(); err != nil {
+		t.Fatalf("order with an unknown status rejected: %v", err)
+	}
+	if name := order.Products[0].Status.Name(); name != "unknown" {
+		t.Errorf("status name %q, want unknown", name)
+	}
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// iso8601Layouts принимаемые форматы времени: RFC 3339 с долями секунды и
+// без них, а также время без часового пояса (считается UTC).
+var iso8601Layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// ParseISO8601 разбирает время в одном из форматов ISO 8601, которые
+// присылают производители заказов.
+func ParseISO8601(value string) (time.Time, error) {
+	for _, layout := range iso8601Layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time format: %s", value)
+}
+
+// ISO8601Time момент времени в формате ISO 8601. Нулевое значение означает
+// отсутствие времени: null в JSON и NULL в базе данных.
+type ISO8601Time time.Time
+
+// IsZero сообщает, что время не задано.
+func (t ISO8601Time) IsZero() bool {
+	return time.Time(t).IsZero()
+}
+
+func (t *ISO8601Time) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = ISO8601Time{}
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("invalid time format: %s", data)
+	}
+	if str == "" {
+		*t = ISO8601Time{}
+		return nil
+	}
+	parsedTime, err := ParseISO8601(str)
+	if err != nil {
+		return err
+	}
+	*t = ISO8601Time(parsedTime)
+	return nil
+}
+
+func (t ISO8601Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(time.Time(t).Format(time.RFC3339Nano))
+}
+
+// Value сохраняет время в базу, нулевое значение — как NULL.
+func (t ISO8601Time) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return time.Time(t), nil
+}
+
+// Scan читает время из базы, NULL — как нулевое значение.
+func (t *ISO8601Time) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*t = ISO8601Time{}
+	case time.Time:
+		*t = ISO8601Time(value)
+	default:
+		return fmt.Errorf("cannot scan %T into ISO8601Time", src)
+	}
+	return nil
+}
+
+// UnixTime момент времени, который в JSON передается числом секунд Unix;
+// строка ISO 8601 также принимается. Нулевое значение означает отсутствие
+// времени: 0 или null в JSON (выводится null) и NULL в базе данных.
+type UnixTime time.Time
+
+// IsZero сообщает, что время не задано.
+func (t UnixTime) IsZero() bool {
+	return time.Time(t).IsZero()
+}
+
+// Unix возвращает число секунд Unix, 0 для нулевого значения.
+func (t UnixTime) Unix() int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return time.Time(t).Unix()
+}
+
+// UnixTimeFromSeconds создает UnixTime из секунд Unix; 0 — отсутствие времени.
+func UnixTimeFromSeconds(seconds int64) UnixTime {
+	if seconds == 0 {
+		return UnixTime{}
+	}
+	return UnixTime(time.Unix(seconds, 0).UTC())
+}
+
+func (t *UnixTime) UnmarshalJSON(data []byte) error {
+	switch {
+	case string(data) == "null":
+		*t = UnixTime{}
+		return nil
+	case bytes.HasPrefix(data, []byte(`"`)):
+		var iso ISO8601Time
+		if err := iso.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		*t = UnixTime(iso)
+		return nil
+	}
+
+	seconds, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Unix time: %s", data)
+	}
+	*t = UnixTimeFromSeconds(seconds)
+	return nil
+}
+
+func (t UnixTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return strconv.AppendInt(nil, t.Unix(), 10), nil
+}
+
+// Value сохраняет время в базу, нулевое значение — как NULL.
+func (t UnixTime) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return time.Time(t), nil
+}
+
+// Scan читает время из базы, NULL — как нулевое значение.
+func (t *UnixTime) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*t = UnixTime{}
+	case time.Time:
+		*t = UnixTime(value)
+	case int64:
+		*t = UnixTimeFromSeconds(value)
+	default:
+		return fmt.Errorf("cannot scan %T into UnixTime", src)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxItems максимальное количество товаров в одном заказе.
+const MaxItems = 100
+
+// ValidationError содержит все нарушения, найденные в заказе.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid order: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate проверяет обязательные поля и числовые ограничения заказа.
+func (o *OrderDetails) Validate() error {
+	var problems []string
+	require := func(field, value string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, field+" is required")
+		}
+	}
+	nonNegative := func(field string, value Money) {
+		if value < 0 {
+			problems = append(problems, field+" must not be negative")
+		}
+	}
+
+	require("order_uid", o.OrderID)
+	require("track_number", o.TrackingNumber)
+	require("entry", o.EntryPoint)
+	require("customer_id", o.CustomerID)
+	require("delivery.name", o.Address.FullName)
+	require("delivery.phone", o.Address.Phone)
+	require("delivery.city", o.Address.City)
+	require("delivery.address", o.Address.Street)
+	require("payment.transaction", o.Payment.TransactionID)
+	require("payment.provider", o.Payment.Provider)
+
+	if len(o.Payment.Currency) != 3 || strings.ToUpper(o.Payment.Currency) != o.Payment.Currency {
+		problems = append(problems, "payment.currency must be a 3-letter ISO 4217 code")
+	} else if _, err := CurrencyExponent(o.Payment.Currency); err != nil {
+		problems = append(problems, "payment.currency must be a known ISO 4217 code")
+	}
+	nonNegative("payment.amount", o.Payment.Amount)
+	nonNegative("payment.delivery_cost", o.Payment.DeliveryCost)
+	nonNegative("payment.goods_total", o.Payment.TotalGoods)
+	nonNegative("payment.custom_fee", o.Payment.CustomFee)
+
+	if o.CreationTimestamp.IsZero() {
+		problems = append(problems, "date_created is required")
+	}
+
+	switch {
+	case len(o.Products) == 0:
+		problems = append(problems, "items must not be empty")
+	case len(o.Products) > MaxItems:
+		problems = append(problems, fmt.Sprintf("items must not contain more than %d entries", MaxItems))
+	}
+	for i, item := range o.Products {
+		prefix := fmt.Sprintf("items[%d].", i)
+		require(prefix+"track_number", item.TrackingNum)
+		require(prefix+"name", item.Name)
+		nonNegative(prefix+"price", item.Price)
+		nonNegative(prefix+"total_price", item.TotalPrice)
+		if item.Discount < 0 || item.Discount > 100 {
+			problems = append(problems, prefix+"sale must be between 0 and 100")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// SchemaVersion текущая версия схемы JSON-сообщения с заказом.
+	//
+	// Версия 2: суммы указываются в минимальных единицах валюты.
+	SchemaVersion = 2
+	// SchemaVersionField поле сообщения с версией схемы. Сообщения без этого
+	// поля считаются версией 1 — форматом, существовавшим до введения версий.
+	SchemaVersionField = "schema_version"
+)
+
+// ErrUnsupportedSchemaVersion возвращается для сообщений неизвестной версии,
+// в том числе более новой, чем понимает сервис.
+var ErrUnsupportedSchemaVersion = errors.New("unsupported order schema version")
+
+// Upcaster переводит сообщение из версии from в версию from+1. Сообщение
+// передается разобранным в map: преобразование не должно зависеть от
+// текущей структуры OrderDetails, которая меняется вместе со схемой.
+type Upcaster func(payload map[string]any) error
+
+// upcasters преобразования по исходной версии. Для каждой версии ниже
+// SchemaVersion должно быть зарегистрировано преобразование, а в
+// testdata/schema — эталонные сообщения (проверяются go test ./model).
+var upcasters = map[int]Upcaster{
+	1: upcastMajorToMinorUnits,
+}
+
+// downcasters обратные преобразования из версии from+1 в версию from. Нужны
+// для подписи: заказ подписывается в той версии, в которой отправлен.
+var downcasters = map[int]Upcaster{
+	1: downcastMinorToMajorUnits,
+}
+
+// versionedOrder заказ вместе с полем версии: поле разрешено в сообщении,
+// но не входит в OrderDetails.
+type versionedOrder struct {
+	SchemaVersion *int `json:"schema_version,omitempty"`
+	*OrderDetails
+}
+
+// MarshalOrder сериализует заказ в JSON текущей версии схемы с полем
+// schema_version. Используется для сообщений, которые будут разобраны ParseOrder.
+func MarshalOrder(order *OrderDetails) ([]byte, error) {
+	version := SchemaVersion
+	return json.Marshal(versionedOrder{SchemaVersion: &version, OrderDetails: order})
+}
+
+// MarshalOrderVersion сериализует заказ в JSON версии схемы version. Сообщение
+// версии 1 не содержит поля schema_version. Ошибка возвращается, если заказ
+// не представим в этой версии (например, сумма с дробной частью в версии 1).
+func MarshalOrderVersion(order *OrderDetails, version int) ([]byte, error) {
+	if version < 1 || version > SchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, version)
+	}
+	data, err := MarshalOrder(order)
+	if err != nil || version == SchemaVersion {
+		return data, err
+	}
+
+	var payload map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+	for from := SchemaVersion - 1; from >= version; from-- {
+		downcaster, ok := downcasters[from]
+		if !ok {
+			return nil, fmt.Errorf("%w: no downcaster to version %d", ErrUnsupportedSchemaVersion, from)
+		}
+		if err := downcaster(payload); err != nil {
+			return nil, fmt.Errorf("failed to downcast order to schema version %d: %w", from, err)
+		}
+	}
+	if version == 1 {
+		delete(payload, SchemaVersionField)
+	} else {
+		payload[SchemaVersionField] = version
+	}
+	return json.Marshal(payload)
+}
+
+// schemaVersionOf возвращает версию схемы сообщения. Для сообщения, которое
+// не является JSON-объектом, возвращается SchemaVersion: ошибку структуры
+// сообщит строгий разбор.
+func schemaVersionOf(data []byte) (int, error) {
+	var envelope struct {
+		SchemaVersion json.RawMessage `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return SchemaVersion, nil
+	}
+	if envelope.SchemaVersion == nil || string(envelope.SchemaVersion) == "null" {
+		return 1, nil
+	}
+
+	version, err := strconv.Atoi(string(envelope.SchemaVersion))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer", ErrUnsupportedSchemaVersion, SchemaVersionField)
+	}
+	if version > SchemaVersion {
+		return 0, fmt.Errorf("%w: %d is newer than supported %d", ErrUnsupportedSchemaVersion, version, SchemaVersion)
+	}
+	return version, nil
+}
+
+// upcast переводит сообщение версии version в текущую версию схемы.
+func upcast(data []byte, version int) ([]byte, error) {
+	var payload map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Числа сохраняются как есть, чтобы не терять точность больших значений
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid JSON structure: %w", err)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		upcaster, ok := upcasters[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no upcaster from version %d", ErrUnsupportedSchemaVersion, version)
+		}
+		if err := upcaster(payload); err != nil {
+			return nil, fmt.Errorf("failed to upcast order from schema version %d: %w", version, err)
+		}
+	}
+	payload[SchemaVersionField] = SchemaVersion
+	return json.Marshal(payload)
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update перезаписывает эталоны текущим результатом: go test ./model -update.
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// rejectedDir каталог сообщений, которые должны быть отклонены.
+const rejectedDir = "rejected"
+
+// TestParseOrderGolden проверяет разбор сообщений всех версий схемы по
+// эталонам. Каталог testdata/schema/vN содержит сообщения версии N
+// (name.json) и ожидаемый результат ParseOrder в текущей версии
+// (name.golden), каталог rejected — сообщения, которые должны быть
+// отклонены, и текст ошибки.
+func TestParseOrderGolden(t *testing.T) {
+	dirs := []string{rejectedDir}
+	for version := 1; version <= SchemaVersion; version++ {
+		dirs = append(dirs, fmt.Sprintf("v%d", version))
+	}
+
+	for _, dir := range dirs {
+		cases, err := filepath.Glob(filepath.Join("testdata", "schema", dir, "*.json"))
+		if err != nil || len(cases) == 0 {
+			t.Errorf("no messages in testdata/schema/%s", dir)
+			continue
+		}
+
+		for _, path := range cases {
+			name := dir + "/" + strings.TrimSuffix(filepath.Base(path), ".json")
+			t.Run(name, func(t *testing.T) {
+				input, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, parseErr := parseGolden(input)
+				switch wantError := dir == rejectedDir; {
+				case wantError && parseErr == nil:
+					t.Fatalf("message must be rejected")
+				case !wantError && parseErr != nil:
+					t.Fatalf("unexpected error: %v", parseErr)
+				}
+
+				goldenPath := strings.TrimSuffix(path, ".json") + ".golden"
+				if *update {
+					if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := os.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("output differs from %s:\n%s", goldenPath, got)
+				}
+			})
+		}
+	}
+}
+
+// parseGolden возвращает заказ в текущей версии схемы или текст ошибки.
+func parseGolden(input []byte) ([]byte, error) {
+	order, err := ParseOrder(input, MaxItems)
+	if err != nil {
+		return []byte("error: " + err.Error() + "\n"), err
+	}
+	data, err := MarshalOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
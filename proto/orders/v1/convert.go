@@ -0,0 +1,173 @@
+package ordersv1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromModel переводит заказ в сообщение Order текущей версии схемы.
+func FromModel(order *model.OrderDetails) *Order {
+	items := make([]*Item, 0, len(order.Products))
+	for _, item := range order.Products {
+		items = append(items, &Item{
+			ChrtId:      int64(item.ChartID),
+			TrackNumber: item.TrackingNum,
+			Price:       int64(item.Price),
+			Rid:         item.RID,
+			Name:        item.Name,
+			Sale:        int32(item.Discount),
+			Size:        item.Size,
+			TotalPrice:  int64(item.TotalPrice),
+			NmId:        int64(item.ProductID),
+			Brand:       item.Brand,
+			Status:      int32(item.Status),
+		})
+	}
+
+	return &Order{
+		SchemaVersion: model.SchemaVersion,
+		OrderUid:      order.OrderID,
+		TrackNumber:   order.TrackingNumber,
+		Entry:         order.EntryPoint,
+		Delivery: &Delivery{
+			Name:    order.Address.FullName,
+			Phone:   order.Address.Phone,
+			Zip:     order.Address.ZipCode,
+			City:    order.Address.City,
+			Address: order.Address.Street,
+			Region:  order.Address.Region,
+			Email:   order.Address.Email,
+		},
+		Payment: &Payment{
+			Transaction:  order.Payment.TransactionID,
+			RequestId:    order.Payment.RequestID,
+			Currency:     order.Payment.Currency,
+			Provider:     order.Payment.Provider,
+			Amount:       int64(order.Payment.Amount),
+			PaymentDt:    order.Payment.PaymentDate.Unix(),
+			Bank:         order.Payment.Bank,
+			DeliveryCost: int64(order.Payment.DeliveryCost),
+			GoodsTotal:   int64(order.Payment.TotalGoods),
+			CustomFee:    int64(order.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            order.Locale,
+		InternalSignature: order.Signature,
+		CustomerId:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.ShardKey,
+		SmId:              int64(order.SMID),
+		DateCreated:       timestamp(order.CreationTimestamp),
+		OofShard:          order.OutOfShard,
+	}
+}
+
+// timestamp переводит время в Timestamp; отсутствующее время не передается.
+func timestamp(t model.ISO8601Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(time.Time(t))
+}
+
+// ToModel переводит сообщение Order в заказ. Сообщения версии 1 (без
+// schema_version) переводятся в текущую, как JSON-сообщения в
+// model.ParseOrder; сообщения неизвестных версий отклоняются с
+// model.ErrUnsupportedSchemaVersion. Отсутствующие вложенные сообщения дают
+// пустые доставку и оплату, проверка заказа — забота вызывающего.
+func (o *Order) ToModel() (*model.OrderDetails, error) {
+	version := int(o.GetSchemaVersion())
+	if version == 0 {
+		version = 1
+	}
+	if version < 1 || version > model.SchemaVersion {
+		return nil, fmt.Errorf("%w: %d", model.ErrUnsupportedSchemaVersion, version)
+	}
+
+	var products []model.ProductItem
+	for _, item := range o.GetItems() {
+		products = append(products, model.ProductItem{
+			ChartID:     int(item.GetChrtId()),
+			TrackingNum: item.GetTrackNumber(),
+			Price:       model.Money(item.GetPrice()),
+			RID:         item.GetRid(),
+			Name:        item.GetName(),
+			Discount:    int(item.GetSale()),
+			Size:        item.GetSize(),
+			TotalPrice:  model.Money(item.GetTotalPrice()),
+			ProductID:   int(item.GetNmId()),
+			Brand:       item.GetBrand(),
+			Status:      model.ItemStatus(item.GetStatus()),
+		})
+	}
+
+	order := &model.OrderDetails{
+		OrderID:        o.GetOrderUid(),
+		TrackingNumber: o.GetTrackNumber(),
+		EntryPoint:     o.GetEntry(),
+		Address: model.AddressDetails{
+			FullName: o.GetDelivery().GetName(),
+			Phone:    o.GetDelivery().GetPhone(),
+			ZipCode:  o.GetDelivery().GetZip(),
+			City:     o.GetDelivery().GetCity(),
+			Street:   o.GetDelivery().GetAddress(),
+			Region:   o.GetDelivery().GetRegion(),
+			Email:    o.GetDelivery().GetEmail(),
+		},
+		Payment: model.PaymentDetails{
+			TransactionID: o.GetPayment().GetTransaction(),
+			RequestID:     o.GetPayment().GetRequestId(),
+			Currency:      o.GetPayment().GetCurrency(),
+			Provider:      o.GetPayment().GetProvider(),
+			Amount:        model.Money(o.GetPayment().GetAmount()),
+			PaymentDate:   model.UnixTimeFromSeconds(o.GetPayment().GetPaymentDt()),
+			Bank:          o.GetPayment().GetBank(),
+			DeliveryCost:  model.Money(o.GetPayment().GetDeliveryCost()),
+			TotalGoods:    model.Money(o.GetPayment().GetGoodsTotal()),
+			CustomFee:     model.Money(o.GetPayment().GetCustomFee()),
+		},
+		Products:        products,
+		Locale:          o.GetLocale(),
+		Signature:       o.GetInternalSignature(),
+		CustomerID:      o.GetCustomerId(),
+		DeliveryService: o.GetDeliveryService(),
+		ShardKey:        o.GetShardkey(),
+		SMID:            int(o.GetSmId()),
+		OutOfShard:      o.GetOofShard(),
+	}
+	if o.GetDateCreated() != nil {
+		order.CreationTimestamp = model.ISO8601Time(o.GetDateCreated().AsTime())
+	}
+
+	if version == 1 {
+		if err := upcastMajorToMinorUnits(order); err != nil {
+			return nil, fmt.Errorf("failed to upcast order from schema version 1: %w", err)
+		}
+	}
+	order.SchemaVersion = version
+	return order, nil
+}
+
+// upcastMajorToMinorUnits переводит суммы сообщения версии 1 из основных
+// единиц валюты в минимальные.
+func upcastMajorToMinorUnits(order *model.OrderDetails) error {
+	amounts := []*model.Money{
+		&order.Payment.Amount, &order.Payment.DeliveryCost,
+		&order.Payment.TotalGoods, &order.Payment.CustomFee,
+	}
+	for i := range order.Products {
+		amounts = append(amounts, &order.Products[i].Price, &order.Products[i].TotalPrice)
+	}
+
+	for _, amount := range amounts {
+		minor, err := model.MoneyFromMajor(int64(*amount), order.Payment.Currency)
+		if err != nil {
+			return err
+		}
+		*amount = minor
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/fake"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// runGenerate отправляет сгенерированные заказы с заданной скоростью и
+// возвращает код завершения.
+func runGenerate(ctx context.Context, cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	var producerFlags producerFlags
+	producerFlags.register(flags, cfg.Kafka)
+	var (
+		count       = flags.Int("n", 100, "number of orders")
+		rate        = flags.Float64("rate", 0, "target orders per second, 0 for no limit")
+		concurrency = flags.Int("concurrency", 4, "number of concurrent senders")
+		seed        = flags.Int64("seed", 1, "generator seed; the same seed gives the same orders")
+		customers   = flags.Int("customers", 0, "number of distinct customers (default 1000)")
+		corruptRate = flags.Float64("corrupt-rate", 0, "share of orders to corrupt, from 0 to 1")
+		corruptions = flags.String("corrupt", "", "comma-separated corruptions for -corrupt-rate (default all)")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := newLogger()
+	if *count <= 0 || *concurrency <= 0 || *rate < 0 || *corruptRate < 0 || *corruptRate > 1 {
+		logger.Error("Invalid flags: -n and -concurrency must be positive, -rate non-negative, -corrupt-rate from 0 to 1")
+		return 2
+	}
+
+	opts := []fake.Option{fake.WithCustomers(*customers)}
+	if *corruptRate > 0 {
+		selected := fake.Corruptions()
+		if *corruptions != "" {
+			selected = nil
+			for _, name := range strings.Split(*corruptions, ",") {
+				c, err := fake.ParseCorruption(strings.TrimSpace(name))
+				if err != nil {
+					logger.Error("Invalid -corrupt", slog.Any("error", err))
+					return 2
+				}
+				selected = append(selected, c)
+			}
+		}
+		opts = append(opts, fake.WithCorruption(*corruptRate, selected...))
+	}
+	generator := fake.NewGenerator(*seed, opts...)
+
+	producer, err := producerFlags.producer(logger)
+	if err != nil {
+		logger.Error("Failed to create Kafka producer", slog.Any("error", err))
+		return 2
+	}
+
+	// Заказы создает одна горутина (генератор не потокобезопасен) по
+	// расписанию -rate, отправляют -concurrency горутин
+	orders := make(chan *model.OrderDetails, *concurrency)
+	var sent, failed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for order := range orders {
+				if err := producer.SendOrder(ctx, order); err != nil {
+					failed.Add(1)
+					logger.Error("Order not sent", slog.String("orderID", order.OrderID), slog.Any("error", err))
+					continue
+				}
+				sent.Add(1)
+			}
+		}()
+	}
+
+	start := time.Now()
+	var interval time.Duration
+	if *rate > 0 {
+		interval = time.Duration(float64(time.Second) / *rate)
+	}
+generate:
+	for i := 0; i < *count; i++ {
+		// Отставание от расписания догоняется без пауз
+		if sleep(ctx, time.Until(start.Add(time.Duration(i)*interval))) != nil {
+			break
+		}
+		select {
+		case orders <- generator.Order():
+		case <-ctx.Done():
+			break generate
+		}
+	}
+	close(orders)
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	logger.Info("Generate finished",
+		slog.Int64("sent", sent.Load()),
+		slog.Int64("failed", failed.Load()),
+		slog.Duration("elapsed", elapsed),
+		slog.String("ordersPerSecond", formatRate(int(sent.Load()), elapsed.Seconds())),
+		slog.Bool("dryRun", producerFlags.dryRun))
+	if ctx.Err() != nil {
+		return 1
+	}
+	return exitCode(int(failed.Load()))
+}
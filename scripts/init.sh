@@ -2,7 +2,8 @@
 # Создание базы данных
 psql -U $POSTGRES_USER -c 'CREATE DATABASE wb_tech;'
 
-# Создание таблиц и добавление данных
+# Создание таблиц и добавление данных. Суммы хранятся в минимальных единицах
+# валюты оплаты; базу, созданную до этого, переводит migrate-money.sql
 psql -v ON_ERROR_STOP=1 -U $POSTGRES_USER -d wb_tech <<-EOSQL
   CREATE TABLE delivery (
     id SERIAL PRIMARY KEY,
@@ -20,23 +21,23 @@ psql -v ON_ERROR_STOP=1 -U $POSTGRES_USER -d wb_tech <<-EOSQL
     request_id TEXT NOT NULL,
     currency TEXT NOT NULL,
     provider TEXT NOT NULL,
-    amount INTEGER NOT NULL,
-    payment_dt INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    payment_dt TIMESTAMPTZ,
     bank TEXT NOT NULL,
-    delivery_cost INTEGER NOT NULL,
-    goods_total INTEGER NOT NULL,
-    custom_fee INTEGER NOT NULL
+    delivery_cost BIGINT NOT NULL,
+    goods_total BIGINT NOT NULL,
+    custom_fee BIGINT NOT NULL
   );
 
   CREATE TABLE items (
     chrt_id INTEGER PRIMARY KEY,
     track_number TEXT NOT NULL,
-    price INTEGER NOT NULL,
+    price BIGINT NOT NULL,
     rid TEXT NOT NULL,
     name TEXT NOT NULL,
     sale INTEGER NOT NULL,
     size TEXT NOT NULL,
-    total_price INTEGER NOT NULL,
+    total_price BIGINT NOT NULL,
     nm_id INTEGER NOT NULL,
     brand TEXT NOT NULL,
     status INTEGER NOT NULL
@@ -60,6 +61,8 @@ psql -v ON_ERROR_STOP=1 -U $POSTGRES_USER -d wb_tech <<-EOSQL
     FOREIGN KEY (payment_id) REFERENCES payment(transaction)
   );
 
+  CREATE INDEX orders_customer_idx ON orders (customer_id, date_created DESC);
+
   CREATE TABLE order_item_conn (
     order_uid TEXT NOT NULL,
     chrt_id INTEGER NOT NULL,
@@ -67,4 +70,12 @@ psql -v ON_ERROR_STOP=1 -U $POSTGRES_USER -d wb_tech <<-EOSQL
     FOREIGN KEY (order_uid) REFERENCES orders(order_uid),
     FOREIGN KEY (chrt_id) REFERENCES items(chrt_id)
   );
+
+  CREATE TABLE api_keys (
+    key_hash TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+  );
 EOSQL
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/kafka"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// record запись входного потока: заказ и время для replay.
+type record struct {
+	// source файл и номер записи для журнала
+	source string
+	order  *model.OrderDetails
+	// time время сообщения в дампе или date_created заказа
+	time time.Time
+}
+
+// dumpRecord строка дампа, который печатает -dry-run и читает replay.
+// Value — заказ в JSON или, для двоичных форматов, строка base64.
+type dumpRecord struct {
+	Time    time.Time         `json:"time"`
+	Key     string            `json:"key,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Value   json.RawMessage   `json:"value"`
+}
+
+// requestLine строка в формате requests.jsonl: заказ в поле body, объектом
+// или строкой с JSON.
+type requestLine struct {
+	RequestID string          `json:"request_id"`
+	Title     string          `json:"title"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// binaryDecoders двоичные форматы сообщений дампа по заголовку content-type;
+// Avro без реестра схем не разбирается.
+var binaryDecoders = map[string]kafka.Codec{
+	kafka.ContentTypeProtobuf: kafka.NewProtobufCodec(),
+}
+
+// readRecords читает записи из файлов (или stdin, если файлов нет либо имя
+// "-") и передает их в handle по порядку. Каждый файл — поток JSON-значений:
+// заказ, массив заказов, строки NDJSON, requests.jsonl или дамп -dry-run.
+// Ошибка разбора одной записи передается в handle и не прерывает чтение.
+func readRecords(paths []string, handle func(record, error) error) error {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	for _, path := range paths {
+		if err := readFile(path, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(path string, handle func(record, error) error) error {
+	var input io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input, name = file, path
+	}
+
+	decoder := json.NewDecoder(input)
+	n := 0
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			// После синтаксической ошибки поток дальше не читается
+			return fmt.Errorf("%s: invalid JSON after record %d: %w", name, n, err)
+		}
+
+		values := []json.RawMessage{value}
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+			values = nil
+			if err := json.Unmarshal(value, &values); err != nil {
+				return fmt.Errorf("%s: invalid JSON array: %w", name, err)
+			}
+		}
+		for _, value := range values {
+			n++
+			rec, err := parseRecord(value)
+			rec.source = fmt.Sprintf("%s#%d", name, n)
+			if err := handle(rec, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseRecord разбирает запись любого поддерживаемого вида.
+func parseRecord(value json.RawMessage) (record, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return record{}, fmt.Errorf("record is not a JSON object: %w", err)
+	}
+
+	_, isOrder := fields["order_uid"]
+	switch {
+	case !isOrder && fields["value"] != nil:
+		var dump dumpRecord
+		if err := json.Unmarshal(value, &dump); err != nil {
+			return record{}, fmt.Errorf("invalid dump record: %w", err)
+		}
+		order, err := decodeDumpValue(dump)
+		return record{order: order, time: dump.Time}, err
+	case !isOrder && fields["body"] != nil:
+		var request requestLine
+		if err := json.Unmarshal(value, &request); err != nil {
+			return record{}, fmt.Errorf("invalid request line: %w", err)
+		}
+		body := []byte(request.Body)
+		var text string
+		if json.Unmarshal(request.Body, &text) == nil {
+			body = []byte(text)
+		}
+		order, err := model.ParseOrder(body, model.MaxItems)
+		if err != nil {
+			return record{}, fmt.Errorf("request %s: %w", request.RequestID, err)
+		}
+		return record{order: order, time: time.Time(order.CreationTimestamp)}, nil
+	}
+
+	order, err := model.ParseOrder(value, model.MaxItems)
+	if err != nil {
+		return record{}, err
+	}
+	return record{order: order, time: time.Time(order.CreationTimestamp)}, nil
+}
+
+// decodeDumpValue разбирает заказ из записи дампа по ее content-type.
+func decodeDumpValue(dump dumpRecord) (*model.OrderDetails, error) {
+	contentType := dump.Headers["content-type"]
+	if contentType == "" || contentType == kafka.ContentTypeJSON {
+		return model.ParseOrder(dump.Value, model.MaxItems)
+	}
+
+	codec, ok := binaryDecoders[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content-type %q in dump", contentType)
+	}
+	var encoded string
+	if err := json.Unmarshal(dump.Value, &encoded); err != nil {
+		return nil, fmt.Errorf("dump value of %s must be a base64 string", contentType)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 dump value: %w", err)
+	}
+	return codec.Decode(context.Background(), data)
+}
+
+// dumpWriter реализует kafka.MessageWriter для -dry-run: печатает сообщения
+// строками дампа вместо отправки.
+type dumpWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newDumpWriter(w io.Writer) *dumpWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &dumpWriter{encoder: encoder}
+}
+
+func (w *dumpWriter) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, msg := range msgs {
+		dump := dumpRecord{Time: msg.Time, Key: string(msg.Key), Headers: make(map[string]string, len(msg.Headers))}
+		if dump.Time.IsZero() {
+			dump.Time = time.Now().UTC()
+		}
+		for _, header := range msg.Headers {
+			dump.Headers[header.Key] = string(header.Value)
+		}
+		if contentType := dump.Headers["content-type"]; contentType == "" || contentType == kafka.ContentTypeJSON {
+			dump.Value = msg.Value
+		} else {
+			encoded, _ := json.Marshal(base64.StdEncoding.EncodeToString(msg.Value))
+			dump.Value = encoded
+		}
+		if err := w.encoder.Encode(dump); err != nil {
+			return err
+		}
+	}
+	return nil
+}
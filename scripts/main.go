@@ -1,112 +1,211 @@
-// Пакет для внесения заказа в БД
+// Команда для отправки заказов в Kafka: из файлов (send), сгенерированных
+// (generate) и с повтором сохраненного потока (replay).
+//
+//	go run ./scripts send orders.json
+//	go run ./scripts generate -n 1000 -rate 50 -concurrency 8
+//	go run ./scripts replay -speed 2 dump.jsonl
+//
+// Параметры брокера по умолчанию берутся из окружения (.env): KAFKA_URL,
+// KAFKA_TOPIC, KAFKA_PARTITION, KAFKA_CODEC, ORDER_SIGNING_KEYSET.
 package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
 	"time"
 
-	"log/slog"
-
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
 	"github.com/Sh1ni-Gami/WB_Tech_L0/kafka"
 	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/signing"
 	"github.com/joho/godotenv"
 )
 
-// getEnv возвращает значение переменной окружения или значение по умолчанию.
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
-	}
-	return fallback
-}
+const usage = `Usage: scripts <command> [flags] [files]
+
+Commands:
+  send      send orders from files or stdin (JSON, JSON array, NDJSON or requests.jsonl)
+  generate  send generated orders at a target rate
+  replay    resend a dump preserving the relative timing of its records
+
+Run "scripts <command> -h" for the command flags.
+`
 
 func main() {
-	// Загружаем переменные окружения.
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file: %v\n", err)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
 
-	// Создаем контекст с тайм-аутом.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	commands := map[string]func(ctx context.Context, cfg *config.Config, args []string) int{
+		"send":     runSend,
+		"generate": runGenerate,
+		"replay":   runReplay,
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
 
-	// Получаем параметры Kafka из переменных окружения.
-	kafkaPartition, err := strconv.Atoi(getEnv("KAFKA_PARTITION", "0"))
+	// Файл настроек и переменные окружения задают значения флагов по умолчанию
+	_ = godotenv.Load()
+	cfg, err := config.NewLoader(config.WithHostDefaults()).Load()
 	if err != nil {
-		log.Fatalf("Invalid Kafka partition: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
 	}
-	kafkaTopic := getEnv("KAFKA_TOPIC", "wb-topic")
-	kafkaURL := getEnv("KAFKA_URL", "localhost:9094")
 
-	// Логгер.
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := command(ctx, cfg, os.Args[2:])
+	stop()
+	os.Exit(code)
+}
 
-	// Создаем Kafka сервис.
-	kafkaService, err := kafka.NewKafkaService(kafkaTopic, kafkaURL, strconv.Itoa(kafkaPartition), logger, nil)
+// producerFlags общие флаги отправки заказов.
+type producerFlags struct {
+	broker    string
+	topic     string
+	partition string
+	codec     string
+	registry  string
+	schemaID  int
+	key       string
+	headers   []string
+	keyset    string
+	dryRun    bool
+	verbose   bool
+}
+
+// register добавляет общие флаги в набор команды; значения по умолчанию
+// берутся из настроек Kafka.
+func (p *producerFlags) register(flags *flag.FlagSet, cfg config.KafkaConfig) {
+	flags.StringVar(&p.broker, "broker", cfg.URL, "Kafka broker address")
+	flags.StringVar(&p.topic, "topic", cfg.Topic, "Kafka topic")
+	flags.StringVar(&p.partition, "partition", strconv.Itoa(cfg.Partition), "Kafka partition of the service reader")
+	flags.StringVar(&p.codec, "codec", cfg.Codec, "message format: json, protobuf or avro")
+	flags.StringVar(&p.registry, "schema-registry", cfg.SchemaRegistry, "Schema Registry URL or schema directory for avro")
+	flags.IntVar(&p.schemaID, "avro-schema-id", cfg.AvroSchemaID, "Avro schema id in the registry to write with")
+	flags.StringVar(&p.key, "key", "none", "message key: none, order_uid, customer_id or track_number")
+	flags.Func("H", "extra message header `key=value` (repeatable)", func(value string) error {
+		if name, _, ok := strings.Cut(value, "="); !ok || name == "" {
+			return fmt.Errorf("header must be key=value")
+		}
+		p.headers = append(p.headers, value)
+		return nil
+	})
+	flags.StringVar(&p.keyset, "keyset", cfg.SigningKeyset, "signing keyset file; orders are signed with its active key")
+	flags.BoolVar(&p.dryRun, "dry-run", false, "print messages to stdout as a dump instead of sending them")
+	flags.BoolVar(&p.verbose, "v", false, "log every sent order")
+}
+
+// messageKeys ключи сообщений по флагу -key.
+var messageKeys = map[string]kafka.KeyFunc{
+	"order_uid":    func(o *model.OrderDetails) []byte { return []byte(o.OrderID) },
+	"customer_id":  func(o *model.OrderDetails) []byte { return []byte(o.CustomerID) },
+	"track_number": func(o *model.OrderDetails) []byte { return []byte(o.TrackingNumber) },
+}
+
+// producer создает KafkaService для отправки заказов по общим флагам. Журнал
+// пишется в stderr, чтобы не смешиваться с выводом -dry-run.
+func (p *producerFlags) producer(logger *slog.Logger) (kafka.KafkaService, error) {
+	codec, err := p.producerCodec()
 	if err != nil {
-		log.Fatalf("Failed to create Kafka service: %v\n", err)
+		return nil, err
 	}
+	opts := []kafka.Option{kafka.WithProducerCodec(codec)}
 
-	// Создаем данные для отправки.
-	order := &model.OrderDetails{
-		OrderID:        "b563feb7b2b84b6test",
-		TrackingNumber: "WBILMTESTTRACK",
-		EntryPoint:     "WBIL",
-		Address: model.AddressDetails{
-			FullName: "Test Testov",
-			Phone:    "+9720000000",
-			ZipCode:  "2639809",
-			City:     "Kiryat Mozkin",
-			Street:   "Ploshad Mira 15",
-			Region:   "Kraiot",
-			Email:    "test@gmail.com",
-		},
-		Payment: model.PaymentDetails{
-			TransactionID: "b563feb7b2b84b6test",
-			RequestID:     "",
-			Currency:      "USD",
-			Provider:      "wbpay",
-			Amount:        1817,
-			PaymentDate:   1637907727,
-			Bank:          "alpha",
-			DeliveryCost:  1500,
-			TotalGoods:    317,
-			CustomFee:     0,
-		},
-		Products: []model.ProductItem{
-			{
-				ChartID:     9934930,
-				TrackingNum: "WBILMTESTTRACK",
-				Price:       453,
-				RID:         "ab4219087a764ae0btest",
-				Name:        "Mascaras",
-				Discount:    30,
-				Size:        "0",
-				TotalPrice:  317,
-				ProductID:   2389212,
-				Brand:       "Vivienne Sabo",
-				Status:      202,
-			},
-		},
-		Locale:            "en",
-		Signature:         "",
-		CustomerID:        "test",
-		DeliveryService:   "meest",
-		ShardKey:          "9",
-		SMID:              99,
-		CreationTimestamp: model.ISO8601Time(time.Date(2021, time.November, 26, 6, 22, 19, 0, time.UTC)),
-		OutOfShard:        "1",
+	if p.key != "none" {
+		key, ok := messageKeys[p.key]
+		if !ok {
+			return nil, fmt.Errorf("invalid -key %q: must be none, order_uid, customer_id or track_number", p.key)
+		}
+		opts = append(opts, kafka.WithKey(key))
+	}
+	for _, header := range p.headers {
+		name, value, _ := strings.Cut(header, "=")
+		opts = append(opts, kafka.WithHeader(name, value))
+	}
+	if p.keyset != "" {
+		keyset, err := signing.NewFileKeyset(p.keyset, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing keyset: %w", err)
+		}
+		opts = append(opts, kafka.WithSigner(keyset))
+	}
+	if p.dryRun {
+		opts = append(opts, kafka.WithWriter(newDumpWriter(os.Stdout)))
 	}
 
-	// Отправляем заказ в Kafka.
-	err = kafkaService.SendOrder(ctx, order)
-	if err != nil {
-		log.Fatalf("Failed to send order to Kafka: %v\n", err)
+	// Без -v отправка каждого заказа не журналируется
+	serviceLogger := logger
+	if !p.verbose {
+		serviceLogger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
 	}
+	return kafka.NewKafkaService(p.topic, p.broker, p.partition, serviceLogger, nil, opts...)
+}
+
+// producerCodec выбирает формат отправки по флагу -codec.
+func (p *producerFlags) producerCodec() (kafka.Codec, error) {
+	switch p.codec {
+	case "json":
+		return kafka.NewJSONCodec(), nil
+	case "protobuf":
+		return kafka.NewProtobufCodec(), nil
+	case "avro":
+		if p.registry == "" || p.schemaID <= 0 {
+			return nil, fmt.Errorf("-codec avro requires -schema-registry and -avro-schema-id")
+		}
+		registry, err := kafka.NewSchemaRegistry(p.registry)
+		if err != nil {
+			return nil, err
+		}
+		return kafka.NewAvroCodec(registry, p.schemaID), nil
+	}
+	return nil, fmt.Errorf("invalid -codec %q: must be json, protobuf or avro", p.codec)
+}
+
+// newLogger журнал команды в stderr.
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stderr, nil))
+}
 
-	logger.Info("Order sent successfully", slog.String("orderID", order.OrderID))
+// exitCode код завершения по числу неудачных отправок.
+func exitCode(failed int) int {
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// formatRate форматирует скорость отправки для журнала.
+func formatRate(count int, seconds float64) string {
+	if seconds <= 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(count)/seconds, 'f', 1, 64)
+}
+
+// sleep ждет d или отмены контекста.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,53 @@
+-- Перевод базы, созданной до хранения сумм в минимальных единицах валюты:
+-- суммы умножаются на 10^exponent валюты оплаты (ISO 4217), payment_dt из
+-- секунд Unix становится TIMESTAMPTZ (0 — NULL). Запускается один раз:
+-- psql -v ON_ERROR_STOP=1 -d wb_tech -f migrate-money.sql
+BEGIN;
+
+CREATE TEMPORARY TABLE currency_factor (currency TEXT PRIMARY KEY, factor BIGINT NOT NULL) ON COMMIT DROP;
+INSERT INTO currency_factor VALUES
+  ('BIF', 1), ('CLP', 1), ('DJF', 1), ('GNF', 1), ('ISK', 1), ('JPY', 1), ('KMF', 1), ('KRW', 1),
+  ('PYG', 1), ('RWF', 1), ('UGX', 1), ('UYI', 1), ('VND', 1), ('VUV', 1), ('XAF', 1), ('XOF', 1),
+  ('XPF', 1),
+  ('BHD', 1000), ('IQD', 1000), ('JOD', 1000), ('KWD', 1000), ('LYD', 1000), ('OMR', 1000),
+  ('TND', 1000),
+  ('CLF', 10000), ('UYW', 10000);
+
+ALTER TABLE payment
+  ALTER COLUMN amount TYPE BIGINT,
+  ALTER COLUMN delivery_cost TYPE BIGINT,
+  ALTER COLUMN goods_total TYPE BIGINT,
+  ALTER COLUMN custom_fee TYPE BIGINT,
+  ALTER COLUMN payment_dt DROP NOT NULL,
+  ALTER COLUMN payment_dt TYPE TIMESTAMPTZ
+    USING CASE WHEN payment_dt = 0 THEN NULL ELSE to_timestamp(payment_dt) END;
+ALTER TABLE items
+  ALTER COLUMN price TYPE BIGINT,
+  ALTER COLUMN total_price TYPE BIGINT;
+
+UPDATE payment p SET
+  amount = amount * f.factor,
+  delivery_cost = delivery_cost * f.factor,
+  goods_total = goods_total * f.factor,
+  custom_fee = custom_fee * f.factor
+FROM (
+  SELECT p2.transaction, COALESCE(c.factor, 100) AS factor
+  FROM payment p2 LEFT JOIN currency_factor c ON c.currency = upper(p2.currency)
+) f
+WHERE f.transaction = p.transaction;
+
+-- Товар может входить в несколько заказов; валюта берется из любого из них
+UPDATE items i SET
+  price = price * f.factor,
+  total_price = total_price * f.factor
+FROM (
+  SELECT DISTINCT ON (conn.chrt_id) conn.chrt_id, COALESCE(c.factor, 100) AS factor
+  FROM order_item_conn conn
+  JOIN orders o ON o.order_uid = conn.order_uid
+  JOIN payment p ON p.transaction = o.payment_id
+  LEFT JOIN currency_factor c ON c.currency = upper(p.currency)
+  ORDER BY conn.chrt_id
+) f
+WHERE f.chrt_id = i.chrt_id;
+
+COMMIT;
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
+)
+
+// runReplay повторно отправляет записи дампа, сохраняя интервалы между ними,
+// и возвращает код завершения. Время записи — поле time дампа -dry-run или
+// date_created заказа, поэтому подходит и выгрузка NDJSON.
+func runReplay(ctx context.Context, cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	var producerFlags producerFlags
+	producerFlags.register(flags, cfg.Kafka)
+	var (
+		speed   = flags.Float64("speed", 1, "replay speed multiplier; 0 sends without pauses")
+		maxWait = flags.Duration("max-gap", 0, "cap on a single pause between records, 0 for no cap")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := newLogger()
+	if *speed < 0 || *maxWait < 0 {
+		logger.Error("Invalid flags: -speed and -max-gap must be non-negative")
+		return 2
+	}
+	producer, err := producerFlags.producer(logger)
+	if err != nil {
+		logger.Error("Failed to create Kafka producer", slog.Any("error", err))
+		return 2
+	}
+
+	// Запись отправляется в момент start + (время записи - время первой)/speed;
+	// записи со временем раньше предыдущей отправляются сразу
+	var first, previous time.Time
+	start := time.Now()
+	sent, failed := 0, 0
+	err = readRecords(flags.Args(), func(rec record, err error) error {
+		if err != nil {
+			failed++
+			logger.Error("Record skipped", slog.String("source", rec.source), slog.Any("error", err))
+			return nil
+		}
+
+		if *speed > 0 && !rec.time.IsZero() {
+			if first.IsZero() {
+				first, previous = rec.time, rec.time
+			}
+			if gap := rec.time.Sub(previous); *maxWait > 0 && gap > *maxWait {
+				// Длинная пауза сокращается, следующие интервалы сохраняются
+				first = first.Add(gap - *maxWait)
+			}
+			if rec.time.After(previous) {
+				previous = rec.time
+			}
+			offset := time.Duration(float64(rec.time.Sub(first)) / *speed)
+			if err := sleep(ctx, time.Until(start.Add(offset))); err != nil {
+				return err
+			}
+		}
+
+		if err := producer.SendOrder(ctx, rec.order); err != nil {
+			failed++
+			logger.Error("Order not sent", slog.String("source", rec.source), slog.Any("error", err))
+			return ctx.Err()
+		}
+		sent++
+		return nil
+	})
+	if err != nil {
+		logger.Error("Replay interrupted", slog.Any("error", err))
+		failed++
+	}
+
+	logger.Info("Replay finished",
+		slog.Int("sent", sent),
+		slog.Int("failed", failed),
+		slog.Duration("elapsed", time.Since(start)),
+		slog.Bool("dryRun", producerFlags.dryRun))
+	return exitCode(failed)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
+)
+
+// runSend отправляет заказы из файлов или stdin и возвращает код завершения.
+func runSend(ctx context.Context, cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	var producerFlags producerFlags
+	producerFlags.register(flags, cfg.Kafka)
+	validate := flags.Bool("validate", true, "skip orders that fail model validation; disable to send invalid orders on purpose")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := newLogger()
+	producer, err := producerFlags.producer(logger)
+	if err != nil {
+		logger.Error("Failed to create Kafka producer", slog.Any("error", err))
+		return 2
+	}
+
+	start := time.Now()
+	sent, failed := 0, 0
+	err = readRecords(flags.Args(), func(rec record, err error) error {
+		if err == nil && *validate {
+			err = rec.order.Validate()
+		}
+		if err == nil {
+			err = producer.SendOrder(ctx, rec.order)
+		}
+		if err != nil {
+			failed++
+			logger.Error("Order not sent", slog.String("source", rec.source), slog.Any("error", err))
+			return ctx.Err()
+		}
+		sent++
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to read orders", slog.Any("error", err))
+		failed++
+	}
+
+	logger.Info("Send finished",
+		slog.Int("sent", sent),
+		slog.Int("failed", failed),
+		slog.Duration("elapsed", time.Since(start)),
+		slog.Bool("dryRun", producerFlags.dryRun))
+	return exitCode(failed)
+}
@@ -0,0 +1,65 @@
+// Package signing подписывает заказы и проверяет подпись internal_signature.
+//
+// Подписывается каноническое JSON-представление заказа (см. Canonical)
+// ключом из набора ключей: HMAC-SHA256 с общим секретом или Ed25519.
+// Подпись записывается в internal_signature в виде "<key id>:<подпись в
+// base64url>", поэтому ключи можно менять, не теряя возможности проверить
+// заказы, подписанные прежним ключом.
+package signing
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// Canonical возвращает каноническое JSON-представление заказа для подписи:
+// заказ в версии схемы version (с полем schema_version, начиная с версии 2)
+// без internal_signature, ключи объектов по алфавиту, без пробелов и
+// HTML-экранирования. date_created приводится к UTC с точностью до
+// миллисекунды, чтобы подпись не зависела от формата сообщения (Avro хранит
+// миллисекунды).
+//
+// Версия — та, в которой сообщение отправлено: подпись, поставленная в
+// версии 1, проверяется по представлению версии 1 и не ломается при
+// переходе на новую версию схемы.
+func Canonical(order *model.OrderDetails, version int) ([]byte, error) {
+	unsigned := *order
+	unsigned.Signature = ""
+	if !unsigned.CreationTimestamp.IsZero() {
+		created := time.Time(unsigned.CreationTimestamp).UTC().Truncate(time.Millisecond)
+		unsigned.CreationTimestamp = model.ISO8601Time(created)
+	}
+
+	data, err := model.MarshalOrderVersion(&unsigned, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Повторное кодирование через map упорядочивает ключи; числа сохраняются как есть
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// sentVersion возвращает версию схемы, в которой получен заказ; заказ без
+// версии (из Protobuf или созданный в коде) считается текущей версией.
+func sentVersion(order *model.OrderDetails) int {
+	if order.SchemaVersion == 0 {
+		return model.SchemaVersion
+	}
+	return order.SchemaVersion
+}
@@ -0,0 +1,133 @@
+package signing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// v1Message заказ версии 1 из эталонов model: суммы в основных единицах,
+// без schema_version.
+func v1Message(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "model", "testdata", "schema", "v1", "unversioned.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newTestKeyset(t *testing.T) *fileKeyset {
+	t.Helper()
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	path := filepath.Join(t.TempDir(), "keyset.json")
+	data := `{"active_key": "k1", "keys": [{"id": "k1", "algorithm": "hmac-sha256", "secret": "` + secret + `"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keyset, err := NewFileKeyset(path, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewFileKeyset: %v", err)
+	}
+	return keyset.(*fileKeyset)
+}
+
+func TestCanonicalUsesSentVersion(t *testing.T) {
+	message := v1Message(t)
+	order, err := model.ParseOrder(message, model.MaxItems)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+
+	// Представление версии 1 совпадает с исходным сообщением
+	var original map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	if err := decoder.Decode(&original); err != nil {
+		t.Fatal(err)
+	}
+	original["internal_signature"] = ""
+	want, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Canonical(order, 1)
+	if err != nil {
+		t.Fatalf("Canonical: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("version 1 canonical form differs from the message:\n got %s\nwant %s", got, want)
+	}
+
+	current, err := Canonical(order, model.SchemaVersion)
+	if err != nil {
+		t.Fatalf("Canonical: %v", err)
+	}
+	if bytes.Equal(current, got) {
+		t.Errorf("canonical forms of versions 1 and %d must differ", model.SchemaVersion)
+	}
+}
+
+func TestVerifyOrderSignedInSentVersion(t *testing.T) {
+	keyset := newTestKeyset(t)
+	unsigned, err := model.ParseOrder(v1Message(t), model.MaxItems)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+
+	// Производитель версии 1 подписывает сообщение в своей версии
+	canonical, err := Canonical(unsigned, 1)
+	if err != nil {
+		t.Fatalf("Canonical: %v", err)
+	}
+	signature, err := keyset.current().active.sign(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message map[string]any
+	if err := json.Unmarshal(v1Message(t), &message); err != nil {
+		t.Fatal(err)
+	}
+	message["internal_signature"] = "k1:" + base64.RawURLEncoding.EncodeToString(signature)
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := model.ParseOrder(data, model.MaxItems)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+	if err := keyset.Verify(order); err != nil {
+		t.Errorf("order signed as version 1: %v", err)
+	}
+	order.SchemaVersion = model.SchemaVersion
+	if err := keyset.Verify(order); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verified as version %d: error %v, want ErrInvalidSignature", model.SchemaVersion, err)
+	}
+
+	// Заказ, подписанный сервисом, отправляется и проверяется в текущей версии
+	order.Signature, err = keyset.Sign(order)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	sent, err := model.MarshalOrder(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, err := model.ParseOrder(sent, model.MaxItems)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+	if err := keyset.Verify(received); err != nil {
+		t.Errorf("order signed by the service: %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// Алгоритмы подписи.
+const (
+	AlgorithmHMACSHA256 = "hmac-sha256"
+	AlgorithmEd25519    = "ed25519"
+)
+
+// reloadInterval как часто проверяется изменение файла набора ключей.
+const reloadInterval = 10 * time.Second
+
+var (
+	// ErrUnsigned заказ без подписи.
+	ErrUnsigned = errors.New("order is not signed")
+	// ErrUnknownKey подпись сделана ключом, которого нет в наборе.
+	ErrUnknownKey = errors.New("order is signed with an unknown key")
+	// ErrInvalidSignature подпись не соответствует заказу.
+	ErrInvalidSignature = errors.New("invalid order signature")
+)
+
+// Signer подписывает заказы активным ключом набора.
+type Signer interface {
+	// Sign возвращает подпись заказа для internal_signature; заказ не меняется.
+	// Подписывается представление текущей версии схемы, в которой заказ
+	// будет отправлен.
+	Sign(order *model.OrderDetails) (string, error)
+}
+
+// Verifier проверяет подпись заказа.
+type Verifier interface {
+	// Verify возвращает ErrUnsigned, ErrUnknownKey или ErrInvalidSignature,
+	// если заказ не подписан ключом набора. Подпись проверяется по
+	// представлению версии схемы, в которой заказ получен.
+	Verify(order *model.OrderDetails) error
+}
+
+// Keyset набор ключей, которым подписываются и проверяются заказы.
+type Keyset interface {
+	Signer
+	Verifier
+	// ActiveKey возвращает идентификатор ключа для подписи; пустая строка —
+	// набор только для проверки.
+	ActiveKey() string
+}
+
+// keysetFile формат файла набора ключей:
+//
+//	{
+//	  "active_key": "2024-06",
+//	  "keys": [
+//	    {"id": "2024-01", "algorithm": "hmac-sha256", "secret": "<base64>"},
+//	    {"id": "2024-06", "algorithm": "ed25519", "public_key": "<base64>", "private_key": "<base64>"}
+//	  ]
+//	}
+//
+// Для проверки Ed25519 достаточно открытого ключа, закрытый нужен только
+// для подписи активным ключом. При смене ключа новый ключ добавляется и
+// становится активным, прежний остается, пока есть подписанные им заказы.
+type keysetFile struct {
+	ActiveKey string    `json:"active_key"`
+	Keys      []keySpec `json:"keys"`
+}
+
+type keySpec struct {
+	ID         string `json:"id"`
+	Algorithm  string `json:"algorithm"`
+	Secret     string `json:"secret,omitempty"`
+	PublicKey  string `json:"public_key,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+}
+
+// key ключ набора.
+type key struct {
+	id         string
+	algorithm  string
+	secret     []byte
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+}
+
+func (k *key) sign(message []byte) ([]byte, error) {
+	switch k.algorithm {
+	case AlgorithmHMACSHA256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(message)
+		return mac.Sum(nil), nil
+	case AlgorithmEd25519:
+		if k.privateKey == nil {
+			return nil, fmt.Errorf("key %q has no private key", k.id)
+		}
+		return ed25519.Sign(k.privateKey, message), nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q", k.algorithm)
+}
+
+func (k *key) verify(message, signature []byte) bool {
+	switch k.algorithm {
+	case AlgorithmHMACSHA256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(message)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case AlgorithmEd25519:
+		return ed25519.Verify(k.publicKey, message, signature)
+	}
+	return false
+}
+
+// keys разобранный набор ключей.
+type keys struct {
+	active *key
+	byID   map[string]*key
+}
+
+// fileKeyset реализует Keyset поверх файла, перечитывая его при изменении.
+type fileKeyset struct {
+	path   string
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	keys      *keys
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+// NewFileKeyset загружает набор ключей из JSON-файла. Файл перечитывается
+// при изменении (проверка не чаще раза в 10 секунд), поэтому ключи меняются
+// без перезапуска; если новый файл некорректен, остается прежний набор.
+func NewFileKeyset(path string, logger *slog.Logger) (Keyset, error) {
+	k := &fileKeyset{path: path, logger: logger}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyset: %w", err)
+	}
+	if err := k.load(info); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *fileKeyset) Sign(order *model.OrderDetails) (string, error) {
+	current := k.current()
+	if current.active == nil {
+		return "", errors.New("keyset has no active key")
+	}
+
+	// Отправляемые заказы кодируются в текущей версии схемы
+	message, err := Canonical(order, model.SchemaVersion)
+	if err != nil {
+		return "", err
+	}
+	signature, err := current.active.sign(message)
+	if err != nil {
+		return "", err
+	}
+	return current.active.id + ":" + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (k *fileKeyset) ActiveKey() string {
+	if active := k.current().active; active != nil {
+		return active.id
+	}
+	return ""
+}
+
+func (k *fileKeyset) Verify(order *model.OrderDetails) error {
+	if order.Signature == "" {
+		return ErrUnsigned
+	}
+	keyID, encoded, ok := strings.Cut(order.Signature, ":")
+	if !ok {
+		return fmt.Errorf("%w: expected <key id>:<signature>", ErrInvalidSignature)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not base64url", ErrInvalidSignature)
+	}
+
+	verifier, ok := k.current().byID[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	message, err := Canonical(order, sentVersion(order))
+	if err != nil {
+		return err
+	}
+	if !verifier.verify(message, signature) {
+		return fmt.Errorf("%w: key %q", ErrInvalidSignature, keyID)
+	}
+	return nil
+}
+
+// current возвращает набор ключей, перечитав файл, если он изменился.
+func (k *fileKeyset) current() *keys {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if time.Since(k.checkedAt) < reloadInterval {
+		return k.keys
+	}
+	k.checkedAt = time.Now()
+
+	info, err := os.Stat(k.path)
+	if err != nil {
+		k.logger.Error("Failed to check signing keyset, keeping previous keys", slog.String("path", k.path), slog.Any("error", err))
+		return k.keys
+	}
+	if info.ModTime().Equal(k.modTime) && info.Size() == k.size {
+		return k.keys
+	}
+	if err := k.load(info); err != nil {
+		k.logger.Error("Failed to reload signing keyset, keeping previous keys", slog.String("path", k.path), slog.Any("error", err))
+		return k.keys
+	}
+	k.logger.Info("Signing keyset reloaded", slog.String("path", k.path), slog.Int("keys", len(k.keys.byID)))
+	return k.keys
+}
+
+// load читает и разбирает файл набора ключей.
+func (k *fileKeyset) load(info os.FileInfo) error {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to read keyset: %w", err)
+	}
+	parsed, err := parseKeyset(data)
+	if err != nil {
+		return fmt.Errorf("invalid keyset %s: %w", k.path, err)
+	}
+
+	k.keys = parsed
+	k.modTime = info.ModTime()
+	k.size = info.Size()
+	k.checkedAt = time.Now()
+	return nil
+}
+
+// parseKeyset разбирает набор ключей и проверяет все ключи сразу.
+func parseKeyset(data []byte) (*keys, error) {
+	var file keysetFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Keys) == 0 {
+		return nil, errors.New("no keys")
+	}
+
+	var problems []error
+	parsed := &keys{byID: make(map[string]*key, len(file.Keys))}
+	for _, spec := range file.Keys {
+		k, err := parseKey(spec)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("key %q: %w", spec.ID, err))
+			continue
+		}
+		if _, exists := parsed.byID[k.id]; exists {
+			problems = append(problems, fmt.Errorf("key %q: duplicate id", k.id))
+			continue
+		}
+		parsed.byID[k.id] = k
+	}
+
+	if file.ActiveKey != "" {
+		active, ok := parsed.byID[file.ActiveKey]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Errorf("active key %q is not in the keyset", file.ActiveKey))
+		case active.algorithm == AlgorithmEd25519 && active.privateKey == nil:
+			problems = append(problems, fmt.Errorf("active key %q has no private key", file.ActiveKey))
+		default:
+			parsed.active = active
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return parsed, nil
+}
+
+func parseKey(spec keySpec) (*key, error) {
+	if spec.ID == "" || strings.Contains(spec.ID, ":") {
+		return nil, errors.New("id must be non-empty and must not contain ':'")
+	}
+	k := &key{id: spec.ID, algorithm: spec.Algorithm}
+
+	switch spec.Algorithm {
+	case AlgorithmHMACSHA256:
+		secret, err := base64.StdEncoding.DecodeString(spec.Secret)
+		if err != nil || len(secret) < 32 {
+			return nil, errors.New("secret must be at least 32 bytes in base64")
+		}
+		k.secret = secret
+	case AlgorithmEd25519:
+		if spec.PrivateKey != "" {
+			private, err := base64.StdEncoding.DecodeString(spec.PrivateKey)
+			switch {
+			case err != nil:
+				return nil, errors.New("private_key must be base64")
+			case len(private) == ed25519.SeedSize:
+				k.privateKey = ed25519.NewKeyFromSeed(private)
+			case len(private) == ed25519.PrivateKeySize:
+				k.privateKey = ed25519.PrivateKey(private)
+			default:
+				return nil, errors.New("private_key must be a 32-byte seed or a 64-byte key")
+			}
+			k.publicKey = k.privateKey.Public().(ed25519.PublicKey)
+		}
+		if spec.PublicKey != "" {
+			public, err := base64.StdEncoding.DecodeString(spec.PublicKey)
+			if err != nil || len(public) != ed25519.PublicKeySize {
+				return nil, errors.New("public_key must be a 32-byte key in base64")
+			}
+			if k.publicKey != nil && !k.publicKey.Equal(ed25519.PublicKey(public)) {
+				return nil, errors.New("public_key does not match private_key")
+			}
+			k.publicKey = public
+		}
+		if k.publicKey == nil {
+			return nil, errors.New("public_key or private_key is required")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q: must be %s or %s", spec.Algorithm, AlgorithmHMACSHA256, AlgorithmEd25519)
+	}
+	return k, nil
+}
@@ -0,0 +1,131 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+)
+
+// defaultWarmupLimit количество заказов для прогрева, если limit не указан.
+const defaultWarmupLimit = 1024
+
+// evictResponse ответ POST /api/v1/admin/cache/evict.
+type evictResponse struct {
+	Evicted int `json:"evicted"`
+}
+
+// flushResponse ответ POST /api/v1/admin/cache/flush.
+type flushResponse struct {
+	Flushed bool `json:"flushed"`
+}
+
+// warmupResponse ответ POST /api/v1/admin/cache/warmup.
+type warmupResponse struct {
+	Loaded int `json:"loaded"`
+}
+
+// CacheAdmin интерфейс административных операций с кэшем.
+type CacheAdmin interface {
+	Evict(ctx context.Context, orderUID string) error
+	EvictCustomer(ctx context.Context, customerID string) (int, error)
+	Flush(ctx context.Context) error
+	Warmup(ctx context.Context, limit int) (int, error)
+	Stats() ristrettocache.CacheStats
+	Verify(ctx context.Context, repair bool) (*ristrettocache.VerifyReport, error)
+}
+
+// cacheEvictHandler удаляет из кэша заказ по order_uid или все заказы покупателя по customer_id.
+func (t *httpTransport) cacheEvictHandler(w http.ResponseWriter, r *http.Request) {
+	orderUID := r.URL.Query().Get("order_uid")
+	customerID := r.URL.Query().Get("customer_id")
+
+	switch {
+	case orderUID != "" && customerID == "":
+		if err := t.admin.Evict(r.Context(), orderUID); err != nil {
+			t.log(r).Error("Failed to evict order", slog.String("orderUID", orderUID), slog.Any("error", err))
+			http.Error(w, "Failed to evict order", http.StatusInternalServerError)
+			return
+		}
+		t.writeJSON(w, evictResponse{Evicted: 1})
+	case customerID != "" && orderUID == "":
+		count, err := t.admin.EvictCustomer(r.Context(), customerID)
+		if err != nil {
+			t.log(r).Error("Failed to evict customer orders", slog.String("customerID", customerID), slog.Any("error", err))
+			http.Error(w, "Failed to evict customer orders", http.StatusInternalServerError)
+			return
+		}
+		t.writeJSON(w, evictResponse{Evicted: count})
+	default:
+		http.Error(w, "Exactly one of order_uid or customer_id is required", http.StatusBadRequest)
+	}
+}
+
+// cacheFlushHandler полностью очищает кэш.
+func (t *httpTransport) cacheFlushHandler(w http.ResponseWriter, r *http.Request) {
+	if err := t.admin.Flush(r.Context()); err != nil {
+		t.log(r).Error("Failed to flush cache", slog.Any("error", err))
+		http.Error(w, "Failed to flush cache", http.StatusInternalServerError)
+		return
+	}
+	t.writeJSON(w, flushResponse{Flushed: true})
+}
+
+// cacheWarmupHandler повторно прогревает кэш последними заказами.
+func (t *httpTransport) cacheWarmupHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultWarmupLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	loaded, err := t.admin.Warmup(r.Context(), limit)
+	if err != nil {
+		t.log(r).Error("Failed to warm up cache", slog.Any("error", err))
+		http.Error(w, "Failed to warm up cache", http.StatusInternalServerError)
+		return
+	}
+	t.writeJSON(w, warmupResponse{Loaded: loaded})
+}
+
+// cacheStatsHandler возвращает статистику кэша.
+func (t *httpTransport) cacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	t.writeJSON(w, t.admin.Stats())
+}
+
+// cacheVerifyHandler сверяет кэш с базой; repair=true удаляет из кэша
+// расходящиеся записи.
+func (t *httpTransport) cacheVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	repair := false
+	if raw := r.URL.Query().Get("repair"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "Invalid repair", http.StatusBadRequest)
+			return
+		}
+		repair = parsed
+	}
+
+	report, err := t.admin.Verify(r.Context(), repair)
+	if err != nil {
+		t.log(r).Error("Failed to verify cache", slog.Any("error", err))
+		http.Error(w, "Failed to verify cache", http.StatusInternalServerError)
+		return
+	}
+	t.writeJSON(w, report)
+}
+
+// writeJSON кодирует ответ в JSON.
+func (t *httpTransport) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.logger.Error("Failed to encode response to JSON", slog.Any("error", err))
+	}
+}
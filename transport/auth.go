@@ -0,0 +1,257 @@
+package httptransport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// Области доступа (scopes), которые требуют маршруты.
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+	ScopeOrdersAdmin = "orders:admin"
+	// ScopeOrdersExport массовая выгрузка заказов.
+	ScopeOrdersExport = "orders:export"
+)
+
+// ErrNoCredentials возвращается аутентификатором, если в запросе нет данных
+// для его схемы — тогда пробуется следующий аутентификатор.
+var ErrNoCredentials = errors.New("no credentials")
+
+// errInvalidAPIKey возвращается для неизвестного или отозванного ключа.
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// ErrAuthUnavailable возвращается, если учетные данные не удалось проверить,
+// например хранилище ключей недоступно. Клиент получает 503, а не 401.
+var ErrAuthUnavailable = errors.New("authentication unavailable")
+
+// Principal аутентифицированный клиент.
+type Principal struct {
+	Subject string
+	Method  string // "api_key", "jwt" или "anonymous"
+	Scopes  []string
+}
+
+// HasScope проверяет, выдана ли клиенту область доступа.
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator определяет клиента по запросу.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// APIKeyStore хранилище API-ключей. Ключи хранятся в виде SHA-256 (hex);
+// для неизвестного ключа возвращается model.ErrAPIKeyNotFound, любая другая
+// ошибка означает, что хранилище недоступно.
+type APIKeyStore interface {
+	LookupAPIKey(ctx context.Context, keyHash string) (name string, scopes []string, err error)
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext возвращает клиента, сохраненного middleware авторизации.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// WithAuthentication включает проверку доступа. Запросы без учетных данных
+// получают anonymousScopes.
+func WithAuthentication(anonymousScopes []string, authenticators ...Authenticator) Option {
+	return func(t *httpTransport) {
+		t.authenticators = authenticators
+		t.anonymousScopes = anonymousScopes
+	}
+}
+
+// authEnabled включена ли проверка доступа.
+func (t *httpTransport) authEnabled() bool {
+	return len(t.authenticators) > 0
+}
+
+// authorize пропускает запрос, только если клиенту выдана область scope.
+// Аутентифицированный клиент дополнительно ограничивается по частоте запросов.
+// Отказы записываются в журнал аудита.
+func (t *httpTransport) authorize(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !t.authEnabled() {
+			next(w, r)
+			return
+		}
+
+		principal, err := t.authenticate(r)
+		if errors.Is(err, ErrAuthUnavailable) {
+			t.log(r).Error("Failed to authenticate request", slog.String("path", r.URL.Path), slog.Any("error", err))
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			t.auditDenied(r, nil, scope, err.Error())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orders"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !t.allowPrincipal(w, r, principal) {
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			t.auditDenied(r, principal, scope, "missing scope")
+			if principal.Method == "anonymous" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="orders"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	}
+}
+
+// authenticate определяет клиента запроса.
+func (t *httpTransport) authenticate(r *http.Request) (*Principal, error) {
+	return AuthenticateRequest(r, t.anonymousScopes, t.authenticators)
+}
+
+// AuthenticateRequest перебирает аутентификаторы до первого, узнавшего
+// учетные данные; ключ, которого нет в одном хранилище, ищется в следующих.
+// Запрос без учетных данных получает anonymousScopes. Используется и gRPC
+// API, чтобы проверка доступа была одинаковой.
+func AuthenticateRequest(r *http.Request, anonymousScopes []string, authenticators []Authenticator) (*Principal, error) {
+	var unknownKey error
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(r)
+		switch {
+		case errors.Is(err, ErrNoCredentials):
+			continue
+		case errors.Is(err, errInvalidAPIKey):
+			unknownKey = err
+			continue
+		}
+		return principal, err
+	}
+	if unknownKey != nil {
+		return nil, unknownKey
+	}
+
+	return &Principal{Subject: "anonymous", Method: "anonymous", Scopes: anonymousScopes}, nil
+}
+
+// auditDenied пишет в журнал отказ в доступе.
+func (t *httpTransport) auditDenied(r *http.Request, principal *Principal, scope, reason string) {
+	attrs := []any{
+		slog.Bool("audit", true),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("remote", r.RemoteAddr),
+		slog.String("required_scope", scope),
+		slog.String("reason", reason),
+	}
+	if principal != nil {
+		attrs = append(attrs, slog.String("subject", principal.Subject), slog.String("auth_method", principal.Method))
+	}
+	t.log(r).Warn("Access denied", attrs...)
+}
+
+// apiKeyAuthenticator проверяет API-ключи из заголовка X-API-Key или
+// Authorization (схемы ApiKey и Bearer, если токен не похож на JWT).
+type apiKeyAuthenticator struct {
+	store APIKeyStore
+}
+
+// NewAPIKeyAuthenticator создает аутентификатор по API-ключам.
+func NewAPIKeyAuthenticator(store APIKeyStore) Authenticator {
+	return &apiKeyAuthenticator{store: store}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		switch {
+		case strings.EqualFold(scheme, "ApiKey"):
+			key = value
+		case strings.EqualFold(scheme, "Bearer") && !looksLikeJWT(value):
+			key = value
+		}
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	name, scopes, err := a.store.LookupAPIKey(r.Context(), HashAPIKey(key))
+	if errors.Is(err, model.ErrAPIKeyNotFound) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAuthUnavailable, err)
+	}
+	return &Principal{Subject: name, Method: "api_key", Scopes: scopes}, nil
+}
+
+// HashAPIKey возвращает SHA-256 ключа в hex — в таком виде ключи хранятся.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// looksLikeJWT проверяет, состоит ли токен из трех частей, разделенных точками.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// StaticAPIKeys хранилище API-ключей из конфигурации: хэш ключа -> владелец.
+type StaticAPIKeys map[string]StaticAPIKey
+
+// StaticAPIKey владелец и области доступа ключа из конфигурации.
+type StaticAPIKey struct {
+	Name   string
+	Scopes []string
+}
+
+// ParseStaticAPIKeys разбирает ключи в формате
+// "name:sha256hex:scope1,scope2;name2:sha256hex:scope3".
+func ParseStaticAPIKeys(spec string) (StaticAPIKeys, error) {
+	keys := make(StaticAPIKeys)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid API key entry %q: expected name:hash:scopes", entry)
+		}
+		// Области доступа сами содержат двоеточие, поэтому собираем хвост обратно
+		name, hash, scopes := parts[0], strings.ToLower(parts[1]), strings.Join(parts[2:], ":")
+		if len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid API key entry %q: hash must be hex SHA-256", entry)
+		}
+		keys[hash] = StaticAPIKey{Name: name, Scopes: strings.Split(scopes, ",")}
+	}
+	return keys, nil
+}
+
+// LookupAPIKey реализует APIKeyStore.
+func (k StaticAPIKeys) LookupAPIKey(_ context.Context, keyHash string) (string, []string, error) {
+	key, ok := k[keyHash]
+	if !ok {
+		return "", nil, model.ErrAPIKeyNotFound
+	}
+	return key.Name, key.Scopes, nil
+}
@@ -0,0 +1,71 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// stubKeyStore хранилище с одним ключом; err, если задана, возвращается
+// вместо поиска.
+type stubKeyStore struct {
+	hash string
+	err  error
+}
+
+func (s stubKeyStore) LookupAPIKey(_ context.Context, keyHash string) (string, []string, error) {
+	if s.err != nil {
+		return "", nil, s.err
+	}
+	if keyHash != s.hash {
+		return "", nil, model.ErrAPIKeyNotFound
+	}
+	return "reader", []string{ScopeOrdersRead}, nil
+}
+
+func TestAuthorizeStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		stores []APIKeyStore
+		key    string
+		want   int
+	}{
+		{"known key", []APIKeyStore{stubKeyStore{hash: HashAPIKey("secret")}}, "secret", http.StatusOK},
+		{"unknown key", []APIKeyStore{stubKeyStore{hash: HashAPIKey("secret")}}, "other", http.StatusUnauthorized},
+		{"store unavailable", []APIKeyStore{stubKeyStore{err: errors.New("connection refused")}}, "secret", http.StatusServiceUnavailable},
+		{
+			"key in second store",
+			[]APIKeyStore{stubKeyStore{hash: HashAPIKey("static")}, stubKeyStore{hash: HashAPIKey("secret")}},
+			"secret", http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var authenticators []Authenticator
+			for _, store := range tt.stores {
+				authenticators = append(authenticators, NewAPIKeyAuthenticator(store))
+			}
+			transport := &httpTransport{
+				logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+				authenticators: authenticators,
+			}
+			handler := transport.authorize(ScopeOrdersRead, func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/o1", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package httptransport
+
+import (
+	"net/http"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// currencyCatalog ответ GET /api/v1/currencies.
+type currencyCatalog struct {
+	// Exponents число знаков после запятой у каждой известной валюты ISO 4217:
+	// сумма в основных единицах равна Money / 10^exponent
+	Exponents map[string]int `json:"exponents"`
+}
+
+// currenciesHandler возвращает показатели минимальных единиц валют, чтобы
+// клиенты не держали свою копию таблицы ISO 4217.
+func (t *httpTransport) currenciesHandler(w http.ResponseWriter, r *http.Request) {
+	catalog := currencyCatalog{Exponents: make(map[string]int)}
+	for _, code := range model.Currencies() {
+		exponent, err := model.CurrencyExponent(code)
+		if err != nil {
+			continue
+		}
+		catalog.Exponents[code] = exponent
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	t.writeJSON(w, catalog)
+}
@@ -0,0 +1,96 @@
+package httptransport
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+)
+
+// EncodedStore хранилище, умеющее отдавать заранее сериализованные заказы.
+type EncodedStore interface {
+	GetEncodedOrder(ctx context.Context, orderUID string) (*ristrettocache.EncodedOrder, error)
+}
+
+// gzipETagSuffix отличает ETag gzip-представления от ETag исходного JSON:
+// у разных представлений должны быть разные сильные валидаторы (RFC 9110, 8.8.3).
+const gzipETagSuffix = "-gz"
+
+// writeEncodedOrder отдает сериализованный заказ с ETag и поддержкой gzip.
+// На If-None-Match, совпадающий с ETag любого из представлений, отвечает 304 без тела.
+func (t *httpTransport) writeEncodedOrder(w http.ResponseWriter, r *http.Request, encoded *ristrettocache.EncodedOrder) {
+	header := w.Header()
+	header.Set("Vary", "Accept-Encoding")
+
+	body, etag := encoded.JSON, encoded.ETag
+	gzipped := encoded.Gzip != nil && acceptsGzip(r.Header.Get("Accept-Encoding"))
+	if gzipped {
+		body, etag = encoded.Gzip, gzipETag(encoded.ETag)
+	}
+	header.Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), encoded.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if gzipped {
+		header.Set("Content-Encoding", "gzip")
+	}
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(body); err != nil {
+		t.log(r).Error("Failed to write order response", slog.Any("error", err))
+	}
+}
+
+// gzipETag возвращает ETag gzip-представления: суффикс добавляется внутри кавычек.
+func gzipETag(etag string) string {
+	return strings.TrimSuffix(etag, `"`) + gzipETagSuffix + `"`
+}
+
+// etagMatches проверяет заголовок If-None-Match (слабое сравнение, RFC 9110).
+// Совпадением считается ETag как исходного, так и gzip-представления.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == gzipETag(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip проверяет, разрешает ли клиент ответ в gzip.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				quality, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		return quality > 0
+	}
+	return false
+}
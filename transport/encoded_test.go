@@ -0,0 +1,70 @@
+package httptransport
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+func TestWriteEncodedOrderETagPerRepresentation(t *testing.T) {
+	encoded, err := ristrettocache.EncodeOrder(&model.OrderDetails{OrderID: "o1"})
+	if err != nil {
+		t.Fatalf("EncodeOrder: %v", err)
+	}
+	gzipTag := gzipETag(encoded.ETag)
+	if gzipTag == encoded.ETag {
+		t.Fatalf("gzip ETag %s equals identity ETag", gzipTag)
+	}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		ifNoneMatch    string
+		wantStatus     int
+		wantETag       string
+		wantEncoding   string
+	}{
+		{"identity", "", "", http.StatusOK, encoded.ETag, ""},
+		{"gzip", "gzip", "", http.StatusOK, gzipTag, "gzip"},
+		{"gzip refused", "gzip;q=0", "", http.StatusOK, encoded.ETag, ""},
+		{"identity revalidated", "", encoded.ETag, http.StatusNotModified, encoded.ETag, ""},
+		{"gzip revalidated", "gzip", gzipTag, http.StatusNotModified, gzipTag, ""},
+		{"gzip tag on identity request", "", gzipTag, http.StatusNotModified, encoded.ETag, ""},
+		{"weak gzip tag", "gzip", "W/" + gzipTag, http.StatusNotModified, gzipTag, ""},
+		{"stale tag", "gzip", `"stale"`, http.StatusOK, gzipTag, "gzip"},
+	}
+
+	transport := &httpTransport{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/orders/o1", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			transport.writeEncodedOrder(w, r, encoded)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %s, want %s", got, tt.wantETag)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+		})
+	}
+}
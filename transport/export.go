@@ -0,0 +1,137 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/export"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// exportFlushEvery через сколько заказов выгрузка отправляется клиенту.
+const exportFlushEvery = 100
+
+// Exporter хранилище с потоковой выгрузкой заказов.
+type Exporter interface {
+	ExportOrders(ctx context.Context, filter model.OrderFilter, fn func(*model.OrderDetails) error) error
+}
+
+// WithExport включает выгрузку заказов GET /api/v1/orders:export.
+func WithExport(exporter Exporter) Option {
+	return func(t *httpTransport) {
+		t.exporter = exporter
+	}
+}
+
+// ParseOrderFilter разбирает фильтр заказов из параметров запроса:
+// created_from, created_to, customer_id, delivery_service и limit.
+func ParseOrderFilter(query url.Values) (model.OrderFilter, error) {
+	var filter model.OrderFilter
+
+	times := map[string]*time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+	}
+	for name, target := range times {
+		if value := query.Get(name); value != "" {
+			parsed, err := model.ParseFilterTime(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	filter.CustomerID = query.Get("customer_id")
+	filter.DeliveryService = query.Get("delivery_service")
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("limit must be an integer")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, filter.Validate()
+}
+
+// exportOrdersHandler выгружает заказы в NDJSON (format=ndjson, по умолчанию)
+// или CSV (format=csv). Ответ передается по мере чтения из базы; если
+// выгрузка обрывается на середине, соединение разрывается, чтобы клиент не
+// принял неполный файл за целый.
+func (t *httpTransport) exportOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	format := export.FormatNDJSON
+	if value := r.URL.Query().Get("format"); value != "" {
+		var err error
+		if format, err = export.ParseFormat(value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	filter, err := ParseOrderFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Выгрузка может идти дольше WriteTimeout сервера
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		t.log(r).Warn("Failed to reset write deadline for export", slog.Any("error", err))
+	}
+
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Заголовки выставляются перед первыми данными: до этого ошибку еще
+	// можно вернуть обычным ответом 500
+	setHeaders := func() {
+		header := w.Header()
+		header.Set("Content-Type", format.ContentType())
+		header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="orders.%s"`, format))
+		header.Set("Cache-Control", "no-store")
+	}
+
+	exported := 0
+	err = t.exporter.ExportOrders(r.Context(), filter, func(order *model.OrderDetails) error {
+		if exported == 0 {
+			setHeaders()
+		}
+		if err := writer.Write(t.maskOrder(r, order)); err != nil {
+			return err
+		}
+		exported++
+		if exported%exportFlushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			return controller.Flush()
+		}
+		return nil
+	})
+
+	switch {
+	case err != nil && exported == 0:
+		t.log(r).Error("Failed to export orders", slog.Any("error", err))
+		http.Error(w, "Failed to export orders", http.StatusInternalServerError)
+	case err != nil:
+		t.log(r).Error("Order export interrupted", slog.Int("exported", exported), slog.Any("error", err))
+		panic(http.ErrAbortHandler)
+	default:
+		if exported == 0 {
+			setHeaders()
+		}
+		if err := writer.Flush(); err != nil {
+			t.log(r).Error("Failed to write export response", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// stubExporter выгружает заданные заказы и запоминает фильтр.
+type stubExporter struct {
+	orders []*model.OrderDetails
+	filter model.OrderFilter
+}
+
+func (e *stubExporter) ExportOrders(_ context.Context, filter model.OrderFilter, fn func(*model.OrderDetails) error) error {
+	e.filter = filter
+	for _, order := range e.orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportTestOrders() []*model.OrderDetails {
+	return []*model.OrderDetails{
+		{
+			OrderID: "o1",
+			Payment: model.PaymentDetails{Currency: "USD", Amount: 181700},
+			Products: []model.ProductItem{
+				{ChartID: 1, Name: "Mascaras", Price: 45300, TotalPrice: 31700},
+				{ChartID: 2, Name: "Lipstick", Price: 105, TotalPrice: 105},
+			},
+		},
+		{OrderID: "o2", Payment: model.PaymentDetails{Currency: "JPY", Amount: 1817}},
+	}
+}
+
+func TestExportOrdersHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantType    string
+		wantRecords [][2]string // order_uid и второе проверяемое поле каждой строки
+	}{
+		{
+			"ndjson by default", "", "application/x-ndjson",
+			[][2]string{{"o1", `"schema_version":2`}, {"o2", `"schema_version":2`}},
+		},
+		{
+			"csv", "format=csv", "text/csv; charset=utf-8",
+			[][2]string{{"order_uid", "item_name"}, {"o1", "Mascaras"}, {"o1", "Lipstick"}, {"o2", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &httpTransport{
+				logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+				exporter: &stubExporter{orders: exportTestOrders()},
+			}
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/orders:export?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			transport.exportOrdersHandler(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+
+			if tt.wantType == "application/x-ndjson" {
+				lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+				if len(lines) != len(tt.wantRecords) {
+					t.Fatalf("got %d lines, want %d", len(lines), len(tt.wantRecords))
+				}
+				for i, want := range tt.wantRecords {
+					if !strings.Contains(lines[i], `"order_uid":"`+want[0]+`"`) || !strings.Contains(lines[i], want[1]) {
+						t.Errorf("line %d = %s, want order %s with %s", i, lines[i], want[0], want[1])
+					}
+				}
+				return
+			}
+
+			records, err := csv.NewReader(w.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("read CSV: %v", err)
+			}
+			if len(records) != len(tt.wantRecords) {
+				t.Fatalf("got %d records, want %d", len(records), len(tt.wantRecords))
+			}
+			nameColumn := 32 // item_name
+			for i, want := range tt.wantRecords {
+				if records[i][0] != want[0] || records[i][nameColumn] != want[1] {
+					t.Errorf("record %d = %s/%s, want %s/%s", i, records[i][0], records[i][nameColumn], want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestExportOrdersHandlerRejectsBadQuery(t *testing.T) {
+	for _, query := range []string{"format=xml", "limit=many", "created_from=yesterday"} {
+		transport := &httpTransport{
+			logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+			exporter: &stubExporter{},
+		}
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/orders:export?"+query, nil)
+		w := httptest.NewRecorder()
+
+		transport.exportOrdersHandler(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package httptransport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/frontend"
+)
+
+const (
+	// indexCacheControl страница всегда перепроверяется по ETag, чтобы
+	// новая версия интерфейса подхватывалась сразу после выката.
+	indexCacheControl = "no-cache"
+	// staticCacheControl статические файлы кэшируются браузером на час.
+	staticCacheControl = "public, max-age=3600"
+	// contentSecurityPolicy разрешает только собственные скрипты и стили.
+	contentSecurityPolicy = "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'"
+)
+
+// staticAsset встроенный файл интерфейса.
+type staticAsset struct {
+	name        string
+	data        []byte
+	etag        string
+	contentType string
+}
+
+// frontendAssets файлы интерфейса по пути от корня встроенной файловой системы.
+type frontendAssets map[string]*staticAsset
+
+// loadFrontendAssets читает встроенные файлы и вычисляет их ETag.
+func loadFrontendAssets(fsys fs.FS) (frontendAssets, error) {
+	assets := make(frontendAssets)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+
+		assets[name] = &staticAsset{
+			name:        name,
+			data:        data,
+			etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+			contentType: contentType,
+		}
+		return nil
+	})
+	return assets, err
+}
+
+// serve отдает файл с заданным Cache-Control. Условные запросы
+// (If-None-Match) обрабатывает http.ServeContent.
+func (a *staticAsset) serve(w http.ResponseWriter, r *http.Request, cacheControl string) {
+	header := w.Header()
+	header.Set("Content-Type", a.contentType)
+	header.Set("Cache-Control", cacheControl)
+	header.Set("ETag", a.etag)
+	header.Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, a.name, time.Time{}, bytes.NewReader(a.data))
+}
+
+// frontendRoutes регистрирует страницу просмотра заказов и ее статические файлы.
+// Страница открывается и по прямой ссылке /orders/{uid}.
+func (t *httpTransport) frontendRoutes(router *http.ServeMux) {
+	assets, err := loadFrontendAssets(frontend.Files)
+	if err != nil {
+		// Файлы встроены при сборке, поэтому ошибка означает поврежденный бинарный файл
+		panic("failed to load embedded frontend: " + err.Error())
+	}
+
+	index := assets["index.html"]
+	indexHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		w.Header().Set("X-Frame-Options", "DENY")
+		index.serve(w, r, indexCacheControl)
+	}
+
+	router.HandleFunc("GET /{$}", indexHandler)
+	router.HandleFunc("GET /orders/{uid}", indexHandler)
+	router.HandleFunc("GET /static/", func(w http.ResponseWriter, r *http.Request) {
+		asset, ok := assets[path.Clean(r.URL.Path[1:])]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		asset.serve(w, r, staticCacheControl)
+	})
+}
@@ -0,0 +1,26 @@
+package httptransport
+
+import (
+	"net/http"
+
+	graphqlapi "github.com/Sh1ni-Gami/WB_Tech_L0/graphql"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// WithGraphQL включает GraphQL-эндпоинт /graphql (GET и POST), созданный
+// graphqlapi.NewHandler. Доступ — как у API чтения заказов.
+func WithGraphQL(handler http.Handler) Option {
+	return func(t *httpTransport) {
+		t.graphql = handler
+	}
+}
+
+// graphQLHandler передает запрос GraphQL-эндпоинту вместе с маскированием
+// персональных данных по областям доступа клиента.
+func (t *httpTransport) graphQLHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := graphqlapi.ContextWithMask(r.Context(), func(order *model.OrderDetails) *model.OrderDetails {
+		return t.maskOrder(r, order)
+	})
+	r.Body = http.MaxBytesReader(w, r.Body, t.limits.MaxBodyBytes)
+	t.graphql.ServeHTTP(w, r.WithContext(ctx))
+}
@@ -0,0 +1,267 @@
+package httptransport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// maxBatchOrders максимальное количество заказов в одном пакетном запросе.
+const maxBatchOrders = 1000
+
+// IngestMode определяет, что делать с заказом, принятым по HTTP.
+type IngestMode string
+
+const (
+	// IngestKafka публикует заказ в Kafka, сохранением занимается консьюмер.
+	IngestKafka IngestMode = "kafka"
+	// IngestDirect сохраняет заказ напрямую в хранилище.
+	IngestDirect IngestMode = "direct"
+)
+
+// Publisher интерфейс для публикации заказов (реализуется KafkaService).
+type Publisher interface {
+	SendOrder(ctx context.Context, order *model.OrderDetails) error
+}
+
+// Verifier проверяет internal_signature заказа (реализуется signing.Keyset).
+type Verifier interface {
+	Verify(order *model.OrderDetails) error
+}
+
+// Статусы обработки отдельного заказа.
+const (
+	statusCreated   = "created"
+	statusAccepted  = "accepted"
+	statusDuplicate = "duplicate"
+	statusInvalid   = "invalid"
+	statusFailed    = "failed"
+)
+
+// ingestResult результат обработки одного заказа.
+type ingestResult struct {
+	OrderUID string   `json:"order_uid,omitempty"`
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// batchResponse ответ на пакетный запрос.
+type batchResponse struct {
+	Results []ingestResult `json:"results"`
+	Summary map[string]int `json:"summary"`
+}
+
+// WithIngestion включает прием заказов по HTTP. В режиме IngestKafka заказы
+// публикуются через publisher, в режиме IngestDirect сохраняются в Store.
+func WithIngestion(mode IngestMode, publisher Publisher) Option {
+	return func(t *httpTransport) {
+		t.ingestMode = mode
+		t.publisher = publisher
+	}
+}
+
+// WithVerifier включает проверку internal_signature заказов, сохраняемых в
+// режиме IngestDirect, — ту же, что выполняет слушатель Kafka. Заказ без
+// подписи или с неверной подписью не сохраняется и получает статус invalid.
+// В режиме IngestKafka подпись проверяет слушатель.
+func WithVerifier(verifier Verifier) Option {
+	return func(t *httpTransport) {
+		t.verifier = verifier
+	}
+}
+
+// createOrderHandler принимает один заказ в формате JSON.
+func (t *httpTransport) createOrderHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, t.limits.MaxBodyBytes))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	result := t.ingestOrder(r.Context(), body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(ingestStatusCode(result.Status))
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		t.log(r).Error("Failed to encode response to JSON", slog.Any("error", err))
+	}
+}
+
+// createOrdersBatchHandler принимает пакет заказов: JSON-массив или NDJSON.
+func (t *httpTransport) createOrdersBatchHandler(w http.ResponseWriter, r *http.Request) {
+	payloads, err := splitBatch(http.MaxBytesReader(w, r.Body, t.limits.MaxBatchBodyBytes))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(payloads) == 0 {
+		http.Error(w, "Empty batch", http.StatusBadRequest)
+		return
+	}
+	if len(payloads) > maxBatchOrders {
+		http.Error(w, "Too many orders in batch", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	response := batchResponse{
+		Results: make([]ingestResult, 0, len(payloads)),
+		Summary: make(map[string]int),
+	}
+	for _, payload := range payloads {
+		result := t.ingestOrder(r.Context(), payload)
+		response.Results = append(response.Results, result)
+		response.Summary[result.Status]++
+	}
+
+	t.writeJSON(w, response)
+}
+
+// ingestOrder разбирает, проверяет и сохраняет или публикует один заказ.
+func (t *httpTransport) ingestOrder(ctx context.Context, data []byte) ingestResult {
+	order, err := model.ParseOrder(data, model.MaxItems)
+	if err != nil {
+		return ingestResult{Status: statusInvalid, Error: err.Error()}
+	}
+
+	result := ingestResult{OrderUID: order.OrderID}
+	if err := order.Validate(); err != nil {
+		result.Status = statusInvalid
+		result.Error = err.Error()
+
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			result.Error = "validation failed"
+			result.Problems = validationErr.Problems
+		}
+		return result
+	}
+
+	switch t.ingestMode {
+	case IngestDirect:
+		if t.verifier != nil {
+			if err := t.verifier.Verify(order); err != nil {
+				logging.FromContext(ctx, t.logger).Warn("Order rejected: signature verification failed",
+					slog.String("orderUID", order.OrderID), slog.Any("error", err))
+				result.Status = statusInvalid
+				result.Error = "signature verification failed: " + err.Error()
+				return result
+			}
+		}
+		err = t.store.AddOrder(ctx, order)
+		result.Status = statusCreated
+	default:
+		err = t.publisher.SendOrder(ctx, order)
+		result.Status = statusAccepted
+	}
+
+	switch {
+	case errors.Is(err, model.ErrDuplicateOrder):
+		result.Status = statusDuplicate
+	case errors.Is(err, model.ErrConflictingData):
+		result.Status = statusInvalid
+		result.Error = err.Error()
+	case err != nil:
+		logging.FromContext(ctx, t.logger).Error("Failed to ingest order", slog.String("orderUID", order.OrderID), slog.Any("error", err))
+		result.Status = statusFailed
+		result.Error = "failed to store order"
+	}
+
+	return result
+}
+
+// splitBatch разбивает тело пакетного запроса на отдельные заказы. Тело,
+// начинающееся с '[', считается JSON-массивом, иначе — NDJSON.
+func splitBatch(body io.Reader) ([][]byte, error) {
+	reader := bufio.NewReader(body)
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if first == '[' {
+		var raw []json.RawMessage
+		if err := json.NewDecoder(reader).Decode(&raw); err != nil {
+			if isBodyTooLarge(err) {
+				return nil, err
+			}
+			return nil, errors.New("invalid JSON array")
+		}
+		payloads := make([][]byte, len(raw))
+		for i, item := range raw {
+			payloads[i] = item
+		}
+		return payloads, nil
+	}
+
+	var payloads [][]byte
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		payloads = append(payloads, bytes.Clone(line))
+		if len(payloads) > maxBatchOrders {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if isBodyTooLarge(err) {
+			return nil, err
+		}
+		return nil, errors.New("invalid NDJSON body")
+	}
+	return payloads, nil
+}
+
+// peekNonSpace пропускает пробельные символы и возвращает первый значимый байт.
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.Discard(1)
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// ingestStatusCode возвращает HTTP-статус для результата обработки одного заказа.
+func ingestStatusCode(status string) int {
+	switch status {
+	case statusCreated:
+		return http.StatusCreated
+	case statusAccepted:
+		return http.StatusAccepted
+	case statusDuplicate:
+		return http.StatusOK
+	case statusInvalid:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,333 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// ingestMessage пример заказа, который принимает сервис.
+const ingestMessage = "../scripts/order.json"
+
+func readIngestOrder(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(ingestMessage)
+	if err != nil {
+		t.Fatalf("read %s: %v", ingestMessage, err)
+	}
+	return data
+}
+
+// ingestOrderBody возвращает пример заказа с другим order_uid.
+func ingestOrderBody(t *testing.T, orderUID string) []byte {
+	t.Helper()
+	var message map[string]any
+	if err := json.Unmarshal(readIngestOrder(t), &message); err != nil {
+		t.Fatalf("unmarshal order: %v", err)
+	}
+	message["order_uid"] = orderUID
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("marshal order: %v", err)
+	}
+	return data
+}
+
+// stubIngestStore сохраняет заказы в памяти; для заказов из errs вместо
+// сохранения возвращается ошибка.
+type stubIngestStore struct {
+	Store
+	errs  map[string]error
+	added []string
+}
+
+func (s *stubIngestStore) AddOrder(_ context.Context, order *model.OrderDetails) error {
+	if err := s.errs[order.OrderID]; err != nil {
+		return err
+	}
+	s.added = append(s.added, order.OrderID)
+	return nil
+}
+
+// stubPublisher запоминает опубликованные заказы; для заказов из errs
+// возвращает ошибку.
+type stubPublisher struct {
+	errs map[string]error
+	sent []string
+}
+
+func (p *stubPublisher) SendOrder(_ context.Context, order *model.OrderDetails) error {
+	if err := p.errs[order.OrderID]; err != nil {
+		return err
+	}
+	p.sent = append(p.sent, order.OrderID)
+	return nil
+}
+
+// newIngestTransport создает транспорт с приемом заказов в режиме mode.
+func newIngestTransport(mode IngestMode, store *stubIngestStore, publisher *stubPublisher) *httpTransport {
+	return &httpTransport{
+		store:      store,
+		publisher:  publisher,
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		limits:     DefaultLimits(),
+		ingestMode: mode,
+	}
+}
+
+// stubVerifier принимает только заказы с подписью signature.
+type stubVerifier struct {
+	signature string
+}
+
+func (v stubVerifier) Verify(order *model.OrderDetails) error {
+	if order.Signature != v.signature {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func TestIngestDirectVerifiesSignature(t *testing.T) {
+	var message map[string]any
+	if err := json.Unmarshal(readIngestOrder(t), &message); err != nil {
+		t.Fatalf("unmarshal order: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		signature  string
+		wantStatus int
+		wantStored bool
+	}{
+		{name: "signed", signature: "k1:valid", wantStatus: http.StatusCreated, wantStored: true},
+		{name: "unsigned", signature: "", wantStatus: http.StatusUnprocessableEntity},
+		{name: "forged", signature: "k1:forged", wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message["internal_signature"] = tt.signature
+			body, err := json.Marshal(message)
+			if err != nil {
+				t.Fatalf("marshal order: %v", err)
+			}
+			store := &stubIngestStore{}
+			transport := newIngestTransport(IngestDirect, store, nil)
+			transport.verifier = stubVerifier{signature: "k1:valid"}
+			w := httptest.NewRecorder()
+
+			transport.createOrderHandler(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(string(body))))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if stored := len(store.added) > 0; stored != tt.wantStored {
+				t.Errorf("stored = %t, want %t", stored, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "json array", body: ` [{"order_uid":"a"}, {"order_uid":"b"}]`, want: []string{`{"order_uid":"a"}`, `{"order_uid":"b"}`}},
+		{name: "ndjson", body: "{\"order_uid\":\"a\"}\n\n  {\"order_uid\":\"b\"}  \r\n", want: []string{`{"order_uid":"a"}`, `{"order_uid":"b"}`}},
+		// Строка NDJSON разбирается потом как отдельный заказ и получает статус invalid
+		{name: "ndjson with a broken line", body: "{\"order_uid\":\"a\"}\nnot json\n", want: []string{`{"order_uid":"a"}`, "not json"}},
+		{name: "empty", body: ""},
+		{name: "whitespace", body: " \n\t"},
+		{name: "empty array", body: "[]", want: []string{}},
+		{name: "broken array", body: `[{"order_uid":"a"},`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads, err := splitBatch(strings.NewReader(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			var got []string
+			if payloads != nil {
+				got = make([]string, len(payloads))
+			}
+			for i, payload := range payloads {
+				got[i] = string(payload)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payloads = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOrdersBatchRejectsBody(t *testing.T) {
+	ndjson := func(n int) string {
+		return strings.Repeat("{}\n", n)
+	}
+	array := func(n int) string {
+		return "[" + strings.TrimSuffix(strings.Repeat("{},", n), ",") + "]"
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		maxBodyBytes int64
+		wantStatus   int
+	}{
+		{name: "empty body", body: "", wantStatus: http.StatusBadRequest},
+		{name: "empty array", body: "[]", wantStatus: http.StatusBadRequest},
+		{name: "broken array", body: "[{}", wantStatus: http.StatusBadRequest},
+		{name: "too many ndjson orders", body: ndjson(maxBatchOrders + 1), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "too many array orders", body: array(maxBatchOrders + 1), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "oversized ndjson", body: ndjson(100), maxBodyBytes: 64, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "oversized array", body: array(100), maxBodyBytes: 64, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &stubIngestStore{}
+			transport := newIngestTransport(IngestDirect, store, nil)
+			if tt.maxBodyBytes > 0 {
+				transport.limits.MaxBatchBodyBytes = tt.maxBodyBytes
+			}
+			w := httptest.NewRecorder()
+
+			transport.createOrdersBatchHandler(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders:batch", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if len(store.added) > 0 {
+				t.Errorf("stored %v from a rejected batch", store.added)
+			}
+		})
+	}
+}
+
+func TestCreateOrderRejectsOversizedBody(t *testing.T) {
+	transport := newIngestTransport(IngestDirect, &stubIngestStore{}, nil)
+	transport.limits.MaxBodyBytes = 64
+	w := httptest.NewRecorder()
+
+	transport.createOrderHandler(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(string(readIngestOrder(t)))))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+}
+
+func TestIngestResults(t *testing.T) {
+	failures := map[string]error{
+		"duplicate": model.ErrDuplicateOrder,
+		"conflict":  model.ErrConflictingData,
+		"broken":    errors.New("connection refused"),
+	}
+	tests := []struct {
+		name       string
+		mode       IngestMode
+		body       func(t *testing.T) []byte
+		wantStatus string
+		wantCode   int
+	}{
+		{name: "direct created", mode: IngestDirect, body: orderBody("new"), wantStatus: statusCreated, wantCode: http.StatusCreated},
+		{name: "kafka accepted", mode: IngestKafka, body: orderBody("new"), wantStatus: statusAccepted, wantCode: http.StatusAccepted},
+		{name: "direct duplicate", mode: IngestDirect, body: orderBody("duplicate"), wantStatus: statusDuplicate, wantCode: http.StatusOK},
+		{name: "kafka duplicate", mode: IngestKafka, body: orderBody("duplicate"), wantStatus: statusDuplicate, wantCode: http.StatusOK},
+		{name: "conflicting data", mode: IngestDirect, body: orderBody("conflict"), wantStatus: statusInvalid, wantCode: http.StatusUnprocessableEntity},
+		{name: "direct failed", mode: IngestDirect, body: orderBody("broken"), wantStatus: statusFailed, wantCode: http.StatusInternalServerError},
+		{name: "kafka failed", mode: IngestKafka, body: orderBody("broken"), wantStatus: statusFailed, wantCode: http.StatusInternalServerError},
+		{name: "malformed JSON", mode: IngestDirect, body: rawBody(`{"order_uid":`), wantStatus: statusInvalid, wantCode: http.StatusUnprocessableEntity},
+		{name: "failed validation", mode: IngestDirect, body: orderBody(""), wantStatus: statusInvalid, wantCode: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t)
+			transport := newIngestTransport(tt.mode, &stubIngestStore{errs: failures}, &stubPublisher{errs: failures})
+
+			w := httptest.NewRecorder()
+			transport.createOrderHandler(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(string(body))))
+			var result ingestResult
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if w.Code != tt.wantCode || result.Status != tt.wantStatus {
+				t.Errorf("single: %d %s, want %d %s", w.Code, result.Status, tt.wantCode, tt.wantStatus)
+			}
+			if tt.wantStatus == statusInvalid && result.Error == "" {
+				t.Errorf("invalid order without an error: %+v", result)
+			}
+			if tt.wantStatus == statusFailed && result.Error != "failed to store order" {
+				t.Errorf("failed order error %q leaks the cause", result.Error)
+			}
+
+			// В пакете тот же результат, а код ответа всегда 200
+			w = httptest.NewRecorder()
+			transport.createOrdersBatchHandler(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders:batch", strings.NewReader(string(body)+"\n")))
+			var batch batchResponse
+			if err := json.NewDecoder(w.Body).Decode(&batch); err != nil {
+				t.Fatalf("decode batch response: %v", err)
+			}
+			if w.Code != http.StatusOK || len(batch.Results) != 1 || batch.Results[0].Status != tt.wantStatus {
+				t.Errorf("batch: %d %+v, want 200 with %s", w.Code, batch.Results, tt.wantStatus)
+			}
+			if batch.Summary[tt.wantStatus] != 1 {
+				t.Errorf("batch summary %v, want one %s", batch.Summary, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCreateOrdersBatchMixedResults(t *testing.T) {
+	store := &stubIngestStore{errs: map[string]error{"duplicate": model.ErrDuplicateOrder}}
+	transport := newIngestTransport(IngestDirect, store, nil)
+	body := "[" + strings.Join([]string{
+		string(ingestOrderBody(t, "first")),
+		string(ingestOrderBody(t, "duplicate")),
+		`{"order_uid": 1}`,
+		string(ingestOrderBody(t, "second")),
+	}, ",") + "]"
+	w := httptest.NewRecorder()
+
+	transport.createOrdersBatchHandler(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders:batch", strings.NewReader(body)))
+
+	var batch batchResponse
+	if err := json.NewDecoder(w.Body).Decode(&batch); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	var statuses []string
+	for _, result := range batch.Results {
+		statuses = append(statuses, result.OrderUID+":"+result.Status)
+	}
+	want := []string{"first:created", "duplicate:duplicate", ":invalid", "second:created"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("results %v, want %v in request order", statuses, want)
+	}
+	if want := map[string]int{statusCreated: 2, statusDuplicate: 1, statusInvalid: 1}; !reflect.DeepEqual(batch.Summary, want) {
+		t.Errorf("summary %v, want %v", batch.Summary, want)
+	}
+	if !reflect.DeepEqual(store.added, []string{"first", "second"}) {
+		t.Errorf("stored %v, want first and second", store.added)
+	}
+}
+
+// orderBody тело с примером заказа; пустой orderUID не проходит проверку.
+func orderBody(orderUID string) func(t *testing.T) []byte {
+	return func(t *testing.T) []byte { return ingestOrderBody(t, orderUID) }
+}
+
+// rawBody тело запроса как есть.
+func rawBody(body string) func(t *testing.T) []byte {
+	return func(*testing.T) []byte { return []byte(body) }
+}
@@ -0,0 +1,40 @@
+package httptransport
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/invoice"
+)
+
+// invoiceContentSecurityPolicy накладная загружает только свою таблицу стилей
+// /static/invoice.css. Встроенные стили не разрешены: накладная, открытая
+// интерфейсом как blob:, получает политику страницы интерфейса, а не эту.
+const invoiceContentSecurityPolicy = "default-src 'none'; style-src 'self'"
+
+// orderInvoiceHandler отдает печатную накладную заказа. Персональные данные
+// маскируются по тем же правилам, что и в API.
+func (t *httpTransport) orderInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := t.lookupOrder(w, r)
+	if !ok {
+		return
+	}
+
+	// Шаблон выполняется в буфер, чтобы ошибка не оставила клиенту половину страницы
+	var buf bytes.Buffer
+	if err := invoice.Render(&buf, order); err != nil {
+		t.log(r).Error("Failed to render invoice", slog.String("orderUID", order.OrderID), slog.Any("error", err))
+		http.Error(w, "Failed to render invoice", http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("Content-Security-Policy", invoiceContentSecurityPolicy)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		t.log(r).Error("Failed to write invoice response", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,224 @@
+package httptransport
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucketIdleTTL время, после которого неиспользуемый бакет клиента удаляется.
+const bucketIdleTTL = 10 * time.Minute
+
+// Limits ограничения HTTP-сервера.
+type Limits struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// MaxBodyBytes ограничение тела запроса на прием одного заказа и запроса GraphQL.
+	MaxBodyBytes int64
+	// MaxBatchBodyBytes ограничение тела пакетного запроса.
+	MaxBatchBodyBytes int64
+
+	// RateLimit запросов в секунду на клиента, 0 — без ограничения. До
+	// аутентификации клиент определяется по IP, после нее — по владельцу
+	// проверенных учетных данных.
+	RateLimit float64
+	// RateBurst допустимый всплеск запросов сверх RateLimit.
+	RateBurst int
+	// MaxConcurrent одновременно обрабатываемых запросов, 0 — без ограничения.
+	MaxConcurrent int
+	// TrustProxyHeaders брать IP клиента из X-Forwarded-For (только за доверенным прокси).
+	TrustProxyHeaders bool
+	// TrustedProxyHops число доверенных прокси перед сервером: адрес клиента
+	// берется на таком расстоянии от правого края X-Forwarded-For.
+	TrustedProxyHops int
+}
+
+// DefaultLimits возвращает ограничения по умолчанию.
+func DefaultLimits() Limits {
+	return Limits{
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+		MaxBodyBytes:      1 << 20,
+		MaxBatchBodyBytes: 32 << 20,
+		RateLimit:         50,
+		RateBurst:         100,
+		MaxConcurrent:     256,
+		TrustedProxyHops:  1,
+	}
+}
+
+// WithLimits задает ограничения HTTP-сервера вместо DefaultLimits.
+func WithLimits(limits Limits) Option {
+	return func(t *httpTransport) {
+		t.limits = limits
+	}
+}
+
+// rateLimiter ограничивает частоту запросов каждого клиента алгоритмом token bucket.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket состояние бакета одного клиента.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow списывает токен клиента. Если токенов нет, возвращает время до
+// появления следующего.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > bucketIdleTTL {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > bucketIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// initRateLimiters создает бакеты по IP и по аутентифицированному клиенту.
+// Бакеты раздельные, чтобы клиент с ключом не делил лимит с соседями по NAT.
+func (t *httpTransport) initRateLimiters() {
+	if t.limits.RateLimit > 0 {
+		t.ipLimiter = newRateLimiter(t.limits.RateLimit, t.limits.RateBurst)
+		t.principalLimiter = newRateLimiter(t.limits.RateLimit, t.limits.RateBurst)
+	}
+}
+
+// withLimits оборачивает обработчик ограничением одновременных запросов и
+// частоты запросов с IP-адреса клиента. Учетные данные здесь еще не
+// проверены, поэтому бакет выбирается только по IP.
+func (t *httpTransport) withLimits(next http.Handler) http.Handler {
+	var semaphore chan struct{}
+	if t.limits.MaxConcurrent > 0 {
+		semaphore = make(chan struct{}, t.limits.MaxConcurrent)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.allowRequest(w, r, t.ipLimiter, "ip:"+t.clientIP(r)) {
+			return
+		}
+
+		if semaphore != nil {
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			default:
+				w.Header().Set("Retry-After", "1")
+				t.log(r).Warn("Concurrency limit reached", slog.String("path", r.URL.Path))
+				http.Error(w, "Server is busy", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowPrincipal ограничивает частоту запросов аутентифицированного клиента.
+// Вызывается только после проверки учетных данных, поэтому подбор случайных
+// ключей не дает новых бакетов.
+func (t *httpTransport) allowPrincipal(w http.ResponseWriter, r *http.Request, principal *Principal) bool {
+	if principal.Method == "anonymous" {
+		return true
+	}
+	return t.allowRequest(w, r, t.principalLimiter, principal.Method+":"+principal.Subject)
+}
+
+// allowRequest списывает токен из бакета key; при превышении отвечает 429 с
+// Retry-After. Без ограничителя пропускает все запросы.
+func (t *httpTransport) allowRequest(w http.ResponseWriter, r *http.Request, limiter *rateLimiter, key string) bool {
+	if limiter == nil {
+		return true
+	}
+
+	ok, wait := limiter.allow(key, time.Now())
+	if !ok {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		t.log(r).Warn("Rate limit exceeded", slog.String("client", key), slog.String("path", r.URL.Path))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+	}
+	return ok
+}
+
+// clientIP возвращает IP-адрес клиента. За доверенными прокси это запись
+// X-Forwarded-For на расстоянии TrustedProxyHops от правого края: левее
+// стоят адреса, которые клиент мог подставить сам.
+func (t *httpTransport) clientIP(r *http.Request) string {
+	if t.limits.TrustProxyHeaders {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(strings.Join(forwarded, ","), ",")
+			index := len(hops) - max(t.limits.TrustedProxyHops, 1)
+			if index < 0 {
+				index = 0
+			}
+			if ip := strings.TrimSpace(hops[index]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isBodyTooLarge проверяет, что ошибка чтения вызвана превышением MaxBytesReader.
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,96 @@
+package httptransport
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestRateLimitIgnoresUnverifiedCredentials(t *testing.T) {
+	limits := DefaultLimits()
+	limits.RateLimit, limits.RateBurst = 1, 2
+	transport := NewHTTPTransport(nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithLimits(limits),
+		WithAuthentication([]string{ScopeOrdersRead}, NewAPIKeyAuthenticator(stubKeyStore{hash: HashAPIKey("secret")})),
+	).(*httpTransport)
+	handler := transport.withLimits(transport.authorize(ScopeOrdersRead, func(w http.ResponseWriter, r *http.Request) {}))
+
+	codes := make([]int, 0, 3)
+	for i := range 3 {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/orders/o1", nil)
+		r.Header.Set("X-API-Key", "random-"+strconv.Itoa(i))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", codes, want)
+		}
+	}
+	if n := len(transport.ipLimiter.buckets); n != 1 {
+		t.Errorf("ip buckets = %d, want 1", n)
+	}
+	if n := len(transport.principalLimiter.buckets); n != 0 {
+		t.Errorf("principal buckets = %d, want 0", n)
+	}
+}
+
+func TestRateLimitByPrincipal(t *testing.T) {
+	limits := DefaultLimits()
+	limits.RateLimit, limits.RateBurst = 1, 1
+	transport := NewHTTPTransport(nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithLimits(limits),
+		WithAuthentication(nil, NewAPIKeyAuthenticator(stubKeyStore{hash: HashAPIKey("secret")})),
+	).(*httpTransport)
+	handler := transport.authorize(ScopeOrdersRead, func(w http.ResponseWriter, r *http.Request) {})
+
+	// Тот же ключ с разных адресов делит один бакет
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/orders/o1", nil)
+		r.RemoteAddr = "192.0.2." + strconv.Itoa(i+1) + ":1234"
+		r.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i, w.Code, want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		trust     bool
+		hops      int
+		forwarded []string
+		want      string
+	}{
+		{"proxy headers ignored", false, 1, []string{"203.0.113.9"}, "192.0.2.1"},
+		{"single proxy", true, 1, []string{"203.0.113.9"}, "203.0.113.9"},
+		{"spoofed leftmost entry", true, 1, []string{"198.51.100.7, 203.0.113.9"}, "203.0.113.9"},
+		{"two proxies", true, 2, []string{"198.51.100.7, 203.0.113.9, 10.0.0.2"}, "203.0.113.9"},
+		{"repeated header", true, 1, []string{"198.51.100.7", "203.0.113.9"}, "203.0.113.9"},
+		{"fewer entries than hops", true, 3, []string{"203.0.113.9, 10.0.0.2"}, "203.0.113.9"},
+		{"no header", true, 1, nil, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &httpTransport{limits: Limits{TrustProxyHeaders: tt.trust, TrustedProxyHops: tt.hops}}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := transport.clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package httptransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// MaskingPolicy выбирает профиль маскирования персональных данных по
+// областям доступа клиента.
+type MaskingPolicy struct {
+	// Profiles профили по имени.
+	Profiles map[string]model.MaskProfile
+	// ScopeProfiles пары "область доступа -> профиль"; выбирается первая
+	// область, выданная клиенту.
+	ScopeProfiles []ScopeProfile
+	// Default профиль для клиентов без подходящей области доступа.
+	Default string
+}
+
+// ScopeProfile сопоставляет область доступа профилю маскирования.
+type ScopeProfile struct {
+	Scope   string
+	Profile string
+}
+
+// NewMaskingPolicy создает политику со встроенными профилями full, partial и
+// redacted, дополненными профилями из JSON-файла profilesFile (если задан).
+// scopeProfiles задается в виде "scope=profile,scope2=profile2".
+func NewMaskingPolicy(defaultProfile, scopeProfiles, profilesFile string) (*MaskingPolicy, error) {
+	policy := &MaskingPolicy{
+		Profiles: map[string]model.MaskProfile{
+			"full":     model.MaskProfileFull,
+			"partial":  model.MaskProfilePartial,
+			"redacted": model.MaskProfileRedacted,
+		},
+		Default: defaultProfile,
+	}
+
+	if profilesFile != "" {
+		data, err := os.ReadFile(profilesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read masking profiles: %w", err)
+		}
+		var custom map[string]model.MaskProfile
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return nil, fmt.Errorf("invalid masking profiles file: %w", err)
+		}
+		for name, profile := range custom {
+			if err := profile.Validate(); err != nil {
+				return nil, fmt.Errorf("masking profile %q: %w", name, err)
+			}
+			policy.Profiles[name] = profile
+		}
+	}
+
+	for _, pair := range strings.Split(scopeProfiles, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		scope, profile, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid scope profile %q: expected scope=profile", pair)
+		}
+		policy.ScopeProfiles = append(policy.ScopeProfiles, ScopeProfile{Scope: scope, Profile: profile})
+	}
+
+	if _, ok := policy.Profiles[policy.Default]; !ok {
+		return nil, fmt.Errorf("unknown default masking profile %q", policy.Default)
+	}
+	for _, sp := range policy.ScopeProfiles {
+		if _, ok := policy.Profiles[sp.Profile]; !ok {
+			return nil, fmt.Errorf("unknown masking profile %q for scope %q", sp.Profile, sp.Scope)
+		}
+	}
+
+	return policy, nil
+}
+
+// ProfileFor возвращает профиль для клиента (nil principal — клиент без аутентификации).
+func (p *MaskingPolicy) ProfileFor(principal *Principal) model.MaskProfile {
+	if principal != nil {
+		for _, sp := range p.ScopeProfiles {
+			if principal.HasScope(sp.Scope) {
+				return p.Profiles[sp.Profile]
+			}
+		}
+	}
+	return p.Profiles[p.Default]
+}
+
+// WithMasking включает маскирование персональных данных в ответах.
+func WithMasking(policy *MaskingPolicy) Option {
+	return func(t *httpTransport) {
+		t.masking = policy
+	}
+}
+
+// maskProfile возвращает профиль маскирования для запроса; пустой профиль
+// означает, что данные отдаются как есть.
+func (t *httpTransport) maskProfile(r *http.Request) model.MaskProfile {
+	if t.masking == nil {
+		return nil
+	}
+	principal, _ := PrincipalFromContext(r.Context())
+	return t.masking.ProfileFor(principal)
+}
+
+// maskOrder применяет к заказу профиль маскирования запроса.
+func (t *httpTransport) maskOrder(r *http.Request, order *model.OrderDetails) *model.OrderDetails {
+	return order.Masked(t.maskProfile(r))
+}
+
+// fieldTree дерево запрошенных полей: ключ — имя поля JSON, значение —
+// вложенные поля (nil — поле целиком).
+type fieldTree map[string]fieldTree
+
+// parseFields разбирает параметр fields (например, "order_uid,items.name")
+// и проверяет пути по JSON-тегам структуры OrderDetails.
+func parseFields(spec string) (fieldTree, error) {
+	tree := make(fieldTree)
+	for _, path := range strings.Split(spec, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		parts := strings.Split(path, ".")
+		typ := reflect.TypeOf(model.OrderDetails{})
+		for _, part := range parts {
+			fieldType, ok := jsonFieldType(typ, part)
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", path)
+			}
+			typ = fieldType
+		}
+
+		node := tree
+		for i, part := range parts {
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+
+			child, exists := node[part]
+			if exists && child == nil {
+				// Поле уже запрошено целиком
+				break
+			}
+			if child == nil {
+				child = make(fieldTree)
+				node[part] = child
+			}
+			node = child
+		}
+	}
+
+	if len(tree) == 0 {
+		return nil, fmt.Errorf("fields must not be empty")
+	}
+	return tree, nil
+}
+
+// jsonFieldType находит поле структуры по JSON-имени и возвращает его тип
+// (для срезов — тип элемента).
+func jsonFieldType(typ reflect.Type, name string) (reflect.Type, bool) {
+	for typ.Kind() == reflect.Slice || typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == name {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// project оставляет в значении только поля из дерева.
+func project(value any, tree fieldTree) any {
+	if tree == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(tree))
+		for name, sub := range tree {
+			if fieldValue, ok := v[name]; ok {
+				result[name] = project(fieldValue, sub)
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = project(item, tree)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// projectOrder возвращает заказ, сокращенный до запрошенных полей. Числа
+// остаются json.Number: через float64 суммы и идентификаторы больше 2^53
+// потеряли бы точность.
+func projectOrder(order *model.OrderDetails, tree fieldTree) (any, error) {
+	raw, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return project(generic, tree), nil
+}
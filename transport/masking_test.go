@@ -0,0 +1,36 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+func TestProjectOrderKeepsLargeNumbers(t *testing.T) {
+	order := &model.OrderDetails{
+		OrderID: "o1",
+		Payment: model.PaymentDetails{Amount: 9007199254740993, Currency: "RUB"},
+		Products: []model.ProductItem{
+			{ChartID: 9007199, Price: 9007199254740995},
+		},
+	}
+	tree, err := parseFields("order_uid,payment.amount,items.price")
+	if err != nil {
+		t.Fatalf("parseFields: %v", err)
+	}
+
+	projected, err := projectOrder(order, tree)
+	if err != nil {
+		t.Fatalf("projectOrder: %v", err)
+	}
+	data, err := json.Marshal(projected)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	want := `{"items":[{"price":9007199254740995}],"order_uid":"o1","payment":{"amount":9007199254740993}}`
+	if got := string(data); got != want {
+		t.Errorf("projection changed numbers:\n got %s\nwant %s", got, want)
+	}
+}
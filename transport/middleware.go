@@ -0,0 +1,149 @@
+package httptransport
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+)
+
+// requestIDHeader заголовок с идентификатором запроса.
+const requestIDHeader = "X-Request-ID"
+
+// log возвращает логгер запроса с его идентификатором.
+func (t *httpTransport) log(r *http.Request) *slog.Logger {
+	return logging.FromContext(r.Context(), t.logger)
+}
+
+// withRequestID назначает запросу идентификатор: принимает корректный
+// X-Request-ID клиента или генерирует новый. Идентификатор возвращается в
+// ответе и добавляется ко всем записям журнала, сделанным при обработке.
+func (t *httpTransport) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !logging.ValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		logger := t.logger.With(slog.String("request_id", requestID))
+		next.ServeHTTP(w, r.WithContext(logging.WithLogger(r.Context(), logger)))
+	})
+}
+
+// statusRecorder запоминает код ответа и количество записанных байт.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush передает сброс буфера исходному ResponseWriter, если он это умеет.
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withAccessLog пишет одну запись журнала на каждый запрос: метод, шаблон
+// маршрута, код ответа, размер и время обработки. Запись делается и для
+// прерванного запроса (паника, в том числе http.ErrAbortHandler, которую
+// withRecovery передает серверу дальше): такой запрос помечается aborted.
+func (t *httpTransport) withAccessLog(router *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		completed := false
+
+		defer func() {
+			status := recorder.status
+			if status == 0 && completed {
+				status = http.StatusOK
+			}
+			// Шаблон маршрута вместо пути, чтобы идентификаторы заказов не
+			// раздували число уникальных значений
+			_, route := router.Handler(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			attrs := []any{
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote", t.clientIP(r)),
+			}
+			if !completed {
+				t.log(r).Warn("HTTP request aborted", append(attrs, slog.Bool("aborted", true))...)
+				return
+			}
+			t.log(r).Info("HTTP request", attrs...)
+		}()
+
+		next.ServeHTTP(recorder, r)
+		completed = true
+	})
+}
+
+// withRecovery перехватывает панику обработчика, пишет ее в журнал со стеком
+// и отвечает 500, если ответ еще не начат.
+func (t *httpTransport) withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder, ok := w.(*statusRecorder)
+		if !ok {
+			recorder = &statusRecorder{ResponseWriter: w}
+		}
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// http.ErrAbortHandler намеренно прерывает ответ, сервер обрабатывает его сам
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			t.log(r).Error("Panic while handling request",
+				slog.Any("panic", recovered),
+				slog.String("stack", string(debug.Stack())),
+			)
+			if recorder.status == 0 {
+				http.Error(recorder, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// handler собирает цепочку middleware вокруг таблицы маршрутов.
+func (t *httpTransport) handler() http.Handler {
+	router := t.routes()
+	return t.withRequestID(t.withAccessLog(router, t.withRecovery(t.withLimits(router))))
+}
@@ -0,0 +1,146 @@
+package httptransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/logging"
+)
+
+// logRecords разбирает журнал в формате JSON по записи на строку.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("parse log record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// middlewareHandler собирает цепочку middleware, как handler, вокруг
+// маршрута GET /test с обработчиком next; журнал пишется в buf.
+func middlewareHandler(buf *bytes.Buffer, next http.HandlerFunc) http.Handler {
+	transport := &httpTransport{logger: slog.New(slog.NewJSONHandler(buf, nil))}
+	router := http.NewServeMux()
+	router.HandleFunc("GET /test", next)
+	return transport.withRequestID(transport.withAccessLog(router, transport.withRecovery(router)))
+}
+
+func TestRequestID(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	tests := []struct {
+		name   string
+		header string
+		want   string // пустое значение — идентификатор генерируется
+	}{
+		{name: "accepted", header: "client-id-42", want: "client-id-42"},
+		{name: "missing"},
+		{name: "with spaces", header: "bad id"},
+		{name: "too long", header: strings.Repeat("a", logging.MaxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := middlewareHandler(&buf, func(w http.ResponseWriter, r *http.Request) {
+				logging.FromContext(r.Context(), slog.Default()).Info("inside handler")
+			})
+			r := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.header != "" {
+				r.Header.Set(requestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			requestID := w.Header().Get(requestIDHeader)
+			if tt.want != "" && requestID != tt.want {
+				t.Errorf("%s = %q, want %q", requestIDHeader, requestID, tt.want)
+			}
+			if tt.want == "" && !generated.MatchString(requestID) {
+				t.Errorf("%s = %q, want a generated one", requestIDHeader, requestID)
+			}
+
+			records := logRecords(t, &buf)
+			if len(records) != 2 {
+				t.Fatalf("got %d log records, want the handler's and the access log's:\n%s", len(records), buf.String())
+			}
+			for _, record := range records {
+				if record["request_id"] != requestID {
+					t.Errorf("record %q has request_id %v, want %q", record["msg"], record["request_id"], requestID)
+				}
+			}
+		})
+	}
+}
+
+func TestRecoveryLogsPanic(t *testing.T) {
+	var buf bytes.Buffer
+	handler := middlewareHandler(&buf, func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	var panics int
+	for _, record := range logRecords(t, &buf) {
+		switch record["msg"] {
+		case "Panic while handling request":
+			panics++
+			if record["panic"] != "boom" {
+				t.Errorf("panic = %v, want boom", record["panic"])
+			}
+			if stack, _ := record["stack"].(string); !strings.Contains(stack, "middleware_test.go") {
+				t.Errorf("stack does not point at the handler:\n%s", stack)
+			}
+		case "HTTP request":
+			if record["status"] != float64(http.StatusInternalServerError) {
+				t.Errorf("access log status = %v, want 500", record["status"])
+			}
+		}
+	}
+	if panics != 1 {
+		t.Errorf("got %d panic records, want 1:\n%s", panics, buf.String())
+	}
+}
+
+func TestRecoveryRepanicsAbortHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := middlewareHandler(&buf, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic(http.ErrAbortHandler)
+	})
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", recovered)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	}()
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("got %d log records, want only the access log:\n%s", len(records), buf.String())
+	}
+	if records[0]["msg"] != "HTTP request aborted" || records[0]["aborted"] != true || records[0]["route"] != "GET /test" {
+		t.Errorf("access log record %v, want an aborted GET /test", records[0])
+	}
+}
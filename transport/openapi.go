@@ -0,0 +1,592 @@
+package httptransport
+
+//go:generate go test -run TestAPIDocuments . -update
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/jsonschema"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+const (
+	openAPIPattern     = "GET /openapi.json"
+	orderSchemaPattern = "GET /schemas/order.json"
+	// orderSchemaID $id схемы заказа — адрес, по которому она отдается.
+	orderSchemaID = "/schemas/order.json"
+	// specCacheControl описание API меняется только с выкатом новой версии.
+	specCacheControl = "public, max-age=300"
+)
+
+// apiOperation описание маршрута API для документа OpenAPI. Параметры пути
+// берутся из шаблона маршрута, ответы 401/403 и 429/503 добавляются по
+// настройкам доступа и ограничений.
+type apiOperation struct {
+	id          string
+	summary     string
+	description string
+	query       []apiParam
+	body        []apiContent
+	responses   []apiResponse
+}
+
+// apiParam параметр строки запроса.
+type apiParam struct {
+	name        string
+	description string
+	required    bool
+	schema      *jsonschema.Schema
+}
+
+// apiResponse ответ операции.
+type apiResponse struct {
+	status      int
+	description string
+	content     []apiContent
+}
+
+// apiContent тело запроса или ответа: Go-тип для JSON или готовая схема.
+type apiContent struct {
+	mediaType string
+	typ       reflect.Type
+	schema    *jsonschema.Schema
+}
+
+func jsonContent(v any) apiContent {
+	return apiContent{mediaType: "application/json", typ: reflect.TypeOf(v)}
+}
+
+func textContent(mediaType string) apiContent {
+	return apiContent{mediaType: mediaType, schema: stringSchema("")}
+}
+
+func stringSchema(description string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: jsonschema.Type{"string"}, Description: description}
+}
+
+// errorResponse ответ об ошибке: http.Error отдает текст.
+func errorResponse(status int, description string) apiResponse {
+	return apiResponse{status: status, description: description, content: []apiContent{textContent("text/plain")}}
+}
+
+var (
+	notFoundResponse = errorResponse(http.StatusNotFound, "Order not found")
+	internalResponse = errorResponse(http.StatusInternalServerError, "Storage error")
+)
+
+// pathParams описания параметров пути по имени в шаблоне.
+var pathParams = map[string]string{
+	"uid": "Order UID (order_uid)",
+}
+
+// apiSchemaTypes имена структур ответов в components/schemas в дополнение
+// к типам заказа.
+var apiSchemaTypes = map[reflect.Type]string{
+	reflect.TypeOf(ingestResult{}):              "IngestResult",
+	reflect.TypeOf(batchResponse{}):             "BatchResponse",
+	reflect.TypeOf(statusCatalog{}):             "StatusCatalog",
+	reflect.TypeOf(orderState{}):                "OrderStateResponse",
+	reflect.TypeOf(ristrettocache.CacheStats{}): "CacheStats",
+}
+
+// graphQLRequest тело POST /graphql.
+type graphQLRequest struct {
+	Query         string         `json:"query" jsonschema:"required"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// graphQLResponse ответ GraphQL: данные и ошибки выполнения.
+type graphQLResponse struct {
+	Data   map[string]any   `json:"data,omitempty"`
+	Errors []map[string]any `json:"errors,omitempty"`
+}
+
+var fieldsParam = apiParam{
+	name:        "fields",
+	description: "Comma-separated fields to return, nested fields with dots or parentheses: order_uid,payment.amount,items(name,price)",
+	schema:      stringSchema(""),
+}
+
+// apiOperations описания маршрутов API по шаблону ServeMux.
+var apiOperations = map[string]apiOperation{
+	"GET /api/v1/orders/{uid}": {
+		id:          "getOrder",
+		summary:     "Get an order",
+		description: "Personal data is masked according to the client's scopes. The full response carries an ETag and honors If-None-Match.",
+		query:       []apiParam{fieldsParam},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Order", content: []apiContent{jsonContent(model.OrderDetails{})}},
+			{status: http.StatusNotModified, description: "Order has not changed since the ETag in If-None-Match"},
+			errorResponse(http.StatusBadRequest, "Invalid fields parameter"),
+			notFoundResponse, internalResponse,
+		},
+	},
+	"GET /api/v1/orders/{uid}/delivery": {
+		id:      "getOrderDelivery",
+		summary: "Get order delivery details",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Delivery details", content: []apiContent{jsonContent(model.AddressDetails{})}},
+			notFoundResponse, internalResponse,
+		},
+	},
+	"GET /api/v1/orders/{uid}/payment": {
+		id:      "getOrderPayment",
+		summary: "Get order payment details",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Payment details", content: []apiContent{jsonContent(model.PaymentDetails{})}},
+			notFoundResponse, internalResponse,
+		},
+	},
+	"GET /api/v1/orders/{uid}/items": {
+		id:      "getOrderItems",
+		summary: "Get order items",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Order items", content: []apiContent{jsonContent([]model.ProductItem{})}},
+			notFoundResponse, internalResponse,
+		},
+	},
+	"GET /api/v1/orders/{uid}/state": {
+		id:      "getOrderState",
+		summary: "Get the order state derived from item statuses",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Order state and item statuses", content: []apiContent{jsonContent(orderState{})}},
+			notFoundResponse, internalResponse,
+		},
+	},
+	"GET /api/v1/statuses": {
+		id:      "listStatuses",
+		summary: "List item statuses with allowed transitions and order states",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Status catalog", content: []apiContent{jsonContent(statusCatalog{})}},
+		},
+	},
+	"GET /api/v1/currencies": {
+		id:          "listCurrencies",
+		summary:     "List currency minor unit exponents",
+		description: "Amounts are in minor units of payment.currency; divide by 10^exponent to get major units.",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Exponent of every known ISO 4217 currency", content: []apiContent{jsonContent(currencyCatalog{})}},
+		},
+	},
+	"GET /api/v1/order": {
+		id:          "getOrderLegacy",
+		summary:     "Get an order (legacy address)",
+		description: "Same as GET /api/v1/orders/{uid}.",
+		query: []apiParam{
+			{name: "order_uid", description: "Order UID", required: true, schema: stringSchema("")},
+			fieldsParam,
+		},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Order", content: []apiContent{jsonContent(model.OrderDetails{})}},
+			{status: http.StatusNotModified, description: "Order has not changed since the ETag in If-None-Match"},
+			errorResponse(http.StatusBadRequest, "Missing order_uid or invalid fields parameter"),
+			notFoundResponse, internalResponse,
+		},
+	},
+	"GET /api/v1/orders:export": {
+		id:          "exportOrders",
+		summary:     "Export orders",
+		description: "Streams orders ordered by date_created. If the export fails midway the connection is aborted, so a complete response is never truncated silently.",
+		query: []apiParam{
+			{name: "format", description: "Export format", schema: &jsonschema.Schema{Type: jsonschema.Type{"string"}, Enum: []any{"ndjson", "csv"}}},
+			{name: "created_from", description: "Only orders created at or after this time (RFC 3339 or date)", schema: stringSchema("")},
+			{name: "created_to", description: "Only orders created before this time (RFC 3339 or date)", schema: stringSchema("")},
+			{name: "customer_id", description: "Only orders of this customer", schema: stringSchema("")},
+			{name: "delivery_service", description: "Only orders of this delivery service", schema: stringSchema("")},
+			{name: "limit", description: "Maximum number of orders", schema: &jsonschema.Schema{Type: jsonschema.Type{"integer"}, Minimum: jsonschema.Ptr(1.0)}},
+		},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "One order per line (ndjson) or one item per row (csv)", content: []apiContent{
+				{mediaType: "application/x-ndjson", schema: stringSchema("Orders in the Order schema, one per line")},
+				textContent("text/csv"),
+			}},
+			errorResponse(http.StatusBadRequest, "Invalid format or filter"),
+			errorResponse(http.StatusInternalServerError, "Export failed"),
+		},
+	},
+	"GET /graphql": {
+		id:          "graphQLQuery",
+		summary:     "Run a GraphQL query",
+		description: "Queries only; mutations must use POST.",
+		query: []apiParam{
+			{name: "query", description: "GraphQL query", required: true, schema: stringSchema("")},
+			{name: "operationName", description: "Operation to run", schema: stringSchema("")},
+			{name: "variables", description: "Variables as a JSON object", schema: stringSchema("")},
+		},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "GraphQL result", content: []apiContent{jsonContent(graphQLResponse{})}},
+			errorResponse(http.StatusBadRequest, "Invalid request"),
+		},
+	},
+	"POST /graphql": {
+		id:      "graphQLExecute",
+		summary: "Run a GraphQL query",
+		body:    []apiContent{jsonContent(graphQLRequest{})},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "GraphQL result", content: []apiContent{jsonContent(graphQLResponse{})}},
+			errorResponse(http.StatusBadRequest, "Invalid request"),
+			errorResponse(http.StatusRequestEntityTooLarge, "Request body too large"),
+		},
+	},
+	"POST /api/v1/orders": {
+		id:          "createOrder",
+		summary:     "Submit an order",
+		description: "The order is validated like a Kafka message and then published to Kafka or stored directly, depending on the server mode.",
+		body:        []apiContent{jsonContent(model.OrderDetails{})},
+		responses: []apiResponse{
+			{status: http.StatusCreated, description: "Order stored", content: []apiContent{jsonContent(ingestResult{})}},
+			{status: http.StatusAccepted, description: "Order published to Kafka", content: []apiContent{jsonContent(ingestResult{})}},
+			{status: http.StatusOK, description: "Order already exists", content: []apiContent{jsonContent(ingestResult{})}},
+			errorResponse(http.StatusBadRequest, "Failed to read request body"),
+			errorResponse(http.StatusRequestEntityTooLarge, "Request body too large"),
+			{status: http.StatusUnprocessableEntity, description: "Invalid order", content: []apiContent{jsonContent(ingestResult{})}},
+			{status: http.StatusInternalServerError, description: "Failed to store or publish the order", content: []apiContent{jsonContent(ingestResult{})}},
+		},
+	},
+	"POST /api/v1/orders:batch": {
+		id:          "createOrdersBatch",
+		summary:     "Submit a batch of orders",
+		description: "Each order is processed independently; the response lists a result per order in request order.",
+		body: []apiContent{
+			jsonContent([]model.OrderDetails{}),
+			{mediaType: "application/x-ndjson", schema: stringSchema("Orders in the Order schema, one per line")},
+		},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Per-order results and counts by status", content: []apiContent{jsonContent(batchResponse{})}},
+			errorResponse(http.StatusBadRequest, "Malformed or empty batch"),
+			errorResponse(http.StatusRequestEntityTooLarge, "Request body too large or too many orders"),
+		},
+	},
+	"POST /api/v1/admin/cache/evict": {
+		id:      "evictCache",
+		summary: "Evict an order or all orders of a customer from the cache",
+		query: []apiParam{
+			{name: "order_uid", description: "Order to evict; exactly one of order_uid and customer_id is required", schema: stringSchema("")},
+			{name: "customer_id", description: "Customer whose orders to evict", schema: stringSchema("")},
+		},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Number of evicted orders", content: []apiContent{jsonContent(evictResponse{})}},
+			errorResponse(http.StatusBadRequest, "Neither or both of order_uid and customer_id given"),
+			errorResponse(http.StatusInternalServerError, "Eviction failed"),
+		},
+	},
+	"POST /api/v1/admin/cache/flush": {
+		id:      "flushCache",
+		summary: "Flush the cache",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Cache flushed", content: []apiContent{jsonContent(flushResponse{})}},
+			errorResponse(http.StatusInternalServerError, "Flush failed"),
+		},
+	},
+	"POST /api/v1/admin/cache/warmup": {
+		id:      "warmupCache",
+		summary: "Load the latest orders into the cache",
+		query: []apiParam{
+			{name: "limit", description: fmt.Sprintf("Number of orders to load, %d by default", defaultWarmupLimit),
+				schema: &jsonschema.Schema{Type: jsonschema.Type{"integer"}, Minimum: jsonschema.Ptr(1.0)}},
+		},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Number of loaded orders", content: []apiContent{jsonContent(warmupResponse{})}},
+			errorResponse(http.StatusBadRequest, "Invalid limit"),
+			errorResponse(http.StatusInternalServerError, "Warmup failed"),
+		},
+	},
+	"GET /api/v1/admin/cache/stats": {
+		id:      "getCacheStats",
+		summary: "Get cache statistics",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Cache statistics", content: []apiContent{jsonContent(ristrettocache.CacheStats{})}},
+		},
+	},
+	"POST /api/v1/admin/cache/verify": {
+		id:      "verifyCache",
+		summary: "Compare cached orders with the database",
+		query: []apiParam{
+			{name: "repair", description: "Evict mismatching entries from the cache", schema: &jsonschema.Schema{Type: jsonschema.Type{"boolean"}}},
+		},
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Cache entries that differ from the database, field by field", content: []apiContent{jsonContent(ristrettocache.VerifyReport{})}},
+			errorResponse(http.StatusBadRequest, "Invalid repair"),
+			errorResponse(http.StatusInternalServerError, "Verification failed"),
+		},
+	},
+	"GET /orders/{uid}/invoice": {
+		id:      "getOrderInvoice",
+		summary: "Get a printable order invoice",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "Invoice page", content: []apiContent{textContent("text/html")}},
+			notFoundResponse,
+			errorResponse(http.StatusInternalServerError, "Failed to render the invoice"),
+		},
+	},
+	openAPIPattern: {
+		id:      "getOpenAPI",
+		summary: "Get this OpenAPI document",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "OpenAPI 3.1 document", content: []apiContent{{mediaType: "application/json", schema: &jsonschema.Schema{Type: jsonschema.Type{"object"}}}}},
+		},
+	},
+	orderSchemaPattern: {
+		id:          "getOrderSchema",
+		summary:     "Get the JSON Schema of order messages",
+		description: "The contract for orders sent to Kafka and POST /api/v1/orders.",
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "JSON Schema (draft 2020-12)", content: []apiContent{{mediaType: "application/schema+json", schema: &jsonschema.Schema{Type: jsonschema.Type{"object"}}}}},
+		},
+	},
+}
+
+// openAPIDocument документ OpenAPI 3.1.
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*jsonschema.Schema    `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type openAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string             `json:"name"`
+	In          string             `json:"in"`
+	Description string             `json:"description,omitempty"`
+	Required    bool               `json:"required,omitempty"`
+	Schema      *jsonschema.Schema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                    `json:"required"`
+	Content  map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema *jsonschema.Schema `json:"schema"`
+}
+
+// apiSpec сериализованные документы, которые отдает сервер.
+type apiSpec struct {
+	openAPI     []byte
+	orderSchema []byte
+}
+
+// buildSpec строит документ OpenAPI для зарегистрированных маршрутов и схему
+// заказа. Маршрут без описания в apiOperations — ошибка.
+func (t *httpTransport) buildSpec(api []route) (*apiSpec, error) {
+	names := model.SchemaTypes()
+	for typ, name := range apiSchemaTypes {
+		names[typ] = name
+	}
+	reflector := &jsonschema.Reflector{Names: names, RefPrefix: "#/components/schemas/"}
+	content := func(contents []apiContent) (map[string]openAPIMedia, error) {
+		media := make(map[string]openAPIMedia, len(contents))
+		for _, c := range contents {
+			schema := c.schema
+			if c.typ != nil {
+				var err error
+				if schema, err = reflector.Reflect(c.typ); err != nil {
+					return nil, err
+				}
+			}
+			media[c.mediaType] = openAPIMedia{Schema: schema}
+		}
+		return media, nil
+	}
+
+	doc := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       "WB Tech L0 orders API",
+			Version:     strconv.Itoa(model.SchemaVersion),
+			Description: "Order lookup, export and ingestion. The version is the order message schema version.",
+		},
+		Paths: make(map[string]map[string]*openAPIOperation),
+	}
+
+	for _, r := range api {
+		spec, ok := apiOperations[r.pattern]
+		if !ok {
+			return nil, fmt.Errorf("route %q has no description in apiOperations", r.pattern)
+		}
+		method, path, _ := strings.Cut(r.pattern, " ")
+
+		op := &openAPIOperation{
+			OperationID: spec.id,
+			Summary:     spec.summary,
+			Description: spec.description,
+			Responses:   make(map[string]openAPIResponse),
+		}
+		for _, segment := range strings.Split(path, "/") {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				name = strings.TrimSuffix(name, "}")
+				op.Parameters = append(op.Parameters, openAPIParameter{
+					Name: name, In: "path", Description: pathParams[name], Required: true, Schema: stringSchema(""),
+				})
+			}
+		}
+		for _, param := range spec.query {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name: param.name, In: "query", Description: param.description, Required: param.required, Schema: param.schema,
+			})
+		}
+		if spec.body != nil {
+			media, err := content(spec.body)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", r.pattern, err)
+			}
+			op.RequestBody = &openAPIRequestBody{Required: true, Content: media}
+		}
+
+		responses := spec.responses
+		var unavailable []string
+		if r.scope != "" && t.authEnabled() {
+			op.Security = []map[string][]string{{"apiKey": {r.scope}}, {"bearer": {r.scope}}}
+			responses = append(responses[:len(responses):len(responses)],
+				errorResponse(http.StatusUnauthorized, "Missing or invalid credentials"),
+				errorResponse(http.StatusForbidden, "Scope "+r.scope+" is required"))
+			unavailable = append(unavailable, "Credentials could not be checked")
+		}
+		if t.limits.RateLimit > 0 {
+			responses = append(responses[:len(responses):len(responses)], errorResponse(http.StatusTooManyRequests, "Rate limit exceeded, see Retry-After"))
+		}
+		if t.limits.MaxConcurrent > 0 {
+			unavailable = append(unavailable, "Server is busy, see Retry-After")
+		}
+		if len(unavailable) > 0 {
+			responses = append(responses[:len(responses):len(responses)], errorResponse(http.StatusServiceUnavailable, strings.Join(unavailable, "; ")))
+		}
+		for _, response := range responses {
+			media, err := content(response.content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", r.pattern, err)
+			}
+			if len(media) == 0 {
+				media = nil
+			}
+			op.Responses[strconv.Itoa(response.status)] = openAPIResponse{Description: response.description, Content: media}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(method)] = op
+	}
+
+	doc.Components.Schemas = reflector.Defs
+	if t.authEnabled() {
+		doc.Components.SecuritySchemes = map[string]openAPISecurityScheme{
+			"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "API key; also accepted as Authorization: ApiKey <key>"},
+			"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "JWT with the scopes in the scope or scp claim"},
+		}
+	}
+
+	orderSchema, err := model.OrderSchema(orderSchemaID)
+	if err != nil {
+		return nil, err
+	}
+	spec := &apiSpec{}
+	if spec.openAPI, err = marshalSpec(doc); err != nil {
+		return nil, err
+	}
+	if spec.orderSchema, err = marshalSpec(orderSchema); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// marshalSpec сериализует документ с отступами: он же сохраняется в репозитории.
+func marshalSpec(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// specRoutes регистрирует /openapi.json и /schemas/order.json.
+func (t *httpTransport) specRoutes(router *http.ServeMux, spec *apiSpec) {
+	serve := func(name string, data []byte, contentType string) http.HandlerFunc {
+		sum := sha256.Sum256(data)
+		asset := &staticAsset{name: name, data: data, etag: `"` + hex.EncodeToString(sum[:16]) + `"`, contentType: contentType}
+		return func(w http.ResponseWriter, r *http.Request) {
+			asset.serve(w, r, specCacheControl)
+		}
+	}
+	router.HandleFunc(openAPIPattern, serve("openapi.json", spec.openAPI, "application/json"))
+	router.HandleFunc(orderSchemaPattern, serve("order.json", spec.orderSchema, "application/schema+json"))
+}
+
+// specStub заглушка зависимостей для документа со всеми маршрутами: ее
+// методы не вызываются, маршруты только регистрируются.
+type specStub struct {
+	Exporter
+	Publisher
+	CacheAdmin
+	Authenticator
+}
+
+// Spec возвращает документ OpenAPI со всеми маршрутами, которые может
+// включить сервер, и JSON Schema заказа — то, что хранится в каталоге api.
+// Сервер отдает документ только с включенными у него маршрутами.
+func Spec() (openAPI, orderSchema []byte, err error) {
+	t := NewHTTPTransport(nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithExport(specStub{}),
+		WithGraphQL(http.NotFoundHandler()),
+		WithIngestion(IngestKafka, specStub{}),
+		WithCacheAdmin(specStub{}),
+		WithAuthentication(nil, specStub{}),
+	).(*httpTransport)
+
+	spec, err := t.buildSpec(t.apiRoutes(http.NewServeMux()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return spec.openAPI, spec.orderSchema, nil
+}
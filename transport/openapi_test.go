@@ -0,0 +1,41 @@
+package httptransport
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update перезаписывает документы в каталоге api: go test ./transport -update.
+var update = flag.Bool("update", false, "rewrite the API documents in the api directory")
+
+// TestAPIDocuments проверяет, что сохраненные в каталоге api документы — контракт
+// для внешних производителей заказов — совпадают с построенными по коду.
+func TestAPIDocuments(t *testing.T) {
+	openAPI, orderSchema, err := Spec()
+	if err != nil {
+		t.Fatalf("Spec: %v", err)
+	}
+
+	documents := map[string][]byte{"openapi.json": openAPI, "order.schema.json": orderSchema}
+	for name, want := range documents {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join("..", "api", name)
+			if *update {
+				if err := os.WriteFile(path, want, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s is out of date with the code, run go test ./transport -update", path)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package httptransport
+
+import "net/http"
+
+// route маршрут API: шаблон ServeMux и требуемая область доступа (пустая —
+// маршрут открыт всем).
+type route struct {
+	pattern string
+	scope   string
+}
+
+// routes строит таблицу маршрутов сервера. Шаблоны ServeMux (Go 1.22) сами
+// отвечают 405 с заголовком Allow на неподдерживаемый метод, а маршруты GET
+// обслуживают и HEAD.
+func (t *httpTransport) routes() *http.ServeMux {
+	router := http.NewServeMux()
+
+	spec, err := t.buildSpec(t.apiRoutes(router))
+	if err != nil {
+		// Описания маршрутов заданы в коде, поэтому это ошибка сборки
+		panic("failed to build OpenAPI document: " + err.Error())
+	}
+	t.specRoutes(router, spec)
+
+	// Пользовательский интерфейс
+	t.frontendRoutes(router)
+
+	return router
+}
+
+// apiRoutes регистрирует маршруты API и возвращает их для документа OpenAPI,
+// поэтому у каждого маршрута должно быть описание в apiOperations.
+func (t *httpTransport) apiRoutes(router *http.ServeMux) []route {
+	var api []route
+	handle := func(pattern, scope string, handler http.HandlerFunc) {
+		router.HandleFunc(pattern, t.authorize(scope, handler))
+		api = append(api, route{pattern: pattern, scope: scope})
+	}
+
+	// Заказы
+	handle("GET /api/v1/orders/{uid}", ScopeOrdersRead, t.orderHandler)
+	handle("GET /api/v1/orders/{uid}/delivery", ScopeOrdersRead, t.orderDeliveryHandler)
+	handle("GET /api/v1/orders/{uid}/payment", ScopeOrdersRead, t.orderPaymentHandler)
+	handle("GET /api/v1/orders/{uid}/items", ScopeOrdersRead, t.orderItemsHandler)
+	handle("GET /api/v1/orders/{uid}/state", ScopeOrdersRead, t.orderStateHandler)
+	handle("GET /api/v1/statuses", ScopeOrdersRead, t.statusesHandler)
+	handle("GET /api/v1/currencies", ScopeOrdersRead, t.currenciesHandler)
+
+	// Совместимость со старым адресом /api/v1/order?order_uid=...
+	handle("GET /api/v1/order", ScopeOrdersRead, t.legacyOrderHandler)
+
+	// Выгрузка заказов
+	if t.exporter != nil {
+		handle("GET /api/v1/orders:export", ScopeOrdersExport, t.exportOrdersHandler)
+	}
+
+	// GraphQL
+	if t.graphql != nil {
+		handle("GET /graphql", ScopeOrdersRead, t.graphQLHandler)
+		handle("POST /graphql", ScopeOrdersRead, t.graphQLHandler)
+	}
+
+	// Прием заказов
+	if t.ingestMode != "" {
+		handle("POST /api/v1/orders", ScopeOrdersWrite, t.createOrderHandler)
+		handle("POST /api/v1/orders:batch", ScopeOrdersWrite, t.createOrdersBatchHandler)
+	}
+
+	// Администрирование кэша доступно только при включенной проверке доступа
+	if t.admin != nil && t.authEnabled() {
+		handle("POST /api/v1/admin/cache/evict", ScopeOrdersAdmin, t.cacheEvictHandler)
+		handle("POST /api/v1/admin/cache/flush", ScopeOrdersAdmin, t.cacheFlushHandler)
+		handle("POST /api/v1/admin/cache/warmup", ScopeOrdersAdmin, t.cacheWarmupHandler)
+		handle("GET /api/v1/admin/cache/stats", ScopeOrdersAdmin, t.cacheStatsHandler)
+		handle("POST /api/v1/admin/cache/verify", ScopeOrdersAdmin, t.cacheVerifyHandler)
+	}
+
+	// Печатная накладная
+	handle("GET /orders/{uid}/invoice", ScopeOrdersRead, t.orderInvoiceHandler)
+
+	// Описание API открыто всем: по нему производители заказов сверяют формат.
+	// Сами документы регистрирует specRoutes, когда они построены
+	return append(api, route{pattern: openAPIPattern}, route{pattern: orderSchemaPattern})
+}
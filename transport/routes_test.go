@@ -0,0 +1,147 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// stubOrderStore отдает заказы из памяти.
+type stubOrderStore struct {
+	stubIngestStore
+	orders map[string]*model.OrderDetails
+}
+
+func (s *stubOrderStore) GetOrder(_ context.Context, orderUID string) (*model.OrderDetails, error) {
+	if order, ok := s.orders[orderUID]; ok {
+		return order, nil
+	}
+	return nil, model.ErrOrderNotFound
+}
+
+// newRoutesHandler собирает полный обработчик сервера с одним заказом и
+// приемом заказов через Kafka.
+func newRoutesHandler(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	order, err := model.ParseOrder(readIngestOrder(t), model.MaxItems)
+	if err != nil {
+		t.Fatalf("ParseOrder: %v", err)
+	}
+	store := &stubOrderStore{orders: map[string]*model.OrderDetails{order.OrderID: order}}
+	transport := NewHTTPTransport(store, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithIngestion(IngestKafka, &stubPublisher{}),
+		WithLimits(Limits{MaxBodyBytes: 1 << 20, MaxBatchBodyBytes: 1 << 20}),
+	).(*httpTransport)
+	return transport.handler(), order.OrderID
+}
+
+func TestRoutes(t *testing.T) {
+	handler, orderUID := newRoutesHandler(t)
+	// Настоящий сервер: ответ на HEAD без тела формирует net/http, а не ResponseRecorder
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+		// wantBody подстрока тела; для HEAD тело должно быть пустым
+		wantBody string
+	}{
+		{name: "order", method: http.MethodGet, path: "/api/v1/orders/" + orderUID, wantStatus: http.StatusOK, wantBody: `"order_uid":"` + orderUID + `"`},
+		{name: "legacy alias", method: http.MethodGet, path: "/api/v1/order?order_uid=" + orderUID, wantStatus: http.StatusOK, wantBody: `"order_uid":"` + orderUID + `"`},
+		{name: "legacy alias without uid", method: http.MethodGet, path: "/api/v1/order", wantStatus: http.StatusBadRequest},
+		{name: "legacy alias of a missing order", method: http.MethodGet, path: "/api/v1/order?order_uid=missing", wantStatus: http.StatusNotFound},
+		{name: "delivery", method: http.MethodGet, path: "/api/v1/orders/" + orderUID + "/delivery", wantStatus: http.StatusOK, wantBody: `"address":`},
+		{name: "payment", method: http.MethodGet, path: "/api/v1/orders/" + orderUID + "/payment", wantStatus: http.StatusOK, wantBody: `"transaction":`},
+		{name: "items", method: http.MethodGet, path: "/api/v1/orders/" + orderUID + "/items", wantStatus: http.StatusOK, wantBody: `[{"chrt_id":`},
+		{name: "state", method: http.MethodGet, path: "/api/v1/orders/" + orderUID + "/state", wantStatus: http.StatusOK, wantBody: `"state":`},
+		{name: "missing order", method: http.MethodGet, path: "/api/v1/orders/missing", wantStatus: http.StatusNotFound},
+		{name: "sub-resource of a missing order", method: http.MethodGet, path: "/api/v1/orders/missing/items", wantStatus: http.StatusNotFound},
+		{name: "unknown sub-resource", method: http.MethodGet, path: "/api/v1/orders/" + orderUID + "/unknown", wantStatus: http.StatusNotFound},
+
+		{name: "head order", method: http.MethodHead, path: "/api/v1/orders/" + orderUID, wantStatus: http.StatusOK},
+		{name: "head sub-resource", method: http.MethodHead, path: "/api/v1/orders/" + orderUID + "/payment", wantStatus: http.StatusOK},
+		{name: "head legacy alias", method: http.MethodHead, path: "/api/v1/order?order_uid=" + orderUID, wantStatus: http.StatusOK},
+		{name: "head missing order", method: http.MethodHead, path: "/api/v1/orders/missing", wantStatus: http.StatusNotFound},
+
+		{name: "delete order", method: http.MethodDelete, path: "/api/v1/orders/" + orderUID, wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD"},
+		{name: "post sub-resource", method: http.MethodPost, path: "/api/v1/orders/" + orderUID + "/items", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD"},
+		{name: "post legacy alias", method: http.MethodPost, path: "/api/v1/order", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD"},
+		{name: "get ingestion", method: http.MethodGet, path: "/api/v1/orders", wantStatus: http.StatusMethodNotAllowed, wantAllow: "POST"},
+		{name: "put batch", method: http.MethodPut, path: "/api/v1/orders:batch", wantStatus: http.StatusMethodNotAllowed, wantAllow: "POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("read body: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if allow := resp.Header.Get("Allow"); allow != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.wantAllow)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+			if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", resp.Header.Get("Content-Type"))
+			}
+			if tt.method == http.MethodHead {
+				if len(body) != 0 {
+					t.Errorf("HEAD response has a body: %s", body)
+				}
+				return
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body %s, want it to contain %s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestLegacyOrderMatchesOrderRoute(t *testing.T) {
+	handler, orderUID := newRoutesHandler(t)
+
+	var bodies [2]map[string]any
+	for i, path := range []string{"/api/v1/orders/" + orderUID, "/api/v1/order?order_uid=" + orderUID} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if err := json.Unmarshal(w.Body.Bytes(), &bodies[i]); err != nil {
+			t.Fatalf("%s: %v: %s", path, err, w.Body)
+		}
+	}
+	if a, b := mustJSON(t, bodies[0]), mustJSON(t, bodies[1]); a != b {
+		t.Errorf("legacy response differs:\n%s\n%s", b, a)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,92 @@
+package httptransport
+
+import (
+	"net/http"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// statusDescription статус товара в справочнике статусов.
+type statusDescription struct {
+	Code        model.ItemStatus   `json:"code"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Next        []model.ItemStatus `json:"next"`
+}
+
+// stateDescription состояние заказа в справочнике статусов.
+type stateDescription struct {
+	Name        model.OrderState `json:"name"`
+	Description string           `json:"description"`
+}
+
+// statusCatalog ответ GET /api/v1/statuses.
+type statusCatalog struct {
+	ItemStatuses []statusDescription `json:"item_statuses"`
+	OrderStates  []stateDescription  `json:"order_states"`
+}
+
+// itemState статус товара в ответе GET /api/v1/orders/{uid}/state.
+type itemState struct {
+	ChartID     int              `json:"chrt_id"`
+	Status      model.ItemStatus `json:"status"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+}
+
+// orderState ответ GET /api/v1/orders/{uid}/state.
+type orderState struct {
+	OrderUID    string           `json:"order_uid"`
+	State       model.OrderState `json:"state"`
+	Description string           `json:"description"`
+	Items       []itemState      `json:"items"`
+}
+
+// statusesHandler возвращает справочник статусов товара с допустимыми
+// переходами и состояний заказа.
+func (t *httpTransport) statusesHandler(w http.ResponseWriter, r *http.Request) {
+	catalog := statusCatalog{}
+	for _, status := range model.ItemStatuses() {
+		next := status.Next()
+		if next == nil {
+			next = []model.ItemStatus{}
+		}
+		catalog.ItemStatuses = append(catalog.ItemStatuses, statusDescription{
+			Code:        status,
+			Name:        status.Name(),
+			Description: status.Description(),
+			Next:        next,
+		})
+	}
+	for _, state := range model.OrderStates() {
+		catalog.OrderStates = append(catalog.OrderStates, stateDescription{Name: state, Description: state.Description()})
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	t.writeJSON(w, catalog)
+}
+
+// orderStateHandler возвращает состояние заказа и статусы товаров с именами.
+func (t *httpTransport) orderStateHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := t.lookupOrder(w, r)
+	if !ok {
+		return
+	}
+
+	state := order.State()
+	response := orderState{
+		OrderUID:    order.OrderID,
+		State:       state,
+		Description: state.Description(),
+		Items:       make([]itemState, 0, len(order.Products)),
+	}
+	for _, item := range order.Products {
+		response.Items = append(response.Items, itemState{
+			ChartID:     item.ChartID,
+			Status:      item.Status,
+			Name:        item.Status.Name(),
+			Description: item.Status.Description(),
+		})
+	}
+	t.writeJSON(w, response)
+}
@@ -2,7 +2,6 @@ package httptransport
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -18,8 +17,8 @@ import (
 
 // Store интерфейс для взаимодействия с хранилищем.
 type Store interface {
-	AddOrder(order *model.OrderDetails) error
-	GetOrder(orderUID string) (*model.OrderDetails, error)
+	AddOrder(ctx context.Context, order *model.OrderDetails) error
+	GetOrder(ctx context.Context, orderUID string) (*model.OrderDetails, error)
 }
 
 // HTTPTransport интерфейс для работы с HTTP-сервером.
@@ -32,26 +31,71 @@ type httpTransport struct {
 	store  Store
 	logger *slog.Logger
 	server *http.Server
+
+	// admin административные операции с кэшем, nil если отключены.
+	admin CacheAdmin
+
+	// authenticators способы аутентификации; пустой список отключает проверку доступа.
+	authenticators  []Authenticator
+	anonymousScopes []string
+
+	// masking политика маскирования персональных данных, nil если отключена.
+	masking *MaskingPolicy
+
+	limits Limits
+	// ipLimiter и principalLimiter бакеты частоты запросов, nil если ограничение отключено.
+	ipLimiter        *rateLimiter
+	principalLimiter *rateLimiter
+
+	// exporter выгрузка заказов, nil если отключена.
+	exporter Exporter
+
+	// graphql GraphQL-эндпоинт, nil если отключен.
+	graphql http.Handler
+
+	// ingestMode режим приема заказов по HTTP, пустой если прием отключен.
+	ingestMode IngestMode
+	publisher  Publisher
+	// verifier проверка подписи заказов в режиме IngestDirect, nil если отключена.
+	verifier Verifier
+}
+
+// Option настраивает httpTransport.
+type Option func(*httpTransport)
+
+// WithCacheAdmin включает административные эндпоинты кэша. Они требуют
+// области доступа orders:admin и регистрируются только вместе с WithAuthentication.
+func WithCacheAdmin(admin CacheAdmin) Option {
+	return func(t *httpTransport) {
+		t.admin = admin
+	}
 }
 
 // NewHTTPTransport создает экземпляр HTTPTransport.
-func NewHTTPTransport(store Store, logger *slog.Logger) HTTPTransport {
-	return &httpTransport{
+func NewHTTPTransport(store Store, logger *slog.Logger, opts ...Option) HTTPTransport {
+	t := &httpTransport{
 		store:  store,
 		logger: logger,
+		limits: DefaultLimits(),
+	}
+	for _, opt := range opts {
+		opt(t)
 	}
+	t.initRateLimiters()
+	return t
 }
 
 // Start запускает HTTP-сервер с поддержкой graceful shutdown.
 func (t *httpTransport) Start(ctx context.Context, addr string) error {
-	router := http.NewServeMux()
-	router.HandleFunc("/api/v1/order", t.orderHandler)
-	router.HandleFunc("/", t.interfaceHandler)
-
 	t.server = &http.Server{
-		Addr:        addr,
-		Handler:     router,
-		BaseContext: func(net.Listener) context.Context { return ctx },
+		Addr:              addr,
+		Handler:           t.handler(),
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		ReadHeaderTimeout: t.limits.ReadHeaderTimeout,
+		ReadTimeout:       t.limits.ReadTimeout,
+		WriteTimeout:      t.limits.WriteTimeout,
+		IdleTimeout:       t.limits.IdleTimeout,
+		MaxHeaderBytes:    t.limits.MaxHeaderBytes,
 	}
 
 	// Обработка сигнала завершения для graceful shutdown
@@ -87,49 +131,111 @@ func (t *httpTransport) listenForShutdown(ctx context.Context) {
 	}
 }
 
-// orderHandler обрабатывает запросы для получения данных заказа.
+// orderHandler возвращает заказ по идентификатору из пути.
 func (t *httpTransport) orderHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
+	t.serveOrder(w, r, r.PathValue("uid"))
+}
 
+// legacyOrderHandler возвращает заказ по параметру order_uid (старый адрес API).
+func (t *httpTransport) legacyOrderHandler(w http.ResponseWriter, r *http.Request) {
 	orderUID := r.URL.Query().Get("order_uid")
 	if orderUID == "" {
 		http.Error(w, "Missing order UID", http.StatusBadRequest)
 		return
 	}
 
-	order, err := t.store.GetOrder(orderUID)
+	t.serveOrder(w, r, orderUID)
+}
+
+// serveOrder отдает заказ целиком или только поля из параметра fields.
+// Заранее сериализованный ответ используется, только если ответ не нужно
+// маскировать или сокращать.
+func (t *httpTransport) serveOrder(w http.ResponseWriter, r *http.Request, orderUID string) {
+	var fields fieldTree
+	if spec := r.URL.Query().Get("fields"); spec != "" {
+		var err error
+		if fields, err = parseFields(spec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	profile := t.maskProfile(r)
+	if encodedStore, ok := t.store.(EncodedStore); ok && fields == nil && len(profile) == 0 {
+		encoded, err := encodedStore.GetEncodedOrder(r.Context(), orderUID)
+		if err != nil {
+			t.writeOrderError(w, r, orderUID, err)
+			return
+		}
+		t.writeEncodedOrder(w, r, encoded)
+		return
+	}
+
+	order, err := t.store.GetOrder(r.Context(), orderUID)
 	if err != nil {
-		t.logger.Error("Failed to fetch order", slog.String("orderUID", orderUID), slog.Any("error", err))
-		http.Error(w, fmt.Sprintf("Error fetching order: %v", err), http.StatusInternalServerError)
+		t.writeOrderError(w, r, orderUID, err)
+		return
+	}
+	order = order.Masked(profile)
+
+	if fields == nil {
+		t.writeJSON(w, order)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(order); err != nil {
-		t.logger.Error("Failed to encode order to JSON", slog.Any("error", err))
+	projected, err := projectOrder(order, fields)
+	if err != nil {
+		t.log(r).Error("Failed to project order fields", slog.Any("error", err))
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
 	}
+	t.writeJSON(w, projected)
 }
 
-// interfaceHandler возвращает HTML-страницу для пользовательского интерфейса.
-func (t *httpTransport) interfaceHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
+// orderDeliveryHandler возвращает данные доставки заказа.
+func (t *httpTransport) orderDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if order, ok := t.lookupOrder(w, r); ok {
+		t.writeJSON(w, order.Address)
+	}
+}
+
+// orderPaymentHandler возвращает данные оплаты заказа.
+func (t *httpTransport) orderPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	if order, ok := t.lookupOrder(w, r); ok {
+		t.writeJSON(w, order.Payment)
+	}
+}
+
+// orderItemsHandler возвращает товары заказа.
+func (t *httpTransport) orderItemsHandler(w http.ResponseWriter, r *http.Request) {
+	if order, ok := t.lookupOrder(w, r); ok {
+		items := order.Products
+		if items == nil {
+			items = []model.ProductItem{}
+		}
+		t.writeJSON(w, items)
 	}
+}
 
-	htmlFile, err := os.ReadFile("frontend/form.html")
+// lookupOrder загружает заказ по идентификатору из пути и маскирует его.
+// При ошибке пишет ответ и возвращает false.
+func (t *httpTransport) lookupOrder(w http.ResponseWriter, r *http.Request) (*model.OrderDetails, bool) {
+	orderUID := r.PathValue("uid")
+	order, err := t.store.GetOrder(r.Context(), orderUID)
 	if err != nil {
-		t.logger.Error("Failed to read HTML file", slog.Any("error", err))
-		http.Error(w, "Page not found", http.StatusNotFound)
-		return
+		t.writeOrderError(w, r, orderUID, err)
+		return nil, false
 	}
+	return t.maskOrder(r, order), true
+}
 
-	w.Header().Set("Content-Type", "text/html")
-	if _, err := w.Write(htmlFile); err != nil {
-		t.logger.Error("Failed to write HTML response", slog.Any("error", err))
+// writeOrderError отвечает 404 для отсутствующего заказа и 500 для остальных ошибок.
+func (t *httpTransport) writeOrderError(w http.ResponseWriter, r *http.Request, orderUID string, err error) {
+	if errors.Is(err, model.ErrOrderNotFound) {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
 	}
+
+	t.log(r).Error("Failed to fetch order", slog.String("orderUID", orderUID), slog.Any("error", err))
+	http.Error(w, fmt.Sprintf("Error fetching order: %v", err), http.StatusInternalServerError)
 }
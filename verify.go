@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	ristrettocache "github.com/Sh1ni-Gami/WB_Tech_L0/caching"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/config"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/data_base"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/kafka"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// Проверки команды verify.
+const (
+	checkKafka   = "kafka"
+	checkCache   = "cache"
+	checkOrphans = "orphans"
+)
+
+// verifyBatchSize количество заказов из Kafka, сверяемых с базой одним запросом.
+const verifyBatchSize = 500
+
+// verifyReport итог сверки Kafka, базы и кэша. Проверки, которые не
+// запускались, в отчет не попадают.
+type verifyReport struct {
+	StartedAt  time.Time                    `json:"started_at"`
+	FinishedAt time.Time                    `json:"finished_at"`
+	Repair     bool                         `json:"repair"`
+	Kafka      *kafkaVerifyReport           `json:"kafka,omitempty"`
+	Cache      *ristrettocache.VerifyReport `json:"cache,omitempty"`
+	// CacheError причина, по которой сервис не сверил кэш
+	CacheError string         `json:"cache_error,omitempty"`
+	Orphans    *orphansReport `json:"orphans,omitempty"`
+	// OK расхождений нет или все они исправлены
+	OK bool `json:"ok"`
+}
+
+// kafkaVerifyReport заказы диапазона смещений [from, to), которых нет в базе.
+type kafkaVerifyReport struct {
+	Partition int              `json:"partition"`
+	From      int64            `json:"from"`
+	To        int64            `json:"to"`
+	Scanned   int              `json:"scanned"`
+	Invalid   []invalidMessage `json:"invalid"`
+	Missing   []missingOrder   `json:"missing"`
+}
+
+// invalidMessage сообщение, которое слушатель не сохранил бы: ошибка
+// декодирования, проверки заказа или подписи.
+type invalidMessage struct {
+	Offset int64  `json:"offset"`
+	Error  string `json:"error"`
+}
+
+// missingOrder корректный заказ из Kafka, которого нет в базе.
+type missingOrder struct {
+	Offset   int64  `json:"offset"`
+	OrderUID string `json:"order_uid"`
+	// Repaired заказ сохранен в базу
+	Repaired bool `json:"repaired,omitempty"`
+}
+
+// orphansReport строки delivery и payment без заказа.
+type orphansReport struct {
+	data_base.Orphans
+	// Deleted число удаленных строк
+	Deleted int64 `json:"deleted,omitempty"`
+}
+
+// ok сообщает, что после проверки не осталось неисправленных расхождений.
+// Некорректные сообщения Kafka исправить нельзя, они только перечисляются.
+func (r *verifyReport) ok() bool {
+	if r.Kafka != nil {
+		for _, missing := range r.Kafka.Missing {
+			if !missing.Repaired {
+				return false
+			}
+		}
+	}
+	if r.CacheError != "" {
+		return false
+	}
+	if r.Cache != nil {
+		for _, mismatch := range r.Cache.Mismatches {
+			if !mismatch.Repaired {
+				return false
+			}
+		}
+	}
+	if r.Orphans != nil && !r.Repair && len(r.Orphans.DeliveryIDs)+len(r.Orphans.PaymentTransactions) > 0 {
+		return false
+	}
+	return true
+}
+
+// runVerify сверяет заказы в Kafka, базе и кэше сервиса, пишет отчет JSON
+// и возвращает код завершения: 1, если остались расхождения.
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	var (
+		checks  = flags.String("checks", "kafka,cache,orphans", "comma-separated checks: kafka, cache, orphans")
+		from    = flags.Int64("from", -1, "first Kafka offset to scan (default the first retained offset)")
+		to      = flags.Int64("to", -1, "Kafka offset to stop before (default the end of the partition)")
+		service = flags.String("service", "http://localhost:8080", "base URL of the service whose cache is checked")
+		token   = flags.String("token", "", "API token with the orders:admin scope (default auth.admin_token)")
+		repair  = flags.Bool("repair", false, "save missing orders, evict mismatching cache entries and delete orphaned rows")
+		output  = flags.String("o", "", "report file (default stdout)")
+	)
+	loader := config.NewLoader(config.WithFlags(flags, "db", "kafka"))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	enabled, err := parseChecks(*checks)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *from >= 0 && *to >= 0 && *from > *to {
+		fmt.Fprintln(os.Stderr, "-from must not be greater than -to")
+		return 2
+	}
+	// Проверка кэша обращается только к API сервиса, база ей не нужна
+	if enabled[checkKafka] || enabled[checkOrphans] {
+		loader.Require("db.password")
+	}
+
+	// Журнал пишется в stderr, чтобы не смешиваться с отчетом в stdout
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	cfg, err := loadConfig(logger, loader)
+	if err != nil {
+		return 2
+	}
+	if *token == "" {
+		*token = cfg.Auth.AdminToken
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		var err error
+		if out, err = os.Create(*output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer out.Close()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report := &verifyReport{StartedAt: time.Now().UTC(), Repair: *repair}
+
+	var dbConn data_base.DBService
+	if enabled[checkKafka] || enabled[checkOrphans] {
+		var err error
+		if dbConn, err = initDatabase(logger, cfg.DB); err != nil {
+			return 1
+		}
+	}
+
+	if enabled[checkKafka] {
+		keyset, err := initKeyset(logger, cfg.Kafka)
+		if err != nil {
+			logger.Error("Failed to load signing keyset", slog.Any("error", err))
+			return 1
+		}
+		kafkaService, err := initKafka(logger, cfg.Kafka, dbConn, keyset)
+		if err != nil {
+			return 1
+		}
+		if report.Kafka, err = verifyKafka(ctx, logger, kafkaService, dbConn, cfg.Kafka.Partition, *from, *to, *repair); err != nil {
+			logger.Error("Kafka verification failed", slog.Any("error", err))
+			return 1
+		}
+	}
+
+	if enabled[checkCache] {
+		cacheReport, err := verifyServiceCache(ctx, *service, *token, *repair)
+		if err != nil {
+			// Недоступный сервис не мешает остальным проверкам
+			logger.Error("Cache verification failed", slog.Any("error", err))
+			report.CacheError = err.Error()
+		}
+		report.Cache = cacheReport
+	}
+
+	if enabled[checkOrphans] {
+		orphans, err := dbConn.FindOrphans(ctx)
+		if err != nil {
+			logger.Error("Orphan search failed", slog.Any("error", err))
+			return 1
+		}
+		report.Orphans = &orphansReport{Orphans: orphans}
+		if *repair {
+			if report.Orphans.Deleted, err = dbConn.DeleteOrphans(ctx, orphans); err != nil {
+				logger.Error("Failed to delete orphaned rows", slog.Any("error", err))
+				return 1
+			}
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	report.OK = report.ok()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logger.Error("Failed to write verification report", slog.Any("error", err))
+		return 1
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// parseChecks разбирает значение -checks в набор включенных проверок.
+func parseChecks(value string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for _, check := range strings.Split(value, ",") {
+		switch check = strings.TrimSpace(check); check {
+		case checkKafka, checkCache, checkOrphans:
+			enabled[check] = true
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown check %q: must be kafka, cache or orphans", check)
+		}
+	}
+	if len(enabled) == 0 {
+		return nil, errors.New("-checks: no checks selected")
+	}
+	return enabled, nil
+}
+
+// verifyKafka читает диапазон смещений раздела и ищет в базе каждый корректный
+// заказ. С repair недостающие заказы сохраняются в базу так же, как их
+// сохранил бы слушатель.
+func verifyKafka(ctx context.Context, logger *slog.Logger, kafkaService kafka.KafkaService, dbConn data_base.DBService, partition int, from, to int64, repair bool) (*kafkaVerifyReport, error) {
+	first, last, err := kafkaService.Offsets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partition offsets: %w", err)
+	}
+	if from < first {
+		from = first
+	}
+	if to < 0 || to > last {
+		to = last
+	}
+	from = min(from, to)
+
+	report := &kafkaVerifyReport{Partition: partition, From: from, To: to, Invalid: []invalidMessage{}, Missing: []missingOrder{}}
+	logger.Info("Scanning Kafka partition", slog.Int("partition", partition), slog.Int64("from", from), slog.Int64("to", to))
+
+	var batch []missingOrder
+	orders := make(map[string]*model.OrderDetails, verifyBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		orderIDs := make([]string, 0, len(orders))
+		for orderID := range orders {
+			orderIDs = append(orderIDs, orderID)
+		}
+		stored, err := dbConn.GetOrders(ctx, orderIDs)
+		if err != nil {
+			return err
+		}
+		for _, candidate := range batch {
+			if _, ok := stored[candidate.OrderUID]; ok {
+				continue
+			}
+			if repair {
+				err := dbConn.AddOrder(ctx, orders[candidate.OrderUID])
+				switch {
+				case errors.Is(err, model.ErrConflictingData):
+					// Такой заказ не сохранил бы и слушатель: он остается в отчете неисправленным
+					logger.Warn("Order conflicts with stored data", slog.String("orderID", candidate.OrderUID), slog.Any("error", err))
+				case err != nil && !errors.Is(err, model.ErrDuplicateOrder):
+					return fmt.Errorf("failed to save order %s: %w", candidate.OrderUID, err)
+				default:
+					candidate.Repaired = true
+				}
+			}
+			report.Missing = append(report.Missing, candidate)
+		}
+		batch = batch[:0]
+		clear(orders)
+		return nil
+	}
+
+	err = kafkaService.ScanOrders(ctx, from, to, func(offset int64, order *model.OrderDetails, err error) error {
+		report.Scanned++
+		if err != nil {
+			report.Invalid = append(report.Invalid, invalidMessage{Offset: offset, Error: err.Error()})
+			return nil
+		}
+		// Повторно отправленный заказ проверяется по первому сообщению
+		if _, ok := orders[order.OrderID]; ok {
+			return nil
+		}
+		orders[order.OrderID] = order
+		batch = append(batch, missingOrder{Offset: offset, OrderUID: order.OrderID})
+		if len(batch) < verifyBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Kafka partition scanned",
+		slog.Int("scanned", report.Scanned),
+		slog.Int("invalid", len(report.Invalid)),
+		slog.Int("missing", len(report.Missing)))
+	return report, nil
+}
+
+// verifyServiceCache просит сервис сверить свой кэш с базой: кэш живет в
+// памяти процесса сервиса и доступен только через API администрирования.
+func verifyServiceCache(ctx context.Context, service, token string, repair bool) (*ristrettocache.VerifyReport, error) {
+	endpoint := strings.TrimRight(service, "/") + "/api/v1/admin/cache/verify?" +
+		url.Values{"repair": {strconv.FormatBool(repair)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cache verify: unexpected status %s", resp.Status)
+	}
+
+	var report ristrettocache.VerifyReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("cache verify: %w", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Sh1ni-Gami/WB_Tech_L0/data_base"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/kafka"
+	"github.com/Sh1ni-Gami/WB_Tech_L0/model"
+)
+
+// scanMessage сообщение раздела для stubPartition: заказ либо ошибка его разбора.
+type scanMessage struct {
+	order *model.OrderDetails
+	err   error
+}
+
+// stubPartition раздел Kafka со смещениями, начиная с first.
+type stubPartition struct {
+	kafka.KafkaService
+	first    int64
+	messages []scanMessage
+}
+
+func (p *stubPartition) Offsets(context.Context) (int64, int64, error) {
+	return p.first, p.first + int64(len(p.messages)), nil
+}
+
+func (p *stubPartition) ScanOrders(_ context.Context, from, to int64, fn kafka.ScanFunc) error {
+	for offset := from; offset < to; offset++ {
+		message := p.messages[offset-p.first]
+		if err := fn(offset, message.order, message.err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stubOrderDB хранит заказы в памяти; заказы из conflicts AddOrder отклоняет.
+type stubOrderDB struct {
+	data_base.DBService
+	orders    map[string]*model.OrderDetails
+	conflicts map[string]bool
+	added     []string
+}
+
+func (db *stubOrderDB) GetOrders(_ context.Context, orderUIDs []string) (map[string]*model.OrderDetails, error) {
+	found := make(map[string]*model.OrderDetails, len(orderUIDs))
+	for _, orderUID := range orderUIDs {
+		if order, ok := db.orders[orderUID]; ok {
+			found[orderUID] = order
+		}
+	}
+	return found, nil
+}
+
+func (db *stubOrderDB) AddOrder(_ context.Context, order *model.OrderDetails) error {
+	if db.conflicts[order.OrderID] {
+		return model.ErrConflictingData
+	}
+	db.orders[order.OrderID] = order
+	db.added = append(db.added, order.OrderID)
+	return nil
+}
+
+func TestVerifyKafka(t *testing.T) {
+	order := func(orderUID string) *model.OrderDetails { return &model.OrderDetails{OrderID: orderUID} }
+	partition := &stubPartition{
+		first: 10,
+		messages: []scanMessage{
+			{order: order("stored")},
+			{order: order("lost")},
+			{err: errors.New("invalid signature")},
+			{order: order("conflict")},
+			// Повтор уже прочитанного заказа не попадает в отчет второй раз
+			{order: order("lost")},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name         string
+		repair       bool
+		wantMissing  []missingOrder
+		wantAdded    []string
+		wantInDBLost bool
+	}{
+		{
+			name:   "report only",
+			repair: false,
+			wantMissing: []missingOrder{
+				{Offset: 11, OrderUID: "lost"},
+				{Offset: 13, OrderUID: "conflict"},
+			},
+		},
+		{
+			name:   "repair",
+			repair: true,
+			wantMissing: []missingOrder{
+				{Offset: 11, OrderUID: "lost", Repaired: true},
+				{Offset: 13, OrderUID: "conflict"},
+			},
+			wantAdded:    []string{"lost"},
+			wantInDBLost: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &stubOrderDB{
+				orders:    map[string]*model.OrderDetails{"stored": order("stored")},
+				conflicts: map[string]bool{"conflict": true},
+			}
+			report, err := verifyKafka(context.Background(), logger, partition, db, 0, 0, -1, tt.repair)
+			if err != nil {
+				t.Fatalf("verifyKafka: %v", err)
+			}
+
+			if report.From != 10 || report.To != 15 || report.Scanned != 5 {
+				t.Errorf("range [%d, %d) scanned %d, want [10, 15) scanned 5", report.From, report.To, report.Scanned)
+			}
+			if len(report.Invalid) != 1 || report.Invalid[0].Offset != 12 {
+				t.Errorf("invalid %+v, want the message at offset 12", report.Invalid)
+			}
+			if len(report.Missing) != len(tt.wantMissing) {
+				t.Fatalf("missing %+v, want %+v", report.Missing, tt.wantMissing)
+			}
+			for i, want := range tt.wantMissing {
+				if report.Missing[i] != want {
+					t.Errorf("missing[%d] = %+v, want %+v", i, report.Missing[i], want)
+				}
+			}
+			if len(db.added) != len(tt.wantAdded) || (len(tt.wantAdded) > 0 && db.added[0] != tt.wantAdded[0]) {
+				t.Errorf("saved %v, want %v", db.added, tt.wantAdded)
+			}
+			if _, ok := db.orders["lost"]; ok != tt.wantInDBLost {
+				t.Errorf("lost order in the database: %t, want %t", ok, tt.wantInDBLost)
+			}
+		})
+	}
+}
+
+func TestParseChecks(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]bool
+		wantErr bool
+	}{
+		{value: "kafka,cache,orphans", want: map[string]bool{checkKafka: true, checkCache: true, checkOrphans: true}},
+		{value: " cache ,", want: map[string]bool{checkCache: true}},
+		{value: "cache,db", wantErr: true},
+		{value: " , ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseChecks(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseChecks(%q) error = %v, want error %t", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseChecks(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyRequiresPasswordOnlyForDatabaseChecks(t *testing.T) {
+	// Пустое значение не подменяется значением из .env
+	t.Setenv("POSTGRES_PASSWORD", "")
+
+	tests := []struct {
+		checks   string
+		wantCode int
+	}{
+		// Сервис недоступен: проверка выполняется и находит ошибку (1), а не
+		// отклоняется на разборе настроек (2)
+		{checks: "cache", wantCode: 1},
+		{checks: "cache,orphans", wantCode: 2},
+		{checks: "kafka", wantCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.checks, func(t *testing.T) {
+			report := filepath.Join(t.TempDir(), "report.json")
+			code := runVerify([]string{"-checks", tt.checks, "-service", "http://127.0.0.1:1", "-o", report})
+			if code != tt.wantCode {
+				t.Errorf("exit code %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}